@@ -2,24 +2,73 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"io"
 	"log"
+	"log/slog"
+	nethttp "net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"dev11/pkg/config"
 	"dev11/pkg/delivery/http"
+	"dev11/pkg/logging"
+	"dev11/pkg/ratelimit"
+	"dev11/pkg/reminder"
+	"dev11/pkg/repository"
+	"dev11/pkg/repository/cache"
+	"dev11/pkg/repository/file"
 	"dev11/pkg/service"
 )
 
-const serverPort = "8080"
-
 func main() {
-	s := service.NewService()
-	h := http.NewHandler(s)
+	cfg, err := config.Load(os.Args[0], os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// log records of the log package go through slog as well
+	slog.SetDefault(slog.New(logging.NewHandler(
+		slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: cfg.Log.Level}))))
+	slog.Info("configuration", slog.Any("config", cfg))
+	for _, w := range cfg.Warnings() {
+		slog.Warn(w)
+	}
+
+	repo, err := newRepository(cfg.Storage)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	notifier := newNotifier(cfg.Notifier)
+
+	s := service.NewService(repo)
+	s.SetMaxEvents(cfg.Limits.MaxEventsPerUser)
+	s.SetTrashRetention(cfg.Trash.Retention)
+	s.SetMaxTrashedEvents(cfg.Trash.MaxEventsPerUser)
+	h := http.NewHandler(s, cfg.Auth.AdminKey)
+	h.SetRateLimits(newLimiter(cfg.Limits.Read), newLimiter(cfg.Limits.Write))
+
+	ctx, stopJobs := context.WithCancel(context.Background())
+	scheduler := reminder.NewScheduler(repo, notifier)
+	scheduler.SetCatchUp(cfg.Notifier.CatchUp)
+	s.OnChange(func(string) { scheduler.Wake() })
+	scheduler.Start(ctx)
+	purged := make(chan struct{})
+	go func() {
+		defer close(purged)
+		s.RunPurger(ctx, cfg.Trash.PurgeInterval)
+	}()
 
 	srv := new(http.Server)
 	go func() {
-		if err := srv.Run(serverPort, h.InitRoutes()); err != nil {
+		if err := srv.Run(cfg.Server, h.InitRoutes()); err != nil && !errors.Is(err, nethttp.ErrServerClosed) {
 			log.Fatal(err)
 		}
 	}()
@@ -32,7 +81,47 @@ func main() {
 
 	log.Print("Service is shutting down...")
 
-	if err := srv.Shutdown(context.Background()); err != nil {
-		log.Printf("error occured on server shutting down: %s", err.Error())
+	h.SetReady(false)
+	time.Sleep(cfg.Server.ShutdownDelay)
+	// streams of changes would keep the server from shutting down
+	s.CloseSubscriptions()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("error occured on server shutting down", slog.String("error", err.Error()))
+	}
+	cancel()
+	stopJobs()
+	scheduler.Wait()
+	<-purged
+	if closer, ok := repo.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			slog.Error("error occured on storage closing", slog.String("error", err.Error()))
+		}
+	}
+}
+
+func newRepository(cfg config.Storage) (repository.User, error) {
+	if cfg.Backend == config.StorageFile {
+		return file.NewUserFile(cfg.Path)
+	}
+	return cache.NewUserCache(cache.NewCache()), nil
+}
+
+func newLimiter(cfg config.RateLimit) *ratelimit.Limiter {
+	if !cfg.Enabled() {
+		return nil
+	}
+	return ratelimit.New(cfg.Rate, cfg.Burst)
+}
+
+// newNotifier returns the notifier of the validated configuration.
+func newNotifier(cfg config.Notifier) reminder.Notifier {
+	switch cfg.Kind {
+	case config.NotifierWebhook:
+		return reminder.NewWebhookNotifier(cfg.WebhookURL)
+	case config.NotifierSMTP:
+		return &reminder.SMTPNotifier{Addr: cfg.SMTPAddr, From: cfg.SMTPFrom, To: cfg.SMTPTo}
+	default:
+		return reminder.LogNotifier{}
 	}
 }
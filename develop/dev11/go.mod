@@ -1,3 +1,3 @@
 module dev11 
 
-go 1.21.1
+go 1.22
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"dev11/pkg/models"
+)
+
+type operationInput struct {
+	Op      models.OperationType `json:"op"`
+	EventId string               `json:"eventId,omitempty"`
+	Version int64                `json:"version,omitempty"`
+	Event   *eventInput          `json:"event,omitempty"`
+}
+
+type batchInput struct {
+	Atomic     bool             `json:"atomic"`
+	Operations []operationInput `json:"operations"`
+}
+
+type operationResult struct {
+	Event *models.Event `json:"event"`
+	Error *Error        `json:"error"`
+}
+
+type batchResult struct {
+	Results []operationResult `json:"results"`
+}
+
+func newOperationInput(op models.EventOperation) operationInput {
+	input := operationInput{Op: op.Type, EventId: op.EventId, Version: op.Version}
+	if op.Type == models.OperationDelete {
+		return input
+	}
+	if op.Type == models.OperationUpdate {
+		input.EventId, input.Version = op.Event.Id, op.Event.Version
+	}
+	event := newEventInput(op.Event)
+	input.Event = &event
+	return input
+}
+
+// ApplyEvents applies the operations to the events of the user in one
+// transaction. If atomic, a failed operation fails the whole batch with an
+// *Error whose Operation is the index of the operation. Otherwise the
+// failed operations are skipped and their errors are returned in the
+// results as *Error.
+func (c *Client) ApplyEvents(
+	ctx context.Context,
+	userId string,
+	ops []models.EventOperation,
+	atomic bool,
+) ([]models.OperationResult, error) {
+	input := batchInput{Atomic: atomic, Operations: make([]operationInput, 0, len(ops))}
+	for _, op := range ops {
+		input.Operations = append(input.Operations, newOperationInput(op))
+	}
+	result, err := call[batchResult](ctx, c, request{
+		method: http.MethodPost,
+		path:   userPath(userId, "events:batch"),
+		body:   input,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.OperationResult, len(result.Results))
+	for i, r := range result.Results {
+		results[i].Event = r.Event
+		if r.Error != nil {
+			results[i].Err = r.Error
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,226 @@
+// Package client is a Go client of the calendar API described by the
+// OpenAPI document served at /openapi.json. Its methods mirror service.User
+// with a context as the first argument, so the calendar can be used over
+// HTTP the same way it is used in process.
+//
+// Location, Authenticate, AuthenticateFeed, CanRead and Stats of
+// service.User have no endpoints and are not provided. Neither are As and
+// WithContext: changes are made on behalf of the user of the API key and
+// the context is passed to every method. PatchEvent takes a Go function and is not provided
+// either; UpdateEvent with the version read makes the same change.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"dev11/pkg/models"
+)
+
+// Client calls the calendar API at BaseURL with the API key of a user or
+// the admin key.
+type Client struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+func New(baseURL, apiKey string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), APIKey: apiKey, HTTPClient: http.DefaultClient}
+}
+
+// FieldError is an invalid field of a request body.
+type FieldError struct {
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// problemTypeQuotaExceeded is the type of problems the API reports an
+// exceeded quota with.
+const problemTypeQuotaExceeded = "/problems/quota-exceeded"
+
+// Error is an RFC 7807 problem returned by the API. It matches the domain
+// error of its type or else of its status code with errors.Is, e.g.
+// models.ErrQuotaExceeded for a quota problem and models.ErrNotFound for
+// 404.
+type Error struct {
+	// Type is the URI of the type of the problem, about:blank unless the
+	// status code alone does not tell the kind of the error.
+	Type       string         `json:"type"`
+	StatusCode int            `json:"status"`
+	Title      string         `json:"title"`
+	Detail     string         `json:"detail"`
+	Fields     []FieldError   `json:"fields"`
+	Conflicts  []models.Event `json:"conflicts"`
+	// Operation is the index of the failed operation of an atomic batch.
+	Operation *int `json:"operation"`
+	// RetryAfter is how long to wait before retrying a rate limited request.
+	RetryAfter time.Duration `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("calendar: %d %s", e.StatusCode, e.Title)
+	}
+	return fmt.Sprintf("calendar: %d %s: %s", e.StatusCode, e.Title, e.Detail)
+}
+
+func (e *Error) Is(target error) bool {
+	if e.Type == problemTypeQuotaExceeded {
+		return target == models.ErrQuotaExceeded
+	}
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return target == models.ErrValidation
+	case http.StatusUnauthorized:
+		return target == models.ErrUnauthorized
+	case http.StatusForbidden:
+		return target == models.ErrForbidden
+	case http.StatusNotFound:
+		return target == models.ErrNotFound
+	case http.StatusConflict:
+		return target == models.ErrConflict
+	case http.StatusPreconditionFailed:
+		return target == models.ErrPreconditionFailed
+	case http.StatusTooManyRequests:
+		return target == models.ErrRateLimited
+	}
+	return false
+}
+
+// request describes a call of the API.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	// body is encoded as JSON unless it is an io.Reader.
+	body        any
+	contentType string
+}
+
+// call sends the request and returns the "result" of the response.
+func call[T any](ctx context.Context, c *Client, req request) (T, error) {
+	var output struct {
+		Result T `json:"result"`
+	}
+	_, err := c.do(ctx, req, &output)
+	return output.Result, err
+}
+
+// do sends the request and decodes the response into result if it is not
+// nil. Responses with an error status are returned as *Error.
+func (c *Client) do(ctx context.Context, req request, result any) (*http.Response, error) {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if result == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return resp, nil
+	}
+	if err = json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, fmt.Errorf("calendar: decode response of %s %s: %w", req.method, req.path, err)
+	}
+	return resp, nil
+}
+
+// send sends the request and returns the response with a successful
+// status. The caller must close its body.
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	target := c.BaseURL + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+
+	var body io.Reader
+	contentType := req.contentType
+	switch b := req.body.(type) {
+	case nil:
+	case io.Reader:
+		body = b
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	r, err := http.NewRequestWithContext(ctx, req.method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range req.header {
+		r.Header[k] = v
+	}
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	if c.APIKey != "" {
+		r.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		return nil, decodeError(resp)
+	}
+	return resp, nil
+}
+
+func decodeError(resp *http.Response) error {
+	e := &Error{}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if json.Unmarshal(data, e) != nil {
+		e.Detail = strings.TrimSpace(string(data))
+	}
+	e.StatusCode = resp.StatusCode
+	if e.Title == "" {
+		e.Title = http.StatusText(resp.StatusCode)
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		e.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return e
+}
+
+// Ping checks that the server and its storage are available.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/healthz"}, nil)
+	return err
+}
+
+func userPath(userId string, elems ...string) string {
+	path := "/users/" + url.PathEscape(userId)
+	for _, e := range elems {
+		path += "/" + url.PathEscape(e)
+	}
+	return path
+}
+
+// ifMatch returns the If-Match header expecting the version or nil for 0.
+func ifMatch(version int64) http.Header {
+	if version == 0 {
+		return nil
+	}
+	return http.Header{"If-Match": {`"` + strconv.FormatInt(version, 10) + `"`}}
+}
@@ -0,0 +1,407 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	delivery "dev11/pkg/delivery/http"
+	"dev11/pkg/models"
+	"dev11/pkg/repository/cache"
+	"dev11/pkg/service"
+)
+
+const adminKey = "admin-secret-key"
+
+func newTestServer(t *testing.T) (*Client, *service.Service) {
+	t.Helper()
+	s := service.NewService(cache.NewUserCache(cache.NewCache()))
+	srv := httptest.NewServer(delivery.NewHandler(s, adminKey).InitRoutes())
+	t.Cleanup(func() {
+		s.CloseSubscriptions()
+		srv.Close()
+	})
+	return New(srv.URL, adminKey), s
+}
+
+func TestClient_Events(t *testing.T) {
+	admin, _ := newTestServer(t)
+	ctx := context.Background()
+
+	user, key, err := admin.CreateUser(ctx, models.User{Id: "alice", TimeZone: "Europe/Moscow"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Id != "alice" || key == "" {
+		t.Fatalf("CreateUser() = %+v, %q", user, key)
+	}
+	c := New(admin.BaseURL, key)
+
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	event, err := c.CreateEvent(ctx, "alice", models.Event{Name: "standup", Date: start, End: &end, RRule: "FREQ=DAILY;COUNT=3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Id == "" || event.TimeZone != "Europe/Moscow" || !event.Date.Equal(start) {
+		t.Fatalf("CreateEvent() = %+v", event)
+	}
+
+	_, err = c.CreateEvent(ctx, "alice", models.Event{Name: "overlap", Date: start.Add(30 * time.Minute), End: &end})
+	var apiErr *Error
+	if !errors.Is(err, models.ErrConflict) || !errors.As(err, &apiErr) || len(apiErr.Conflicts) != 1 {
+		t.Fatalf("CreateEvent() of an overlapping event error = %v", err)
+	}
+
+	stale := event
+	event.Name = "daily"
+	if event, err = c.UpdateEvent(ctx, "alice", event); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.UpdateEvent(ctx, "alice", stale); !errors.Is(err, models.ErrPreconditionFailed) {
+		t.Fatalf("UpdateEvent() of a stale version error = %v, want %v", err, models.ErrPreconditionFailed)
+	}
+
+	second := start.AddDate(0, 0, 1)
+	occurrence, err := c.GetOccurrence(ctx, "alice", event.Id, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !occurrence.Date.Equal(second) {
+		t.Errorf("GetOccurrence().Date = %v, want %v", occurrence.Date, second)
+	}
+	if err = c.DeleteOccurrence(ctx, "alice", event.Id, second); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := c.GetEventsInRange(ctx, "alice", start, start.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Errorf("GetEventsInRange() returned %d events, want 2", len(events))
+	}
+	events, err = c.GetEventsForDay(ctx, "alice", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Name != "daily" {
+		t.Errorf("GetEventsForDay() = %+v", events)
+	}
+
+	busy, free, err := c.GetFreeBusy(ctx, "alice", start, start.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(busy) != 1 || len(free) != 1 {
+		t.Errorf("GetFreeBusy() = %v, %v", busy, free)
+	}
+
+	current, err := c.GetEvent(ctx, "alice", event.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(current.ExDates) != 1 || current.Version <= event.Version {
+		t.Errorf("GetEvent() after DeleteOccurrence() = %+v", current)
+	}
+	if err = c.DeleteEvent(ctx, "alice", event.Id, current.Version); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.GetEvent(ctx, "alice", event.Id); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("GetEvent() of a deleted event error = %v, want %v", err, models.ErrNotFound)
+	}
+}
+
+func TestClient_Users(t *testing.T) {
+	admin, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, key, err := admin.CreateUser(ctx, models.User{Id: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = admin.CreateUser(ctx, models.User{Id: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	c := New(admin.BaseURL, key)
+
+	if _, err = c.GetUsers(ctx); !errors.Is(err, models.ErrForbidden) {
+		t.Errorf("GetUsers() by a user error = %v, want %v", err, models.ErrForbidden)
+	}
+	if _, err = c.GetUser(ctx, "bob"); !errors.Is(err, models.ErrForbidden) {
+		t.Errorf("GetUser() of another user error = %v, want %v", err, models.ErrForbidden)
+	}
+	user, err := c.SetUserTimeZone(ctx, "alice", "Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.TimeZone != "Asia/Tokyo" {
+		t.Errorf("SetUserTimeZone().TimeZone = %q, want Asia/Tokyo", user.TimeZone)
+	}
+	users, err := admin.GetUsers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 3 {
+		t.Errorf("GetUsers() returned %d users, want 3", len(users))
+	}
+	if err = admin.DeleteUser(ctx, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = admin.GetUser(ctx, "bob"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("GetUser() of a deleted user error = %v, want %v", err, models.ErrNotFound)
+	}
+	if err = New(admin.BaseURL, "").Ping(ctx); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+}
+
+func TestClient_ImportEvents(t *testing.T) {
+	c, _ := newTestServer(t)
+	ctx := context.Background()
+
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	errs, err := c.ImportEvents(ctx, "1", []models.Event{
+		{UID: "standup", Name: "standup", Date: start, End: &end},
+		{UID: "invalid", Name: "invalid", Date: start, RRule: "FREQ=NEVER"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 2 || errs[0] != nil || errs[1] == nil {
+		t.Errorf("ImportEvents() = %v", errs)
+	}
+}
+
+func TestClient_SubscribeChanges(t *testing.T) {
+	c, _ := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	created, err := c.CreateEvent(ctx, "1", models.Event{Name: "standup", Date: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var before uint64
+	stream, err := c.SubscribeChanges(ctx, "1", &before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	change, err := stream.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Type != models.ChangeCreated || change.EventId != created.Id {
+		t.Errorf("replayed change = %+v", change)
+	}
+
+	if err = c.DeleteEvent(ctx, "1", created.Id, 0); err != nil {
+		t.Fatal(err)
+	}
+	change, err = stream.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Type != models.ChangeDeleted || *stream.LastId != change.Id {
+		t.Errorf("change = %+v, LastId = %d", change, *stream.LastId)
+	}
+}
+
+func TestClient_Sharing(t *testing.T) {
+	admin, _ := newTestServer(t)
+	ctx := context.Background()
+
+	keys := map[string]string{}
+	for _, id := range []string{"alice", "bob"} {
+		_, key, err := admin.CreateUser(ctx, models.User{Id: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[id] = key
+	}
+	alice, bob := New(admin.BaseURL, keys["alice"]), New(admin.BaseURL, keys["bob"])
+
+	if _, err := admin.CreateGroup(ctx, models.Group{Id: "team", Members: []string{"bob"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.ShareCalendar(ctx, "alice", []string{"team"}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	event, err := alice.CreateEvent(ctx, "alice", models.Event{Name: "review", Date: start, Attendees: []models.Attendee{{UserId: "bob"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events, err := bob.GetEvents(ctx, "alice"); err != nil || len(events) != 1 {
+		t.Errorf("GetEvents() of the shared calendar = %v, %v", events, err)
+	}
+
+	invitations, err := bob.GetInvitations(ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invitations) != 1 || invitations[0].Id != event.Id {
+		t.Fatalf("GetInvitations() = %+v", invitations)
+	}
+	updated, err := bob.RespondToInvitation(ctx, "bob", "alice", event.Id, models.StatusTentative)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := updated.Attendee("bob"); a == nil || a.Status != models.StatusTentative {
+		t.Errorf("RespondToInvitation() = %+v", updated)
+	}
+
+	if _, err = alice.UpdateGroup(ctx, models.Group{Id: "team"}); !errors.Is(err, models.ErrForbidden) {
+		t.Errorf("UpdateGroup() by a user error = %v, want %v", err, models.ErrForbidden)
+	}
+	group, err := admin.UpdateGroup(ctx, models.Group{Id: "team", Name: "Team"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.Name != "Team" || len(group.Members) != 0 {
+		t.Errorf("UpdateGroup() = %+v", group)
+	}
+	if _, err = bob.GetEvents(ctx, "alice"); !errors.Is(err, models.ErrForbidden) {
+		t.Errorf("GetEvents() after leaving the group error = %v, want %v", err, models.ErrForbidden)
+	}
+	if err = admin.DeleteGroup(ctx, "team"); err != nil {
+		t.Fatal(err)
+	}
+	if groups, err := admin.GetGroups(ctx); err != nil || len(groups) != 0 {
+		t.Errorf("GetGroups() = %v, %v", groups, err)
+	}
+}
+
+func TestClient_SearchEvents(t *testing.T) {
+	c, _ := newTestServer(t)
+	ctx := context.Background()
+
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	for _, e := range []models.Event{
+		{Name: "Планёрка", Date: start, RRule: "FREQ=DAILY;COUNT=5", Tags: []string{"work"}},
+		{Name: "Обед", Date: start.Add(4 * time.Hour), Tags: []string{"personal"}},
+	} {
+		if _, err := c.CreateEvent(ctx, "1", e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err := c.SearchEvents(ctx, "1", models.SearchQuery{Text: "план"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Name != "Планёрка" || len(events[0].Tags) != 1 {
+		t.Errorf("SearchEvents() = %+v", events)
+	}
+	events, err = c.SearchEvents(ctx, "1", models.SearchQuery{Tags: []string{"WORK"}, From: start.AddDate(0, 0, 1), To: start.AddDate(0, 0, 3)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].SeriesId == "" {
+		t.Errorf("SearchEvents() in a range = %+v", events)
+	}
+	if _, err = c.SearchEvents(ctx, "1", models.SearchQuery{}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("SearchEvents() without words and tags error = %v, want %v", err, models.ErrValidation)
+	}
+}
+
+func TestClient_Trash(t *testing.T) {
+	c, _ := newTestServer(t)
+	ctx := context.Background()
+
+	event, err := c.CreateEvent(ctx, "1", models.Event{Name: "review", Date: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.DeleteEvent(ctx, "1", event.Id, 0); err != nil {
+		t.Fatal(err)
+	}
+	trash, err := c.GetTrash(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash) != 1 || trash[0].Event.Id != event.Id || !trash[0].PurgeAt.After(trash[0].DeletedAt) {
+		t.Errorf("GetTrash() = %+v", trash)
+	}
+	restored, err := c.RestoreEvent(ctx, "1", event.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Id != event.Id || restored.Version <= event.Version {
+		t.Errorf("RestoreEvent() = %+v", restored)
+	}
+	if _, err = c.RestoreEvent(ctx, "1", event.Id); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("RestoreEvent() twice error = %v, want %v", err, models.ErrNotFound)
+	}
+	history, err := c.GetEventHistory(ctx, "1", event.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 || history[2].Type != models.ChangeRestored || history[2].After == nil {
+		t.Errorf("GetEventHistory() = %+v", history)
+	}
+}
+
+func TestClient_QuotaExceeded(t *testing.T) {
+	c, s := newTestServer(t)
+	s.SetMaxEvents(1)
+	ctx := context.Background()
+
+	if _, err := c.CreateEvent(ctx, "1", models.Event{Name: "a", Date: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := c.CreateEvent(ctx, "1", models.Event{Name: "b", Date: time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)})
+	if !errors.Is(err, models.ErrQuotaExceeded) || errors.Is(err, models.ErrForbidden) {
+		t.Errorf("CreateEvent() over the quota error = %v, want %v", err, models.ErrQuotaExceeded)
+	}
+}
+
+func TestClient_ApplyEvents(t *testing.T) {
+	c, _ := newTestServer(t)
+	ctx := context.Background()
+
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	review, err := c.CreateEvent(ctx, "1", models.Event{Name: "review", Date: start, End: &end})
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved := review
+	moved.Date, moved.End = start.Add(2*time.Hour), nil
+	ops := []models.EventOperation{
+		{Type: models.OperationCreate, Event: models.Event{Name: "overlap", Date: start, End: &end}},
+		{Type: models.OperationUpdate, Event: moved},
+		{Type: models.OperationCreate, Event: models.Event{Name: "standup", Date: start, End: &end}},
+	}
+
+	_, err = c.ApplyEvents(ctx, "1", ops, true)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Operation == nil || *apiErr.Operation != 0 || !errors.Is(err, models.ErrConflict) {
+		t.Fatalf("ApplyEvents() of an atomic batch error = %v, want a conflict of operation 0", err)
+	}
+	results, err := c.ApplyEvents(ctx, "1", ops, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || !errors.Is(results[0].Err, models.ErrConflict) ||
+		results[1].Err != nil || results[1].Event.Version != 2 ||
+		results[2].Err != nil || results[2].Event.Name != "standup" {
+		t.Errorf("ApplyEvents() = %+v", results)
+	}
+
+	results, err = c.ApplyEvents(ctx, "1", []models.EventOperation{
+		{Type: models.OperationDelete, EventId: review.Id, Version: 1},
+		{Type: models.OperationDelete, EventId: review.Id, Version: 2},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(results[0].Err, models.ErrPreconditionFailed) || results[1].Err != nil || results[1].Event != nil {
+		t.Errorf("ApplyEvents() of deletions = %+v", results)
+	}
+}
@@ -0,0 +1,250 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"dev11/pkg/ical"
+	"dev11/pkg/models"
+)
+
+// maxPageLimit is the largest page of GET /events.
+const maxPageLimit = 1000
+
+type eventInput struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Date        time.Time         `json:"date"`
+	End         *time.Time        `json:"end,omitempty"`
+	TimeZone    string            `json:"timezone,omitempty"`
+	RRule       string            `json:"rrule,omitempty"`
+	ExDates     []time.Time       `json:"exdates,omitempty"`
+	Reminders   []models.Duration `json:"reminders,omitempty"`
+	Attendees   []models.Attendee `json:"attendees,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+}
+
+func newEventInput(e models.Event) eventInput {
+	return eventInput{
+		Name:        e.Name,
+		Description: e.Description,
+		Date:        e.Date,
+		End:         e.End,
+		TimeZone:    e.TimeZone,
+		RRule:       e.RRule,
+		ExDates:     e.ExDates,
+		Reminders:   e.Reminders,
+		Attendees:   e.Attendees,
+		Tags:        e.Tags,
+	}
+}
+
+type eventsPage struct {
+	Result     []models.Event `json:"result"`
+	NextCursor string         `json:"nextCursor"`
+}
+
+type importError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+type importResult struct {
+	Imported int           `json:"imported"`
+	Errors   []importError `json:"errors"`
+}
+
+func (c *Client) GetEvents(ctx context.Context, userId string) ([]models.Event, error) {
+	return call[[]models.Event](ctx, c, request{method: http.MethodGet, path: userPath(userId, "events")})
+}
+
+func (c *Client) GetEvent(ctx context.Context, userId, eventId string) (*models.Event, error) {
+	event, err := call[models.Event](ctx, c, request{method: http.MethodGet, path: userPath(userId, "events", eventId)})
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// CreateEvent creates the event. The id and the version of the event are
+// assigned by the server.
+func (c *Client) CreateEvent(ctx context.Context, userId string, event models.Event) (models.Event, error) {
+	return call[models.Event](ctx, c, request{
+		method: http.MethodPost,
+		path:   userPath(userId, "events"),
+		body:   newEventInput(event),
+	})
+}
+
+// UpdateEvent replaces the event with event.Id. Unless event.Version is 0
+// the event is replaced only if it has not changed since that version.
+func (c *Client) UpdateEvent(ctx context.Context, userId string, event models.Event) (models.Event, error) {
+	return call[models.Event](ctx, c, request{
+		method: http.MethodPut,
+		path:   userPath(userId, "events", event.Id),
+		header: ifMatch(event.Version),
+		body:   newEventInput(event),
+	})
+}
+
+// DeleteEvent deletes the event. Unless version is 0 the event is deleted
+// only if it has not changed since that version.
+func (c *Client) DeleteEvent(ctx context.Context, userId, eventId string, version int64) error {
+	_, err := c.do(ctx, request{
+		method: http.MethodDelete,
+		path:   userPath(userId, "events", eventId),
+		header: ifMatch(version),
+	}, nil)
+	return err
+}
+
+func (c *Client) GetOccurrence(ctx context.Context, userId, eventId string, occurrence time.Time) (*models.Event, error) {
+	event, err := call[models.Event](ctx, c, request{
+		method: http.MethodGet,
+		path:   userPath(userId, "events", eventId),
+		query:  occurrenceQuery(occurrence),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// UpdateOccurrence replaces a single occurrence of the series with event.
+func (c *Client) UpdateOccurrence(
+	ctx context.Context,
+	userId, eventId string,
+	occurrence time.Time,
+	event models.Event,
+) (models.Event, error) {
+	return call[models.Event](ctx, c, request{
+		method: http.MethodPut,
+		path:   userPath(userId, "events", eventId),
+		query:  occurrenceQuery(occurrence),
+		body:   newEventInput(event),
+	})
+}
+
+// DeleteOccurrence excludes a single occurrence from the series.
+func (c *Client) DeleteOccurrence(ctx context.Context, userId, eventId string, occurrence time.Time) error {
+	_, err := c.do(ctx, request{
+		method: http.MethodDelete,
+		path:   userPath(userId, "events", eventId),
+		query:  occurrenceQuery(occurrence),
+	}, nil)
+	return err
+}
+
+// SearchEvents returns the events of the user matching the query. With a
+// range occurrences of series within it are returned instead of series.
+func (c *Client) SearchEvents(ctx context.Context, userId string, query models.SearchQuery) ([]models.Event, error) {
+	params := url.Values{}
+	if query.Text != "" {
+		params.Set("q", query.Text)
+	}
+	for _, t := range query.Tags {
+		params.Add("tag", t)
+	}
+	if query.HasRange() {
+		params.Set("from", query.From.Format(time.RFC3339))
+		params.Set("to", query.To.Format(time.RFC3339))
+	}
+	return call[[]models.Event](ctx, c, request{method: http.MethodGet, path: userPath(userId, "events", "search"), query: params})
+}
+
+// GetEventsInRange returns occurrences of the events of the user within
+// [from, to) following the pages of GET /events.
+func (c *Client) GetEventsInRange(ctx context.Context, userId string, from, to time.Time) ([]models.Event, error) {
+	query := url.Values{
+		"user_id": {userId},
+		"from":    {from.Format(time.RFC3339)},
+		"to":      {to.Format(time.RFC3339)},
+		"limit":   {strconv.Itoa(maxPageLimit)},
+	}
+	events := make([]models.Event, 0)
+	for {
+		var page eventsPage
+		if _, err := c.do(ctx, request{method: http.MethodGet, path: "/events", query: query}, &page); err != nil {
+			return nil, err
+		}
+		events = append(events, page.Result...)
+		if page.NextCursor == "" {
+			return events, nil
+		}
+		query.Set("cursor", page.NextCursor)
+	}
+}
+
+// GetEventsForDay returns events of the day containing date. The day is
+// taken in the location of date or, for time.Local, in the default time
+// zone of the user.
+func (c *Client) GetEventsForDay(ctx context.Context, id string, date time.Time) ([]models.Event, error) {
+	return c.getEventsFor(ctx, "/events_for_day", id, date)
+}
+
+// GetEventsForWeek is GetEventsForDay for the week from Monday to Sunday.
+func (c *Client) GetEventsForWeek(ctx context.Context, id string, startWeekDate time.Time) ([]models.Event, error) {
+	return c.getEventsFor(ctx, "/events_for_week", id, startWeekDate)
+}
+
+// GetEventsForMonth is GetEventsForDay for the calendar month.
+func (c *Client) GetEventsForMonth(ctx context.Context, id string, startMonthDate time.Time) ([]models.Event, error) {
+	return c.getEventsFor(ctx, "/events_for_month", id, startMonthDate)
+}
+
+func (c *Client) getEventsFor(ctx context.Context, path, userId string, date time.Time) ([]models.Event, error) {
+	query := url.Values{"user_id": {userId}, "date": {date.Format(time.RFC3339)}}
+	if loc := date.Location(); loc != time.Local {
+		query.Set("tz", loc.String())
+	}
+	return call[[]models.Event](ctx, c, request{method: http.MethodGet, path: path, query: query})
+}
+
+// GetFreeBusy returns merged busy intervals of the user and the free
+// intervals between them within [from, to).
+func (c *Client) GetFreeBusy(ctx context.Context, userId string, from, to time.Time) ([]models.Interval, []models.Interval, error) {
+	query := url.Values{"from": {from.Format(time.RFC3339)}, "to": {to.Format(time.RFC3339)}}
+	result, err := call[struct {
+		Busy []models.Interval `json:"busy"`
+		Free []models.Interval `json:"free"`
+	}](ctx, c, request{method: http.MethodGet, path: userPath(userId, "free-busy"), query: query})
+	if err != nil {
+		return nil, nil, err
+	}
+	return result.Busy, result.Free, nil
+}
+
+// ImportEvents imports the events as an iCalendar file and returns the
+// errors of the events which were not imported by their indexes. Reminders
+// are not kept since iCalendar files are imported without them.
+func (c *Client) ImportEvents(ctx context.Context, userId string, events []models.Event) ([]error, error) {
+	var buf bytes.Buffer
+	if err := ical.Encode(&buf, "", events); err != nil {
+		return nil, err
+	}
+	result, err := call[importResult](ctx, c, request{
+		method:      http.MethodPost,
+		path:        userPath(userId, "import"),
+		body:        &buf,
+		contentType: "text/calendar",
+	})
+	if err != nil {
+		return nil, err
+	}
+	errs := make([]error, len(events))
+	for _, e := range result.Errors {
+		if e.Index >= 0 && e.Index < len(errs) {
+			errs[e.Index] = errors.New(e.Error)
+		}
+	}
+	return errs, nil
+}
+
+func occurrenceQuery(occurrence time.Time) url.Values {
+	return url.Values{"occurrence": {occurrence.Format(time.RFC3339)}}
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"dev11/pkg/models"
+)
+
+type groupInput struct {
+	Id      string   `json:"id,omitempty"`
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+// GetInvitations returns the events of other users the user is invited to.
+func (c *Client) GetInvitations(ctx context.Context, userId string) ([]models.Event, error) {
+	return call[[]models.Event](ctx, c, request{method: http.MethodGet, path: userPath(userId, "invitations")})
+}
+
+// RespondToInvitation sets the status of the user in the attendees of the
+// event of the organizer.
+func (c *Client) RespondToInvitation(
+	ctx context.Context,
+	userId, organizerId, eventId string,
+	status models.AttendeeStatus,
+) (models.Event, error) {
+	return call[models.Event](ctx, c, request{
+		method: http.MethodPut,
+		path:   userPath(userId, "invitations", organizerId, eventId),
+		body:   map[string]models.AttendeeStatus{"status": status},
+	})
+}
+
+// ShareCalendar replaces the groups whose members can read the calendar of
+// the user.
+func (c *Client) ShareCalendar(ctx context.Context, userId string, groupIds []string) (*models.User, error) {
+	if groupIds == nil {
+		groupIds = []string{}
+	}
+	result, err := call[userResult](ctx, c, request{
+		method: http.MethodPut,
+		path:   userPath(userId, "shared-with"),
+		body:   map[string][]string{"groups": groupIds},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.user(), nil
+}
+
+// CreateGroup creates the group. It requires the admin key.
+func (c *Client) CreateGroup(ctx context.Context, group models.Group) (*models.Group, error) {
+	input := groupInput{Id: group.Id, Name: group.Name, Members: group.Members}
+	return groupResult(call[models.Group](ctx, c, request{method: http.MethodPost, path: "/groups", body: input}))
+}
+
+// UpdateGroup replaces the name and the members of the group. It requires
+// the admin key.
+func (c *Client) UpdateGroup(ctx context.Context, group models.Group) (*models.Group, error) {
+	input := groupInput{Name: group.Name, Members: group.Members}
+	return groupResult(call[models.Group](ctx, c, request{method: http.MethodPut, path: groupPath(group.Id), body: input}))
+}
+
+func (c *Client) GetGroup(ctx context.Context, id string) (*models.Group, error) {
+	return groupResult(call[models.Group](ctx, c, request{method: http.MethodGet, path: groupPath(id)}))
+}
+
+// GetGroups returns all groups. It requires the admin key.
+func (c *Client) GetGroups(ctx context.Context) ([]models.Group, error) {
+	return call[[]models.Group](ctx, c, request{method: http.MethodGet, path: "/groups"})
+}
+
+// DeleteGroup deletes the group. It requires the admin key.
+func (c *Client) DeleteGroup(ctx context.Context, id string) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: groupPath(id)}, nil)
+	return err
+}
+
+func groupPath(id string) string {
+	return "/groups/" + url.PathEscape(id)
+}
+
+func groupResult(group models.Group, err error) (*models.Group, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
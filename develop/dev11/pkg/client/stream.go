@@ -0,0 +1,82 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"dev11/pkg/models"
+)
+
+// ErrReset is returned by ChangeStream.Next when the changes missed since
+// the last received one are no longer kept. The events have to be reloaded.
+var ErrReset = errors.New("calendar: changes were reset")
+
+// ChangeStream reads changes of events of a user from the server-sent
+// events of GET /users/{id}/events/stream.
+type ChangeStream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+	// LastId is the id of the last received change to resume from.
+	LastId *uint64
+}
+
+// SubscribeChanges opens a stream of changes of events of the user. If
+// lastId is not nil the stream starts with the changes after it.
+func (c *Client) SubscribeChanges(ctx context.Context, userId string, lastId *uint64) (*ChangeStream, error) {
+	req := request{method: http.MethodGet, path: userPath(userId, "events", "stream"), header: http.Header{}}
+	req.header.Set("Accept", "text/event-stream")
+	if lastId != nil {
+		req.header.Set("Last-Event-ID", strconv.FormatUint(*lastId, 10))
+	}
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &ChangeStream{body: resp.Body, scanner: bufio.NewScanner(resp.Body), LastId: lastId}, nil
+}
+
+// Next blocks until the next change and returns it. It returns io.EOF when
+// the server closes the stream.
+func (s *ChangeStream) Next() (models.Change, error) {
+	var event, data string
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		if line == "" {
+			if event == "" && data == "" {
+				continue
+			}
+			if event == "reset" {
+				return models.Change{}, ErrReset
+			}
+			var c models.Change
+			if err := json.Unmarshal([]byte(data), &c); err != nil {
+				return models.Change{}, fmt.Errorf("calendar: decode change: %w", err)
+			}
+			s.LastId = &c.Id
+			return c, nil
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data += value
+		}
+	}
+	if err := s.scanner.Err(); err != nil {
+		return models.Change{}, err
+	}
+	return models.Change{}, io.EOF
+}
+
+func (s *ChangeStream) Close() error {
+	return s.body.Close()
+}
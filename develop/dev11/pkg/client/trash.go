@@ -0,0 +1,27 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"dev11/pkg/models"
+)
+
+// GetTrash returns the deleted events of the user, the latest deleted
+// first.
+func (c *Client) GetTrash(ctx context.Context, userId string) ([]models.TrashedEvent, error) {
+	return call[[]models.TrashedEvent](ctx, c, request{method: http.MethodGet, path: userPath(userId, "trash")})
+}
+
+// RestoreEvent returns the deleted event from the trash.
+func (c *Client) RestoreEvent(ctx context.Context, userId, eventId string) (models.Event, error) {
+	return call[models.Event](ctx, c, request{
+		method: http.MethodPost,
+		path:   userPath(userId, "trash", eventId, "restore"),
+	})
+}
+
+// GetEventHistory returns the changes of the event from the oldest one.
+func (c *Client) GetEventHistory(ctx context.Context, userId, eventId string) ([]models.AuditEntry, error) {
+	return call[[]models.AuditEntry](ctx, c, request{method: http.MethodGet, path: userPath(userId, "events", eventId, "history")})
+}
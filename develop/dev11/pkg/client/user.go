@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"dev11/pkg/models"
+)
+
+type userResult struct {
+	Id       string      `json:"id"`
+	Name     string      `json:"name,omitempty"`
+	Role     models.Role `json:"role,omitempty"`
+	TimeZone string      `json:"timezone,omitempty"`
+	// SharedWith is only returned.
+	SharedWith []string `json:"sharedWith,omitempty"`
+	APIKey     string   `json:"apiKey,omitempty"`
+}
+
+type feedTokenResult struct {
+	FeedToken string `json:"feedToken"`
+}
+
+func (u userResult) user() *models.User {
+	return &models.User{Id: u.Id, Name: u.Name, Role: u.Role, TimeZone: u.TimeZone, SharedWith: u.SharedWith}
+}
+
+// CreateUser creates the user and returns it with its API key. Only an
+// admin can create another admin.
+func (c *Client) CreateUser(ctx context.Context, user models.User) (*models.User, string, error) {
+	input := userResult{Id: user.Id, Name: user.Name, Role: user.Role, TimeZone: user.TimeZone}
+	result, err := call[userResult](ctx, c, request{method: http.MethodPost, path: "/users", body: input})
+	if err != nil {
+		return nil, "", err
+	}
+	return result.user(), result.APIKey, nil
+}
+
+func (c *Client) GetUser(ctx context.Context, id string) (*models.User, error) {
+	result, err := call[userResult](ctx, c, request{method: http.MethodGet, path: userPath(id)})
+	if err != nil {
+		return nil, err
+	}
+	return result.user(), nil
+}
+
+// GetUsers returns all users. It requires the admin key.
+func (c *Client) GetUsers(ctx context.Context) ([]models.User, error) {
+	result, err := call[[]userResult](ctx, c, request{method: http.MethodGet, path: "/users"})
+	if err != nil {
+		return nil, err
+	}
+	users := make([]models.User, 0, len(result))
+	for _, u := range result {
+		users = append(users, *u.user())
+	}
+	return users, nil
+}
+
+func (c *Client) DeleteUser(ctx context.Context, id string) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: userPath(id)}, nil)
+	return err
+}
+
+// ResetFeedToken replaces the token with which the calendar of the user can
+// be read at /users/{id}/calendar.ics?token= and returns it.
+func (c *Client) ResetFeedToken(ctx context.Context, userId string) (string, error) {
+	result, err := call[feedTokenResult](ctx, c, request{method: http.MethodPost, path: userPath(userId) + "/feed-token"})
+	if err != nil {
+		return "", err
+	}
+	return result.FeedToken, nil
+}
+
+func (c *Client) SetUserTimeZone(ctx context.Context, userId, tz string) (*models.User, error) {
+	body := map[string]string{"timezone": tz}
+	result, err := call[userResult](ctx, c, request{method: http.MethodPatch, path: userPath(userId), body: body})
+	if err != nil {
+		return nil, err
+	}
+	return result.user(), nil
+}
@@ -0,0 +1,455 @@
+// Package config loads the configuration of the server from command line
+// flags, environment variables and an optional JSON or YAML file.
+//
+// Every setting has a flag like -storage-path, an environment variable
+// like CALENDAR_STORAGE_PATH and a key in the file like storage.path. Flags
+// take precedence over environment variables, which take precedence over
+// the file, which overrides the defaults.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	envPrefix = "CALENDAR_"
+	// configFlag is the flag and, with envPrefix, the environment variable
+	// with the path of the configuration file.
+	configFlag = "config"
+
+	redacted = "[REDACTED]"
+	// minAdminKeyLength keeps the admin key from being guessed.
+	minAdminKeyLength = 16
+)
+
+const (
+	StorageMemory = "memory"
+	StorageFile   = "file"
+
+	NotifierLog     = "log"
+	NotifierWebhook = "webhook"
+	NotifierSMTP    = "smtp"
+)
+
+type Config struct {
+	Server   Server
+	Storage  Storage
+	Log      Log
+	Auth     Auth
+	Limits   Limits
+	Notifier Notifier
+	Trash    Trash
+}
+
+type Server struct {
+	// Addr is the address to listen on like ":8080".
+	Addr        string
+	ReadTimeout time.Duration
+	// ReadHeaderTimeout limits reading the headers of a request, so slow
+	// clients can not hold connections open.
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// ShutdownDelay is the time the server keeps serving requests after it
+	// reports not ready on shutdown.
+	ShutdownDelay time.Duration
+	// ShutdownTimeout is how long requests in flight may take to finish on
+	// shutdown before their connections are closed.
+	ShutdownTimeout time.Duration
+	TLS             TLS
+}
+
+// TLS enables HTTPS, which also enables HTTP/2, if both files are set or
+// SelfSigned is.
+type TLS struct {
+	CertFile string
+	KeyFile  string
+	// SelfSigned makes the server generate a self-signed certificate on
+	// start. It is meant for development only.
+	SelfSigned bool
+}
+
+func (t TLS) Enabled() bool {
+	return t.SelfSigned || t.CertFile != "" && t.KeyFile != ""
+}
+
+type Storage struct {
+	// Backend is StorageMemory or StorageFile.
+	Backend string
+	// Path is the path to the log of the file storage.
+	Path string
+}
+
+type Log struct {
+	Level slog.Level
+}
+
+type Auth struct {
+	// AdminKey is the API key with the admin role, disabled if empty.
+	AdminKey string
+}
+
+type Limits struct {
+	// Read limits requests which only read data per user or IP address.
+	Read RateLimit
+	// Write limits requests which change data per user or IP address.
+	Write RateLimit
+	// MaxEventsPerUser limits the number of stored events of a user, 0
+	// means no limit.
+	MaxEventsPerUser int
+}
+
+// RateLimit allows on average Rate requests per second with bursts of up to
+// Burst requests. A zero rate disables the limit.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l RateLimit) Enabled() bool {
+	return l.Rate > 0
+}
+
+type Notifier struct {
+	// Kind is NotifierLog, NotifierWebhook or NotifierSMTP.
+	Kind       string
+	WebhookURL string
+	SMTPAddr   string
+	SMTPFrom   string
+	SMTPTo     []string
+	// CatchUp is how long before the start of the server reminders missed
+	// while it was down are still sent, 0 means none. Reminders sent before
+	// a restart within it are sent again.
+	CatchUp time.Duration
+}
+
+type Trash struct {
+	// Retention is how long deleted events are kept in the trash.
+	Retention time.Duration
+	// PurgeInterval is how often events kept longer than Retention are
+	// removed from the trash.
+	PurgeInterval time.Duration
+	// MaxEventsPerUser limits the number of events in the trash of a user,
+	// the earliest deleted are removed first; 0 means no limit.
+	MaxEventsPerUser int
+}
+
+// Default returns the configuration used when nothing is set.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:              ":8080",
+			ReadTimeout:       10 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      10 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			ShutdownTimeout:   15 * time.Second,
+		},
+		Storage: Storage{Backend: StorageMemory, Path: "calendar.log"},
+		Log:     Log{Level: slog.LevelInfo},
+		Limits: Limits{
+			Read:             RateLimit{Rate: 20, Burst: 40},
+			Write:            RateLimit{Rate: 5, Burst: 10},
+			MaxEventsPerUser: 10000,
+		},
+		Notifier: Notifier{Kind: NotifierLog, SMTPAddr: "localhost:1025", SMTPFrom: "calendar@localhost"},
+		Trash:    Trash{Retention: 30 * 24 * time.Hour, PurgeInterval: time.Hour, MaxEventsPerUser: 10000},
+	}
+}
+
+// setting is a single configurable value.
+type setting struct {
+	flag   string
+	key    string
+	usage  string
+	secret bool
+	value  flag.Value
+}
+
+func (s setting) env() string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(s.flag, "-", "_"))
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{"addr", "server.addr", "address to listen on", false, (*stringValue)(&c.Server.Addr)},
+		{"read-timeout", "server.readTimeout", "maximum duration of reading a request",
+			false, (*durationValue)(&c.Server.ReadTimeout)},
+		{"read-header-timeout", "server.readHeaderTimeout", "maximum duration of reading the headers of a request",
+			false, (*durationValue)(&c.Server.ReadHeaderTimeout)},
+		{"write-timeout", "server.writeTimeout", "maximum duration of writing a response",
+			false, (*durationValue)(&c.Server.WriteTimeout)},
+		{"idle-timeout", "server.idleTimeout", "maximum time to wait for the next request on a connection",
+			false, (*durationValue)(&c.Server.IdleTimeout)},
+		{"shutdown-delay", "server.shutdownDelay",
+			"time to keep serving requests after /readyz reports not ready on shutdown",
+			false, (*durationValue)(&c.Server.ShutdownDelay)},
+		{"shutdown-timeout", "server.shutdownTimeout",
+			"maximum time to wait for requests in flight on shutdown before closing their connections",
+			false, (*durationValue)(&c.Server.ShutdownTimeout)},
+		{"tls-cert", "server.tls.certFile", "path to the TLS certificate, enables HTTPS with -tls-key",
+			false, (*stringValue)(&c.Server.TLS.CertFile)},
+		{"tls-key", "server.tls.keyFile", "path to the TLS private key", false, (*stringValue)(&c.Server.TLS.KeyFile)},
+		{"tls-self-signed", "server.tls.selfSigned", "serve HTTPS with a generated self-signed certificate, for development",
+			false, (*boolValue)(&c.Server.TLS.SelfSigned)},
+		{"storage", "storage.backend", "storage backend: memory or file", false, (*stringValue)(&c.Storage.Backend)},
+		{"storage-path", "storage.path", "path to the log of the file storage", false, (*stringValue)(&c.Storage.Path)},
+		{"log-level", "log.level", "minimum level of logged records: debug, info, warn or error",
+			false, (*levelValue)(&c.Log.Level)},
+		{"admin-key", "auth.adminKey", "API key with the admin role", true, (*stringValue)(&c.Auth.AdminKey)},
+		{"read-rate", "limits.read.rate", "requests per second which read data allowed to a user or IP, 0 disables",
+			false, (*floatValue)(&c.Limits.Read.Rate)},
+		{"read-burst", "limits.read.burst", "burst of requests which read data", false, (*intValue)(&c.Limits.Read.Burst)},
+		{"write-rate", "limits.write.rate", "requests per second which change data allowed to a user or IP, 0 disables",
+			false, (*floatValue)(&c.Limits.Write.Rate)},
+		{"write-burst", "limits.write.burst", "burst of requests which change data",
+			false, (*intValue)(&c.Limits.Write.Burst)},
+		{"max-events-per-user", "limits.maxEventsPerUser", "maximum number of events of a user, 0 means no limit",
+			false, (*intValue)(&c.Limits.MaxEventsPerUser)},
+		{"notifier", "notifier.kind", "reminder notifier: log, webhook or smtp", false, (*stringValue)(&c.Notifier.Kind)},
+		{"webhook-url", "notifier.webhookURL", "URL the webhook notifier posts reminders to",
+			false, (*stringValue)(&c.Notifier.WebhookURL)},
+		{"smtp-addr", "notifier.smtp.addr", "address of the SMTP server", false, (*stringValue)(&c.Notifier.SMTPAddr)},
+		{"smtp-from", "notifier.smtp.from", "sender of reminder mails", false, (*stringValue)(&c.Notifier.SMTPFrom)},
+		{"smtp-to", "notifier.smtp.to", "comma separated recipients of reminder mails",
+			false, (*listValue)(&c.Notifier.SMTPTo)},
+		{"reminder-catch-up", "notifier.catchUp",
+			"how long before the start missed reminders are still sent, including ones sent before a restart",
+			false, (*durationValue)(&c.Notifier.CatchUp)},
+		{"trash-retention", "trash.retention", "how long deleted events are kept in the trash",
+			false, (*durationValue)(&c.Trash.Retention)},
+		{"trash-purge-interval", "trash.purgeInterval", "how often expired events are removed from the trash",
+			false, (*durationValue)(&c.Trash.PurgeInterval)},
+		{"trash-max-events-per-user", "trash.maxEventsPerUser",
+			"maximum number of events in the trash of a user, the earliest deleted are removed first, 0 means no limit",
+			false, (*intValue)(&c.Trash.MaxEventsPerUser)},
+	}
+}
+
+// Load returns the configuration set by the command line arguments, the
+// environment and the configuration file, which is given by the -config
+// flag or the CALENDAR_CONFIG variable. It returns flag.ErrHelp if help was
+// requested.
+func Load(name string, args []string, getenv func(string) string) (Config, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configEnv := envPrefix + strings.ToUpper(configFlag)
+	configPath := fs.String(configFlag, getenv(configEnv),
+		"path to a JSON or YAML configuration file (env "+configEnv+")")
+	// flags are applied after the file and the environment
+	flags := make(map[string]string)
+	for _, s := range settings {
+		name := "-" + s.flag
+		usage := fmt.Sprintf("%s (env %s, file key %s, default %q)", s.usage, s.env(), s.key, s.value.String())
+		set := func(v string) error {
+			flags[name] = v
+			return nil
+		}
+		if b, ok := s.value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			fs.BoolFunc(s.flag, usage, set)
+		} else {
+			fs.Func(s.flag, usage, set)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	if fs.NArg() > 0 {
+		return cfg, fmt.Errorf("unexpected arguments %q", fs.Args())
+	}
+
+	if *configPath != "" {
+		values, err := readFile(*configPath)
+		if err != nil {
+			return cfg, err
+		}
+		if err = apply(settings, values, func(s setting) string { return s.key }); err != nil {
+			return cfg, fmt.Errorf("%s: %w", *configPath, err)
+		}
+	}
+	env := make(map[string]string)
+	for _, s := range settings {
+		if v := getenv(s.env()); v != "" {
+			env[s.env()] = v
+		}
+	}
+	if err := apply(settings, env, setting.env); err != nil {
+		return cfg, err
+	}
+	if err := apply(settings, flags, func(s setting) string { return "-" + s.flag }); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// apply sets the settings to values keyed by name. Values which do not
+// belong to any setting are an error.
+func apply(settings []setting, values map[string]string, name func(setting) string) error {
+	known := make(map[string]bool, len(values))
+	var errs []error
+	for _, s := range settings {
+		v, ok := values[name(s)]
+		if !ok {
+			continue
+		}
+		known[name(s)] = true
+		if err := s.value.Set(v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name(s), err))
+		}
+	}
+	for k := range values {
+		if !known[k] {
+			errs = append(errs, fmt.Errorf("unknown setting %q", k))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// readFile reads a configuration file into values keyed by dotted keys like
+// storage.path. The format is chosen by the extension of the file.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]string
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		values, err = parseJSON(data)
+	case ".yaml", ".yml":
+		values, err = parseYAML(data)
+	default:
+		return nil, fmt.Errorf("%s: unsupported configuration file format %q", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+// Validate checks that the configuration is complete and consistent.
+func (c Config) Validate() error {
+	var errs []error
+	if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("server.addr: %w", err))
+	}
+	for key, d := range map[string]time.Duration{
+		"server.readTimeout":       c.Server.ReadTimeout,
+		"server.readHeaderTimeout": c.Server.ReadHeaderTimeout,
+		"server.writeTimeout":      c.Server.WriteTimeout,
+		"server.idleTimeout":       c.Server.IdleTimeout,
+		"server.shutdownDelay":     c.Server.ShutdownDelay,
+	} {
+		if d < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative", key))
+		}
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("server.shutdownTimeout must be positive"))
+	}
+	if (c.Server.TLS.CertFile == "") != (c.Server.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("server.tls.certFile and server.tls.keyFile must be set together"))
+	}
+	if c.Server.TLS.SelfSigned && c.Server.TLS.CertFile != "" {
+		errs = append(errs, errors.New("server.tls.selfSigned can not be used with server.tls.certFile"))
+	}
+	for key, path := range map[string]string{
+		"server.tls.certFile": c.Server.TLS.CertFile,
+		"server.tls.keyFile":  c.Server.TLS.KeyFile,
+	} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	switch c.Storage.Backend {
+	case StorageMemory:
+	case StorageFile:
+		if c.Storage.Path == "" {
+			errs = append(errs, errors.New("storage.path is required by the file storage"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("storage.backend: unknown storage backend %q", c.Storage.Backend))
+	}
+
+	for key, l := range map[string]RateLimit{"limits.read": c.Limits.Read, "limits.write": c.Limits.Write} {
+		if l.Rate < 0 {
+			errs = append(errs, fmt.Errorf("%s.rate must not be negative", key))
+		}
+		if l.Enabled() && l.Burst < 1 {
+			errs = append(errs, fmt.Errorf("%s.burst must be positive", key))
+		}
+	}
+	if c.Limits.MaxEventsPerUser < 0 {
+		errs = append(errs, errors.New("limits.maxEventsPerUser must not be negative"))
+	}
+	if c.Trash.MaxEventsPerUser < 0 {
+		errs = append(errs, errors.New("trash.maxEventsPerUser must not be negative"))
+	}
+
+	for key, d := range map[string]time.Duration{
+		"trash.retention":     c.Trash.Retention,
+		"trash.purgeInterval": c.Trash.PurgeInterval,
+	} {
+		if d <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", key))
+		}
+	}
+
+	if c.Notifier.CatchUp < 0 {
+		errs = append(errs, errors.New("notifier.catchUp must not be negative"))
+	}
+	switch c.Notifier.Kind {
+	case NotifierLog:
+	case NotifierWebhook:
+		if c.Notifier.WebhookURL == "" {
+			errs = append(errs, errors.New("notifier.webhookURL is required by the webhook notifier"))
+		}
+	case NotifierSMTP:
+		if len(c.Notifier.SMTPTo) == 0 {
+			errs = append(errs, errors.New("notifier.smtp.to is required by the smtp notifier"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("notifier.kind: unknown notifier %q", c.Notifier.Kind))
+	}
+	return errors.Join(errs...)
+}
+
+// Warnings returns problems of the configuration which are accepted for
+// now but will be errors in the next release.
+func (c Config) Warnings() []string {
+	var warnings []string
+	if k := c.Auth.AdminKey; k != "" && len(k) < minAdminKeyLength {
+		warnings = append(warnings, fmt.Sprintf(
+			"auth.adminKey shorter than %d characters is deprecated and will be rejected in the next release",
+			minAdminKeyLength))
+	}
+	return warnings
+}
+
+// LogValue lists the settings with secrets redacted.
+func (c Config) LogValue() slog.Value {
+	settings := c.settings()
+	attrs := make([]slog.Attr, 0, len(settings))
+	for _, s := range settings {
+		v := s.value.String()
+		if s.secret && v != "" {
+			v = redacted
+		}
+		attrs = append(attrs, slog.String(s.key, v))
+	}
+	return slog.GroupValue(attrs...)
+}
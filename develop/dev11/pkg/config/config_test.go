@@ -0,0 +1,185 @@
+package config
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, "calendar.yaml", `
+server:
+  addr: ":9000"
+  readTimeout: 1s
+  writeTimeout: 2s
+storage:
+  backend: file
+  path: /tmp/from-file.log
+`)
+	env := map[string]string{
+		"CALENDAR_CONFIG":        path,
+		"CALENDAR_WRITE_TIMEOUT": "3s",
+		"CALENDAR_STORAGE_PATH":  "/tmp/from-env.log",
+		"CALENDAR_LOG_LEVEL":     "debug",
+	}
+	args := []string{"-storage-path", "/tmp/from-flag.log", "-tls-self-signed"}
+	cfg, err := Load("test", args, func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Default()
+	want.Server.Addr = ":9000"
+	want.Server.ReadTimeout = time.Second
+	want.Server.WriteTimeout = 3 * time.Second
+	want.Storage = Storage{Backend: StorageFile, Path: "/tmp/from-flag.log"}
+	want.Log.Level = slog.LevelDebug
+	want.Server.TLS.SelfSigned = true
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("config = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		data   string
+		args   []string
+		errMsg string
+	}{
+		{"unknown key", "c.json", `{"server":{"port":8080}}`, nil, `unknown setting "server.port"`},
+		{"invalid duration", "", "", []string{"-read-timeout", "10"}, "-read-timeout: time: missing unit"},
+		{"invalid level", "", "", []string{"-log-level", "loud"}, "-log-level"},
+		{"unknown format", "c.toml", `addr = ":1"`, nil, "unsupported configuration file format"},
+		{"invalid address", "", "", []string{"-addr", "localhost"}, "server.addr"},
+		{"negative timeout", "", "", []string{"-idle-timeout", "-1s"}, "server.idleTimeout must not be negative"},
+		{"file storage without path", "", "", []string{"-storage", "file", "-storage-path", ""}, "storage.path is required"},
+		{"unknown storage", "", "", []string{"-storage", "redis"}, "unknown storage backend"},
+		{"cert without key", "", "", []string{"-tls-cert", "cert.pem"}, "must be set together"},
+		{"self-signed with cert", "", "", []string{"-tls-self-signed", "-tls-cert", "cert.pem", "-tls-key", "key.pem"}, "can not be used with"},
+		{"invalid bool", "", "", []string{"-tls-self-signed=maybe"}, "-tls-self-signed"},
+		{"zero shutdown timeout", "", "", []string{"-shutdown-timeout", "0s"}, "server.shutdownTimeout must be positive"},
+		{"rate limit without burst", "", "", []string{"-write-burst", "0"}, "limits.write.burst must be positive"},
+		{"invalid rate", "", "", []string{"-read-rate", "fast"}, "-read-rate"},
+		{"webhook without url", "", "", []string{"-notifier", "webhook"}, "notifier.webhookURL is required"},
+		{"negative catch-up", "", "", []string{"-reminder-catch-up", "-1h"}, "notifier.catchUp must not be negative"},
+		{"zero retention", "", "", []string{"-trash-retention", "0s"}, "trash.retention must be positive"},
+		{"negative trash limit", "", "", []string{"-trash-max-events-per-user", "-1"}, "trash.maxEventsPerUser must not be negative"},
+		{"unexpected argument", "", "", []string{"serve"}, "unexpected arguments"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeFile(t, tt.file, tt.data)}, args...)
+			}
+			_, err := Load("test", args, func(string) string { return "" })
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("error = %v, want %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestConfig_Warnings(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"no admin key", nil, 0},
+		{"short admin key", []string{"-admin-key", "secret"}, 1},
+		{"long admin key", []string{"-admin-key", strings.Repeat("k", minAdminKeyLength)}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load("test", tt.args, func(string) string { return "" })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cfg.Warnings(); len(got) != tt.want {
+				t.Errorf("Warnings() = %q, want %d warnings", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	data := `# calendar
+server:
+  addr: "localhost:8080"   # quoted
+  tls:
+    certFile: 'it''s.pem'
+auth:
+  adminKey: abc#def
+notifier:
+  kind: smtp
+  smtp:
+    to:
+    - a@example.com
+    - "b@example.com"
+other: [x, 'y', "z"]
+empty: ~
+`
+	got, err := parseYAML([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"server.addr":         "localhost:8080",
+		"server.tls.certFile": "it's.pem",
+		"auth.adminKey":       "abc#def",
+		"notifier.kind":       "smtp",
+		"notifier.smtp.to":    "a@example.com,b@example.com",
+		"other":               "x,y,z",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseYAML() = %v, want %v", got, want)
+	}
+
+	for _, invalid := range []string{"- a", "server\n", "a:b", `a: "b`} {
+		if _, err = parseYAML([]byte(invalid)); err == nil {
+			t.Errorf("parseYAML(%q) succeeded", invalid)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	got, err := parseJSON([]byte(`{"server":{"addr":":1","tls":null},"notifier":{"smtp":{"to":["a","b"]}},"n":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"server.addr": ":1", "notifier.smtp.to": "a,b", "n": "1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseJSON() = %v, want %v", got, want)
+	}
+}
+
+func TestConfig_LogValue(t *testing.T) {
+	cfg := Default()
+	cfg.Auth.AdminKey = "0123456789abcdef-secret"
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("configuration", slog.Any("config", cfg))
+	out := buf.String()
+	if strings.Contains(out, "secret") || !strings.Contains(out, `"auth.adminKey":"[REDACTED]"`) {
+		t.Errorf("admin key is not redacted: %s", out)
+	}
+	if !strings.Contains(out, `"server.addr":":8080"`) {
+		t.Errorf("address is not logged: %s", out)
+	}
+}
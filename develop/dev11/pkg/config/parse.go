@@ -0,0 +1,200 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseJSON flattens a JSON object into values keyed by dotted paths.
+// Arrays become comma separated lists.
+func parseJSON(data []byte) (map[string]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var root map[string]any
+	if err := dec.Decode(&root); err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	if err := flattenJSON(values, "", root); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func flattenJSON(values map[string]string, key string, v any) error {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if key != "" {
+				k = key + "." + k
+			}
+			if err := flattenJSON(values, k, child); err != nil {
+				return err
+			}
+		}
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := jsonScalar(item)
+			if !ok {
+				return fmt.Errorf("%s: lists may contain only scalars", key)
+			}
+			items = append(items, s)
+		}
+		values[key] = strings.Join(items, ",")
+	case nil:
+	default:
+		s, ok := jsonScalar(v)
+		if !ok {
+			return fmt.Errorf("%s: unsupported value %v", key, v)
+		}
+		values[key] = s
+	}
+	return nil
+}
+
+func jsonScalar(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
+
+// yamlKey is a mapping key whose value is a nested block.
+type yamlKey struct {
+	indent int
+	path   string
+}
+
+// parseYAML flattens the subset of YAML needed by configuration files into
+// values keyed by dotted paths: nested mappings, plain and quoted scalars,
+// block and flow sequences of scalars, which become comma separated lists,
+// and comments.
+func parseYAML(data []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	var stack []yamlKey
+	for i, line := range strings.Split(string(data), "\n") {
+		lineNum := i + 1
+		line = strings.TrimRight(stripYAMLComment(line), " \r")
+		content := strings.TrimLeft(line, " ")
+		if content == "" || content == "---" {
+			continue
+		}
+		if strings.HasPrefix(content, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed in indentation", lineNum)
+		}
+		indent := len(line) - len(content)
+
+		if item, ok := strings.CutPrefix(content, "-"); ok && (item == "" || item[0] == ' ') {
+			// an item belongs to the closest key above with a lesser or the
+			// same indentation
+			for len(stack) > 0 && stack[len(stack)-1].indent > indent {
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: sequence item outside of a mapping", lineNum)
+			}
+			s, err := yamlScalar(strings.TrimSpace(item))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			path := stack[len(stack)-1].path
+			if prev, ok := values[path]; ok && prev != "" {
+				s = prev + "," + s
+			}
+			values[path] = s
+			continue
+		}
+
+		key, value, ok := strings.Cut(content, ":")
+		if !ok || key == "" || (value != "" && value[0] != ' ') {
+			return nil, fmt.Errorf("line %d: expected key: value", lineNum)
+		}
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		path := strings.TrimSpace(key)
+		if len(stack) > 0 {
+			path = stack[len(stack)-1].path + "." + path
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			stack = append(stack, yamlKey{indent: indent, path: path})
+			continue
+		}
+		if value == "~" || value == "null" {
+			continue
+		}
+		var err error
+		if strings.HasPrefix(value, "[") {
+			value, err = yamlFlowSequence(value)
+		} else {
+			value, err = yamlScalar(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		values[path] = value
+	}
+	return values, nil
+}
+
+// stripYAMLComment cuts a comment starting with # at the start of the line
+// or after a space outside of quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' '):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func yamlScalar(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		return strconv.Unquote(s)
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return "", fmt.Errorf("unterminated string %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	default:
+		return s, nil
+	}
+}
+
+func yamlFlowSequence(s string) (string, error) {
+	if !strings.HasSuffix(s, "]") {
+		return "", fmt.Errorf("unterminated sequence %s", s)
+	}
+	s = strings.TrimSpace(s[1 : len(s)-1])
+	if s == "" {
+		return "", nil
+	}
+	items := strings.Split(s, ",")
+	for i, item := range items {
+		var err error
+		if items[i], err = yamlScalar(strings.TrimSpace(item)); err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(items, ","), nil
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"dev11/pkg/models"
+	"dev11/pkg/service"
+)
+
+// applyUserEvents applies a batch of operations to the events of the user
+// in one transaction. An atomic batch fails as a whole with the problem of
+// the failed operation; otherwise the result of every operation, including
+// one with an invalid event, is returned with the status code it would
+// have as a separate request.
+func (h *Handler) applyUserEvents(w http.ResponseWriter, r *http.Request) {
+	input, err := h.decodeBatchBodyJSON(r)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	userId := r.PathValue("id")
+	// operations which can not be resolved fail on their own unless the
+	// batch is atomic, the rest are applied; index maps the applied
+	// operations back to the batch
+	failed := make([]error, len(input.Operations))
+	ops := make([]models.EventOperation, 0, len(input.Operations))
+	index := make([]int, 0, len(input.Operations))
+	for i, in := range input.Operations {
+		op, err := h.eventOperation(userId, in)
+		if err != nil {
+			if input.Atomic {
+				h.httpErrorResponse(w, r, &service.BatchError{Index: i, Err: err})
+				return
+			}
+			failed[i] = err
+			continue
+		}
+		ops = append(ops, op)
+		index = append(index, i)
+	}
+	results, err := h.actingService(r).ApplyEvents(userId, ops, input.Atomic)
+	if err != nil {
+		var batchErr *service.BatchError
+		if errors.As(err, &batchErr) {
+			err = &service.BatchError{Index: index[batchErr.Index], Err: batchErr.Err}
+		}
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	result := batchResult{Results: make([]operationResult, len(input.Operations))}
+	for i, err := range failed {
+		if err != nil {
+			problem := errorProblem(r, err)
+			result.Results[i] = operationResult{Status: problem.Status, Error: &problem}
+		}
+	}
+	for j, res := range results {
+		i := index[j]
+		if res.Err != nil {
+			problem := errorProblem(r, res.Err)
+			result.Results[i] = operationResult{Status: problem.Status, Error: &problem}
+			continue
+		}
+		result.Applied++
+		result.Results[i] = operationResult{Status: operationStatus(ops[j].Type), Event: res.Event}
+	}
+
+	h.httpBatchResponse(w, http.StatusOK, result)
+}
+
+// eventOperation returns the operation with the dates of its event resolved
+// as in a separate request.
+func (h *Handler) eventOperation(userId string, input operationInput) (models.EventOperation, error) {
+	op := models.EventOperation{Type: input.Op, EventId: input.EventId, Version: input.Version}
+	if input.Event == nil {
+		return op, nil
+	}
+	loc, err := h.service.Location(userId, input.Event.TimeZone)
+	if err != nil {
+		return op, err
+	}
+	if op.Event, err = input.Event.event(input.EventId, loc); err != nil {
+		return op, err
+	}
+	op.Event.Version = input.Version
+	return op, nil
+}
+
+func operationStatus(op models.OperationType) int {
+	switch op {
+	case models.OperationCreate:
+		return http.StatusCreated
+	case models.OperationDelete:
+		return http.StatusNoContent
+	default:
+		return http.StatusOK
+	}
+}
@@ -0,0 +1,123 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"dev11/pkg/models"
+)
+
+func TestHandler_ApplyUserEvents(t *testing.T) {
+	ts := newTestServer(t, models.User{Id: "alice", TimeZone: "Europe/Moscow"}, models.User{Id: "bob", TimeZone: "Europe/Moscow"})
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	review, err := ts.service.CreateEvent("alice", models.Event{Name: "review", Date: start, End: &end})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := 1
+
+	tests := []struct {
+		name      string
+		caller    string
+		body      string
+		code      int
+		statuses  []int
+		operation *int
+		field     string
+	}{
+		{
+			name:     "per operation",
+			caller:   "alice",
+			body:     `{"operations":[{"op":"create","event":{"name":"standup","date":"2024-03-05T10:00:00+03:00"}},{"op":"create","event":{"name":"overlap","date":"2024-03-04T12:30:00+03:00","duration":"1h"}},{"op":"update","eventId":"` + review.Id + `","version":1,"event":{"name":"review","date":"2024-03-06"}},{"op":"delete","eventId":"unknown"}]}`,
+			code:     http.StatusOK,
+			statuses: []int{http.StatusCreated, http.StatusConflict, http.StatusOK, http.StatusNotFound},
+		},
+		{
+			name:      "atomic",
+			caller:    "alice",
+			body:      `{"atomic":true,"operations":[{"op":"create","event":{"name":"retro","date":"2024-03-07"}},{"op":"delete","eventId":"` + review.Id + `","version":1}]}`,
+			code:      http.StatusPreconditionFailed,
+			operation: &second,
+		},
+		{
+			name:     "atomic success",
+			caller:   "alice",
+			body:     `{"atomic":true,"operations":[{"op":"delete","eventId":"` + review.Id + `","version":2}]}`,
+			code:     http.StatusOK,
+			statuses: []int{http.StatusNoContent},
+		},
+		{
+			name:     "unknown time zone",
+			caller:   "alice",
+			body:     `{"operations":[{"op":"create","event":{"name":"x","date":"2024-03-08","timezone":"Mars/Base"}},{"op":"delete","eventId":"unknown"}]}`,
+			code:     http.StatusOK,
+			statuses: []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			name:      "atomic unknown time zone",
+			caller:    "alice",
+			body:      `{"atomic":true,"operations":[{"op":"delete","eventId":"unknown"},{"op":"create","event":{"name":"x","date":"2024-03-08","timezone":"Mars/Base"}}]}`,
+			code:      http.StatusBadRequest,
+			operation: &second,
+		},
+		{
+			name:   "invalid event",
+			caller: "alice",
+			body:   `{"operations":[{"op":"delete","eventId":"a"},{"op":"create","event":{"date":"2024-03-07"}}]}`,
+			code:   http.StatusBadRequest,
+			field:  "operations[1].event.name",
+		},
+		{"missing event", "alice", `{"operations":[{"op":"update","eventId":"a"}]}`, http.StatusBadRequest, nil, nil, "operations[0].event"},
+		{"unknown operation", "alice", `{"operations":[{"op":"move","eventId":"a"}]}`, http.StatusBadRequest, nil, nil, "operations[0].op"},
+		{"no operations", "alice", `{"atomic":true}`, http.StatusBadRequest, nil, nil, "operations"},
+		{"foreign calendar", "bob", `{"operations":[{"op":"delete","eventId":"a"}]}`, http.StatusForbidden, nil, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := ts.do(tt.caller, "POST", "/users/alice/events:batch", tt.body)
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body)
+			}
+			if tt.code != http.StatusOK {
+				var problem problemOutput
+				if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+					t.Fatal(err)
+				}
+				if tt.operation != nil && (problem.Operation == nil || *problem.Operation != *tt.operation) {
+					t.Errorf("operation = %v, want %d", problem.Operation, *tt.operation)
+				}
+				if tt.field != "" && (len(problem.Fields) == 0 || problem.Fields[0].Field != tt.field) {
+					t.Errorf("fields = %+v, want %s", problem.Fields, tt.field)
+				}
+				return
+			}
+
+			var output struct {
+				Result batchResult `json:"result"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &output); err != nil {
+				t.Fatal(err)
+			}
+			applied := 0
+			for i, res := range output.Result.Results {
+				if i < len(tt.statuses) && res.Status != tt.statuses[i] {
+					t.Errorf("results[%d].status = %d, want %d", i, res.Status, tt.statuses[i])
+				}
+				if res.Error == nil {
+					applied++
+				}
+			}
+			if len(output.Result.Results) != len(tt.statuses) || output.Result.Applied != applied {
+				t.Errorf("result = %+v", output.Result)
+			}
+		})
+	}
+
+	events, _ := ts.service.GetEvents("alice")
+	if len(events) != 1 || events[0].Name != "standup" || events[0].TimeZone != "Europe/Moscow" {
+		t.Errorf("events after the batches = %+v", events)
+	}
+}
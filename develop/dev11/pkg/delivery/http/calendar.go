@@ -0,0 +1,84 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+
+	"dev11/pkg/ical"
+	"dev11/pkg/models"
+)
+
+const maxCalendarSize = 10 << 20
+
+func (h *Handler) exportCalendar(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("id")
+	events, err := h.service.GetEvents(userId)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	// encoded first, so a failure is still reported with its status
+	var calendar bytes.Buffer
+	if err = ical.Encode(&calendar, "dev11 calendar of user "+userId, events); err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="calendar.ics"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = calendar.WriteTo(w)
+}
+
+// importCalendar accepts an .ics file either as the request body or as the
+// "file" field of a multipart form.
+func (h *Handler) importCalendar(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxCalendarSize)
+
+	var body io.Reader = r.Body
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			h.httpErrorResponse(w, r, models.NewError(models.ErrValidation, err))
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	decoded, err := ical.Decode(body)
+	if err != nil {
+		h.httpErrorResponse(w, r, models.NewError(models.ErrValidation, err))
+		return
+	}
+
+	result := importResult{Errors: make([]importError, 0)}
+	events := make([]models.Event, 0, len(decoded))
+	indexes := make([]int, 0, len(decoded))
+	for i, e := range decoded {
+		if e.Err != nil {
+			result.Errors = append(result.Errors, newImportError(i, e.Event.UID, e.Err))
+			continue
+		}
+		events = append(events, e.Event)
+		indexes = append(indexes, i)
+	}
+
+	errs, err := h.actingService(r).ImportEvents(r.PathValue("id"), events)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	for i, err := range errs {
+		if err != nil {
+			result.Errors = append(result.Errors, newImportError(indexes[i], events[i].UID, err))
+			continue
+		}
+		result.Imported++
+	}
+
+	h.httpImportResponse(w, http.StatusOK, result)
+}
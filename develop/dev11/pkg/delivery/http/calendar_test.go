@@ -0,0 +1,136 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dev11/pkg/models"
+)
+
+func TestHandler_ImportCalendar(t *testing.T) {
+	ts := newTestServer(t, models.User{Id: "alice"}, models.User{Id: "bob"})
+	const calendar = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\nUID:planning@example.com\r\nDTSTART:20240304T090000Z\r\nDTEND:20240304T100000Z\r\nSUMMARY:planning\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:broken@example.com\r\nSUMMARY:no start\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	fw, err := mw.CreateFormFile("file", "calendar.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = fw.Write([]byte(strings.NewReplacer("planning", "retro", "20240304", "20240305").Replace(calendar)))
+	mw.Close()
+
+	tests := []struct {
+		name         string
+		caller       string
+		contentType  string
+		body         string
+		want         int
+		wantImported int
+		wantErrors   []int
+	}{
+		{"calendar", "alice", "text/calendar", calendar, http.StatusOK, 1, []int{1}},
+		{"multipart form", "alice", mw.FormDataContentType(), form.String(), http.StatusOK, 1, []int{1}},
+		{"same calendar again", "alice", "text/calendar", calendar, http.StatusOK, 1, []int{1}},
+		{"not a calendar", "alice", "text/calendar", "BEGIN:VEVENT\r\nEND:VEVENT\r\n", http.StatusBadRequest, 0, nil},
+		{"unterminated calendar", "alice", "text/calendar", "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\n", http.StatusBadRequest, 0, nil},
+		{"form without file", "alice", mw.FormDataContentType(), "", http.StatusBadRequest, 0, nil},
+		{"foreign calendar", "bob", "text/calendar", calendar, http.StatusForbidden, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/users/alice/import", strings.NewReader(tt.body))
+			r.Header.Set("Authorization", "Bearer "+ts.keys[tt.caller])
+			r.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			ts.routes.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			var output importOutput
+			if err := json.Unmarshal(w.Body.Bytes(), &output); err != nil {
+				t.Fatal(err)
+			}
+			var indexes []int
+			for _, e := range output.Result.Errors {
+				indexes = append(indexes, e.Index)
+			}
+			if output.Result.Imported != tt.wantImported || len(indexes) != len(tt.wantErrors) {
+				t.Fatalf("result = %+v, want %d imported and errors of %v", output.Result, tt.wantImported, tt.wantErrors)
+			}
+			for i := range indexes {
+				if indexes[i] != tt.wantErrors[i] {
+					t.Errorf("errors = %+v, want errors of %v", output.Result.Errors, tt.wantErrors)
+				}
+			}
+		})
+	}
+
+	w := ts.do("alice", "GET", "/users/alice/calendar.ics", "")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/calendar") {
+		t.Fatalf("export: status = %d, Content-Type = %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	for _, name := range []string{"SUMMARY:planning", "SUMMARY:retro"} {
+		if strings.Count(w.Body.String(), name) != 1 {
+			t.Errorf("export does not contain %s once:\n%s", name, w.Body)
+		}
+	}
+}
+
+func TestHandler_CalendarFeedToken(t *testing.T) {
+	ts := newTestServer(t, models.User{Id: "alice"}, models.User{Id: "bob"})
+	if w := ts.do("", "GET", "/users/alice/calendar.ics?token=guess", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("status before a token is issued = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if w := ts.do("bob", "POST", "/users/alice/feed-token", ""); w.Code != http.StatusForbidden {
+		t.Errorf("status of another user resetting the token = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	reset := func() string {
+		w := ts.do("alice", "POST", "/users/alice/feed-token", "")
+		var output struct {
+			Result struct {
+				FeedToken string `json:"feedToken"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &output); err != nil || w.Code != http.StatusOK || output.Result.FeedToken == "" {
+			t.Fatalf("reset: status = %d, error = %v: %s", w.Code, err, w.Body)
+		}
+		return output.Result.FeedToken
+	}
+	old, token := reset(), reset()
+
+	tests := []struct {
+		name   string
+		caller string
+		target string
+		want   int
+	}{
+		{"token", "", "/users/alice/calendar.ics?token=" + token, http.StatusOK},
+		{"replaced token", "", "/users/alice/calendar.ics?token=" + old, http.StatusUnauthorized},
+		{"token of another user", "", "/users/bob/calendar.ics?token=" + token, http.StatusUnauthorized},
+		{"missing user", "", "/users/carol/calendar.ics?token=" + token, http.StatusUnauthorized},
+		{"no token", "", "/users/alice/calendar.ics", http.StatusUnauthorized},
+		{"API key wins over the token", "bob", "/users/alice/calendar.ics?token=" + token, http.StatusForbidden},
+		{"token for other routes", "", "/users/alice/events?token=" + token, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := ts.do(tt.caller, "GET", tt.target, "")
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"dev11/pkg/models"
+)
+
+// eventETag returns the strong entity tag of the event version.
+func eventETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// expectedVersion returns the current version of the event if it matches
+// the If-Match header or 0 if the request has no If-Match header. The
+// version is passed to the service, which checks it again atomically.
+func (h *Handler) expectedVersion(r *http.Request, userId, eventId string) (int64, error) {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return 0, nil
+	}
+	event, err := h.service.GetEvent(userId, eventId)
+	if err != nil {
+		return 0, err
+	}
+	if !etagMatches(ifMatch, eventETag(event.Version), false) {
+		return 0, models.Errorf(models.ErrPreconditionFailed,
+			"event with id = %s has ETag %s", eventId, eventETag(event.Version))
+	}
+	return event.Version, nil
+}
+
+// etagMatches reports whether etag is in the list of entity tags of an
+// If-Match or If-None-Match header. Weak tags match only if weak is set.
+func etagMatches(header, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = tag[2:]
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// etagged sets the ETag of successful responses to the hash of their body
+// and replies 304 Not Modified if it matches If-None-Match, so clients can
+// poll lists cheaply.
+func (h *Handler) etagged(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		buf := &bufferedResponse{header: make(http.Header), status: http.StatusOK}
+		next(buf, r)
+
+		header := w.Header()
+		for k, v := range buf.header {
+			header[k] = v
+		}
+		if buf.status == http.StatusOK {
+			sum := sha256.Sum256(buf.body.Bytes())
+			etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+			header.Set("ETag", etag)
+			if etagMatches(r.Header.Get("If-None-Match"), etag, true) {
+				header.Del("Content-Type")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		w.WriteHeader(buf.status)
+		_, _ = w.Write(buf.body.Bytes())
+	}
+}
+
+// bufferedResponse keeps the response in memory.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	b.status = status
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
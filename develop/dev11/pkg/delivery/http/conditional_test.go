@@ -0,0 +1,95 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dev11/pkg/models"
+	"dev11/pkg/repository/cache"
+	"dev11/pkg/service"
+)
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		weak   bool
+		want   bool
+	}{
+		{`"1"`, false, true},
+		{`"2", "1"`, false, true},
+		{`"2"`, false, false},
+		{`*`, false, true},
+		{`W/"1"`, false, false},
+		{`W/"1"`, true, true},
+		{``, true, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, `"1"`, tt.weak); got != tt.want {
+			t.Errorf("etagMatches(%q, weak=%v) = %v, want %v", tt.header, tt.weak, got, tt.want)
+		}
+	}
+}
+
+func TestHandler_ConditionalRequests(t *testing.T) {
+	s := service.NewService(cache.NewUserCache(cache.NewCache()))
+	event, err := s.CreateEvent("1", models.Event{Name: "standup", Date: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := NewHandler(s, "secret").InitRoutes()
+	eventURL := "/users/1/events/" + event.Id
+	body := `{"name":"daily","date":"2024-03-04"}`
+
+	var listETag string
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		header   string
+		value    func() string
+		want     int
+		wantETag string
+	}{
+		{"get", "GET", eventURL, "", nil, http.StatusOK, `"1"`},
+		{"list", "GET", "/users/1/events", "", nil, http.StatusOK, ""},
+		{"list not modified", "GET", "/users/1/events", "If-None-Match", func() string { return listETag }, http.StatusNotModified, ""},
+		{"matching update", "PUT", eventURL, "If-Match", func() string { return `"1"` }, http.StatusOK, `"2"`},
+		{"stale update", "PUT", eventURL, "If-Match", func() string { return `"1"` }, http.StatusPreconditionFailed, ""},
+		{"stale patch", "PATCH", eventURL, "If-Match", func() string { return `"1"` }, http.StatusPreconditionFailed, ""},
+		{"list modified", "GET", "/users/1/events", "If-None-Match", func() string { return listETag }, http.StatusOK, ""},
+		{"unconditional update", "PUT", eventURL, "", nil, http.StatusOK, `"3"`},
+		{"stale delete", "DELETE", eventURL, "If-Match", func() string { return `"2"` }, http.StatusPreconditionFailed, ""},
+		{"matching delete", "DELETE", eventURL, "If-Match", func() string { return `"3"` }, http.StatusNoContent, ""},
+		{"create", "POST", "/users/1/events", "", nil, http.StatusCreated, `"1"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reqBody *strings.Reader
+			if tt.method != "GET" && tt.method != "DELETE" {
+				reqBody = strings.NewReader(body)
+			} else {
+				reqBody = strings.NewReader("")
+			}
+			r := httptest.NewRequest(tt.method, tt.target, reqBody)
+			r.Header.Set("Authorization", "Bearer secret")
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.value())
+			}
+			w := httptest.NewRecorder()
+			routes.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if got := w.Header().Get("ETag"); tt.wantETag != "" && got != tt.wantETag {
+				t.Errorf("ETag = %s, want %s", got, tt.wantETag)
+			}
+			if tt.target == "/users/1/events" && w.Code == http.StatusOK {
+				listETag = w.Header().Get("ETag")
+			}
+		})
+	}
+}
@@ -1,16 +1,85 @@
 package http
 
 import (
-	"fmt"
+	"errors"
+	"log/slog"
 	"net/http"
+
+	"dev11/pkg/models"
+	"dev11/pkg/service"
 )
 
-func httpMethodErrorCheck(w http.ResponseWriter, expectedMethod, gotMethod string) bool {
-	if gotMethod != expectedMethod {
-		http.Error(
-			w,
-			fmt.Sprintf("expected: %s, got instead: %s", expectedMethod, gotMethod),
-			http.StatusBadRequest)
+// httpErrorResponse writes err as an RFC 7807 problem with the status code
+// of its kind.
+func (h *Handler) httpErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	problem := errorProblem(r, err)
+	if problem.Status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="dev11"`)
+	}
+	h.httpProblemResponse(w, problem)
+}
+
+// problemTypeQuotaExceeded is the type of problems reporting that a quota
+// of the user is exceeded, which tells them apart from other problems with
+// the status code 403.
+const problemTypeQuotaExceeded = "/problems/quota-exceeded"
+
+// errorProblem returns err as an RFC 7807 problem with the status code of
+// its kind. Errors of an unknown kind are logged and reported as 500
+// without details, other errors are logged at the debug level.
+func errorProblem(r *http.Request, err error) problemOutput {
+	status := errorStatusCode(err)
+	problem := newProblemOutput(status, err.Error(), r.URL.Path)
+	if errors.Is(err, models.ErrQuotaExceeded) {
+		problem.Type = problemTypeQuotaExceeded
+	}
+
+	var fields validationError
+	var conflict *service.ConflictError
+	switch {
+	case errors.As(err, &fields):
+		problem.Fields = fields
+	case errors.As(err, &conflict):
+		problem.Conflicts = conflict.Conflicts
+	}
+	var batch *service.BatchError
+	if errors.As(err, &batch) {
+		problem.Operation = &batch.Index
+	}
+	level := slog.LevelDebug
+	if status == http.StatusInternalServerError {
+		level = slog.LevelError
+		problem.Detail = ""
+	}
+	slog.LogAttrs(r.Context(), level, "failed to serve request",
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("error", err.Error()),
+	)
+	return problem
+}
+
+// errorStatusCode returns the status code of the kind of err.
+func errorStatusCode(err error) int {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, models.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, models.ErrForbidden), errors.Is(err, models.ErrQuotaExceeded):
+		return http.StatusForbidden
+	case errors.Is(err, models.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, models.ErrRateLimited):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
 	}
-	return gotMethod != expectedMethod
 }
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dev11/pkg/models"
+	"dev11/pkg/service"
+)
+
+func TestHandler_httpErrorResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantDetail string
+		wantType   string
+	}{
+		{"not found", models.Errorf(models.ErrNotFound, "no user"), http.StatusNotFound, "no user", "about:blank"},
+		{"wrapped", fmt.Errorf("get: %w", models.Errorf(models.ErrNotFound, "no user")), http.StatusNotFound, "get: no user", "about:blank"},
+		{"validation", validationError{{Field: "name", Error: "is required"}}, http.StatusBadRequest, "invalid request: name: is required", "about:blank"},
+		{"conflict", &service.ConflictError{}, http.StatusConflict, "event overlaps ", "about:blank"},
+		{"unauthorized", models.Errorf(models.ErrUnauthorized, "invalid API key"), http.StatusUnauthorized, "invalid API key", "about:blank"},
+		{"forbidden", models.Errorf(models.ErrForbidden, "admin only"), http.StatusForbidden, "admin only", "about:blank"},
+		{"quota", models.Errorf(models.ErrQuotaExceeded, "too many events"), http.StatusForbidden, "too many events", problemTypeQuotaExceeded},
+		{"rate limited", models.Errorf(models.ErrRateLimited, "slow down"), http.StatusTooManyRequests, "slow down", "about:blank"},
+		{"too large", &http.MaxBytesError{Limit: 1}, http.StatusRequestEntityTooLarge, "http: request body too large", "about:blank"},
+		{"unknown", errors.New("disk is on fire"), http.StatusInternalServerError, "", "about:blank"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			(&Handler{}).httpErrorResponse(w, httptest.NewRequest(http.MethodGet, "/users/1", nil), tt.err)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			var got problemOutput
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			want := newProblemOutput(tt.wantStatus, tt.wantDetail, "/users/1")
+			want.Type = tt.wantType
+			if got.Type != want.Type || got.Title != want.Title || got.Status != want.Status ||
+				got.Detail != want.Detail || got.Instance != want.Instance {
+				t.Errorf("problem = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"dev11/pkg/models"
+)
+
+func (h *Handler) listUserEvents(w http.ResponseWriter, r *http.Request) {
+	events, err := h.service.GetEvents(r.PathValue("id"))
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	h.httpEventsResponse(w, http.StatusOK, events)
+}
+
+func (h *Handler) createUserEvent(w http.ResponseWriter, r *http.Request) {
+	input, err := h.decodeEventBodyJSON(r)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	userId := r.PathValue("id")
+	loc, err := h.service.Location(userId, input.TimeZone)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	event, err := input.event("", loc)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	event, err = h.actingService(r).CreateEvent(userId, event)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/users/"+userId+"/events/"+event.Id)
+	h.httpEventResponse(w, http.StatusCreated, event)
+}
+
+// getUserEvent returns the event or, if the occurrence parameter is set, a
+// single occurrence of the series.
+func (h *Handler) getUserEvent(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("id")
+	occurrence, err := h.getOccurrence(r, userId)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	event, err := h.getEventOrOccurrence(userId, r.PathValue("eventId"), occurrence)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	h.httpEventResponse(w, http.StatusOK, *event)
+}
+
+// replaceUserEvent replaces the event. With If-Match the event is replaced
+// only if its ETag matches.
+func (h *Handler) replaceUserEvent(w http.ResponseWriter, r *http.Request) {
+	userId, eventId := r.PathValue("id"), r.PathValue("eventId")
+	occurrence, err := h.getOccurrence(r, userId)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	version, err := h.expectedVersion(r, userId, eventId)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	input, err := h.decodeEventBodyJSON(r)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	loc, err := h.service.Location(userId, input.TimeZone)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	event, err := input.event(eventId, loc)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	event.Version = version
+	event, err = h.updateEventOrOccurrence(r, userId, event, occurrence)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	h.httpEventResponse(w, http.StatusOK, event)
+}
+
+// patchUserEvent applies the JSON Merge Patch from the request body to the
+// event. Bare dates are resolved in the time zone of the event.
+func (h *Handler) patchUserEvent(w http.ResponseWriter, r *http.Request) {
+	userId, eventId := r.PathValue("id"), r.PathValue("eventId")
+	occurrence, err := h.getOccurrence(r, userId)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	version, err := h.expectedVersion(r, userId, eventId)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	input, err := h.decodeEventPatchBodyJSON(r)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	patch := func(event *models.Event) error {
+		loc, err := h.service.Location(userId, input.timeZone(event))
+		if err != nil {
+			return err
+		}
+		return input.apply(event, loc)
+	}
+	var updated models.Event
+	if occurrence != nil {
+		updated, err = h.patchOccurrence(r, userId, eventId, *occurrence, patch)
+	} else {
+		updated, err = h.actingService(r).PatchEvent(userId, eventId, version, patch)
+	}
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	h.httpEventResponse(w, http.StatusOK, updated)
+}
+
+// patchOccurrence changes the occurrence of the series with patch and
+// detaches it from the series.
+func (h *Handler) patchOccurrence(
+	r *http.Request,
+	userId, eventId string,
+	occurrence time.Time,
+	patch func(event *models.Event) error,
+) (models.Event, error) {
+	event, err := h.service.GetOccurrence(userId, eventId, occurrence)
+	if err != nil {
+		return models.Event{}, err
+	}
+	if err = patch(event); err != nil {
+		return models.Event{}, err
+	}
+	return h.updateEventOrOccurrence(r, userId, *event, &occurrence)
+}
+
+// deleteUserEvent deletes the event or, if the occurrence parameter is set,
+// a single occurrence of the series.
+func (h *Handler) deleteUserEvent(w http.ResponseWriter, r *http.Request) {
+	userId, eventId := r.PathValue("id"), r.PathValue("eventId")
+	occurrence, err := h.getOccurrence(r, userId)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	version, err := h.expectedVersion(r, userId, eventId)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	if occurrence != nil {
+		err = h.actingService(r).DeleteOccurrence(userId, eventId, *occurrence)
+	} else {
+		err = h.actingService(r).DeleteEvent(userId, eventId, version)
+	}
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getOccurrence returns the start of the occurrence of a series the request
+// is addressed to. A bare date is resolved in the time zone from the tz
+// parameter or in the default time zone of the user.
+func (h *Handler) getOccurrence(r *http.Request, userId string) (*time.Time, error) {
+	occurrence, err := getOccurrenceParam(r.URL)
+	if err != nil {
+		return nil, err
+	}
+	if occurrence == nil {
+		return nil, nil
+	}
+	loc, err := h.service.Location(userId, r.URL.Query().Get("tz"))
+	if err != nil {
+		return nil, err
+	}
+	return occurrence.OptionalIn(loc), nil
+}
+
+func (h *Handler) getEventOrOccurrence(
+	userId, eventId string,
+	occurrence *time.Time,
+) (*models.Event, error) {
+	if occurrence != nil {
+		return h.service.GetOccurrence(userId, eventId, *occurrence)
+	}
+	return h.service.GetEvent(userId, eventId)
+}
+
+func (h *Handler) updateEventOrOccurrence(
+	r *http.Request,
+	userId string,
+	event models.Event,
+	occurrence *time.Time,
+) (models.Event, error) {
+	if occurrence != nil {
+		seriesId := event.Id
+		if event.SeriesId != "" {
+			seriesId = event.SeriesId
+		}
+		event.Id, event.SeriesId, event.RecurrenceId, event.Version = "", "", nil, 0
+		return h.actingService(r).UpdateOccurrence(userId, seriesId, *occurrence, event)
+	}
+	return h.actingService(r).UpdateEvent(userId, event)
+}
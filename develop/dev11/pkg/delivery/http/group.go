@@ -0,0 +1,99 @@
+package http
+
+import (
+	"net/http"
+
+	"dev11/pkg/models"
+)
+
+func (h *Handler) createGroup(w http.ResponseWriter, r *http.Request) {
+	input, err := h.decodeCreateGroupBodyJSON(r)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	group, err := h.service.CreateGroup(input.group())
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/groups/"+group.Id)
+	h.httpGroupResponse(w, http.StatusCreated, *group)
+}
+
+func (h *Handler) listGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.service.GetGroups()
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	h.httpGroupsResponse(w, http.StatusOK, groups)
+}
+
+// getGroup returns the group to an admin or a member of the group.
+func (h *Handler) getGroup(w http.ResponseWriter, r *http.Request) {
+	user := principal(r)
+	if user == nil {
+		h.httpErrorResponse(w, r, models.Errorf(models.ErrUnauthorized, "authentication required"))
+		return
+	}
+	group, err := h.service.GetGroup(r.PathValue("groupId"))
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	if !user.IsAdmin() && !group.HasMember(user.Id) {
+		h.httpErrorResponse(w, r, models.Errorf(models.ErrForbidden,
+			"access to group with id = %s is forbidden", group.Id))
+		return
+	}
+
+	h.httpGroupResponse(w, http.StatusOK, *group)
+}
+
+func (h *Handler) replaceGroup(w http.ResponseWriter, r *http.Request) {
+	input, err := h.decodeGroupBodyJSON(r)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	group := models.Group{Id: r.PathValue("groupId"), Name: input.Name, Members: input.Members}
+	updated, err := h.service.UpdateGroup(group)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	h.httpGroupResponse(w, http.StatusOK, *updated)
+}
+
+func (h *Handler) deleteGroup(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteGroup(r.PathValue("groupId")); err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// shareCalendar replaces the groups whose members can read the calendar of
+// the user.
+func (h *Handler) shareCalendar(w http.ResponseWriter, r *http.Request) {
+	input, err := h.decodeShareBodyJSON(r)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	user, err := h.service.ShareCalendar(r.PathValue("id"), input.Groups)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	h.httpUserResponse(w, http.StatusOK, *user)
+}
@@ -2,26 +2,87 @@ package http
 
 import (
 	"net/http"
+	"sync/atomic"
 
+	"dev11/pkg/ratelimit"
 	"dev11/pkg/service"
 )
 
 type Handler struct {
 	service service.User
+	// adminKey authenticates requests as an admin, disabled if empty.
+	adminKey string
+	metrics  *httpMetrics
+	notReady atomic.Bool
+
+	readLimiter  *ratelimit.Limiter
+	writeLimiter *ratelimit.Limiter
+}
+
+func NewHandler(s service.User, adminKey string) *Handler {
+	return &Handler{service: s, adminKey: adminKey, metrics: newHTTPMetrics(s)}
 }
 
-func NewHandler(s service.User) *Handler {
-	return &Handler{s}
+// endpoint is a pattern of http.ServeMux with its handler. Every endpoint is
+// described in the OpenAPI document served at /openapi.json.
+type endpoint struct {
+	pattern string
+	handler http.Handler
+}
+
+func (h *Handler) routes() []endpoint {
+	return []endpoint{
+		{"GET /openapi.json", http.HandlerFunc(h.openAPI)},
+		{"GET /metrics", h.metrics.registry},
+		{"GET /healthz", http.HandlerFunc(h.healthz)},
+		{"GET /readyz", http.HandlerFunc(h.readyz)},
+		{"POST /users", http.HandlerFunc(h.createUser)},
+		{"GET /users", h.adminOnly(h.listUsers)},
+		{"GET /users/{id}", h.ownerOnly(h.getUser)},
+		{"PATCH /users/{id}", h.ownerOnly(h.setUserTimeZone)},
+		{"DELETE /users/{id}", h.ownerOnly(h.deleteUser)},
+		{"GET /users/{id}/events", h.readersOnly(h.etagged(h.listUserEvents))},
+		{"POST /users/{id}/events", h.ownerOnly(h.createUserEvent)},
+		{"POST /users/{id}/events:batch", h.ownerOnly(h.applyUserEvents)},
+		{"GET /users/{id}/events/stream", h.readersOnly(h.streamUserEvents)},
+		{"GET /users/{id}/events/search", h.readersOnly(h.searchUserEvents)},
+		{"GET /users/{id}/events/{eventId}", h.readersOnly(h.getUserEvent)},
+		{"PUT /users/{id}/events/{eventId}", h.ownerOnly(h.replaceUserEvent)},
+		{"PATCH /users/{id}/events/{eventId}", h.ownerOnly(h.patchUserEvent)},
+		{"DELETE /users/{id}/events/{eventId}", h.ownerOnly(h.deleteUserEvent)},
+		{"GET /users/{id}/events/{eventId}/history", h.ownerOnly(h.getEventHistory)},
+		{"GET /users/{id}/trash", h.ownerOnly(h.listTrash)},
+		{"POST /users/{id}/trash/{eventId}/restore", h.ownerOnly(h.restoreEvent)},
+		{"GET /users/{id}/free-busy", h.readersOnly(h.getFreeBusy)},
+		{"GET /users/{id}/calendar.ics", h.feedReaders(h.exportCalendar)},
+		{"POST /users/{id}/feed-token", h.ownerOnly(h.resetFeedToken)},
+		{"POST /users/{id}/import", h.ownerOnly(h.importCalendar)},
+		{"PUT /users/{id}/shared-with", h.ownerOnly(h.shareCalendar)},
+		{"GET /users/{id}/invitations", h.ownerOnly(h.listInvitations)},
+		{"PUT /users/{id}/invitations/{organizer}/{eventId}", h.ownerOnly(h.respondToInvitation)},
+		{"POST /groups", h.adminOnly(h.createGroup)},
+		{"GET /groups", h.adminOnly(h.listGroups)},
+		{"GET /groups/{groupId}", http.HandlerFunc(h.getGroup)},
+		{"PUT /groups/{groupId}", h.adminOnly(h.replaceGroup)},
+		{"DELETE /groups/{groupId}", h.adminOnly(h.deleteGroup)},
+		{"GET /events", h.etagged(h.getEventsInRange)},
+
+		// legacy RPC-style endpoints
+		{"POST /create_event", http.HandlerFunc(h.createEvent)},
+		{"POST /update_event", http.HandlerFunc(h.updateEvent)},
+		{"POST /delete_event", http.HandlerFunc(h.deleteEvent)},
+		{"GET /events_for_day", h.etagged(h.getEventsForDay)},
+		{"GET /events_for_week", h.etagged(h.getEventsForWeek)},
+		{"GET /events_for_month", h.etagged(h.getEventsForMonth)},
+	}
 }
 
 func (h *Handler) InitRoutes() http.Handler {
 	mux := http.NewServeMux()
-	mux.HandleFunc("/create_event", h.createEvent)
-	mux.HandleFunc("/update_event", h.updateEvent)
-	mux.HandleFunc("/delete_event", h.deleteEvent)
-	mux.HandleFunc("/events_for_day", h.getEventsForDay)
-	mux.HandleFunc("/events_for_week", h.getEventsForWeek)
-	mux.HandleFunc("/events_for_month", h.getEventsForMonth)
-	handler := Log(mux)
-	return handler
+	for _, r := range h.routes() {
+		mux.Handle(r.pattern, r.handler)
+	}
+	handler := h.recoverPanic(h.Authenticate(h.rateLimit(mux)))
+	handler = h.instrument(mux, handler)
+	return RequestID(h.accessLog(mux, handler))
 }
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dev11/pkg/models"
+	"dev11/pkg/repository/cache"
+	"dev11/pkg/service"
+)
+
+// testAdminKey is the admin key of test servers, the key of the "admin"
+// caller.
+const testAdminKey = "secret"
+
+// testServer serves the routes of a handler over an in-memory service.
+type testServer struct {
+	service *service.Service
+	routes  http.Handler
+	// keys are the API keys of the callers by user id.
+	keys map[string]string
+}
+
+// newTestServer returns a server with the users created and the admin key
+// set.
+func newTestServer(t *testing.T, users ...models.User) *testServer {
+	t.Helper()
+	s := service.NewService(cache.NewUserCache(cache.NewCache()))
+	keys := map[string]string{"admin": testAdminKey}
+	for _, u := range users {
+		_, key, err := s.CreateUser(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[u.Id] = key
+	}
+	return &testServer{service: s, routes: NewHandler(s, testAdminKey).InitRoutes(), keys: keys}
+}
+
+// do serves the request of the caller, anonymous if empty, and returns the
+// response.
+func (ts *testServer) do(caller, method, target, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if caller != "" {
+		r.Header.Set("Authorization", "Bearer "+ts.keys[caller])
+	}
+	w := httptest.NewRecorder()
+	ts.routes.ServeHTTP(w, r)
+	return w
+}
@@ -0,0 +1,37 @@
+package http
+
+import (
+	"net/http"
+)
+
+const (
+	healthOK          = "ok"
+	healthUnavailable = "unavailable"
+	healthShutdown    = "shutting down"
+)
+
+// SetReady sets whether the server accepts traffic. The server is ready
+// from the start and becomes not ready when it shuts down, so that load
+// balancers stop routing requests to it.
+func (h *Handler) SetReady(ready bool) {
+	h.notReady.Store(!ready)
+}
+
+// healthz reports whether the server is alive, which requires the storage
+// to be available.
+func (h *Handler) healthz(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Ping(); err != nil {
+		h.httpHealthResponse(w, http.StatusServiceUnavailable, healthUnavailable, err.Error())
+		return
+	}
+	h.httpHealthResponse(w, http.StatusOK, healthOK, "")
+}
+
+// readyz reports whether the server accepts traffic.
+func (h *Handler) readyz(w http.ResponseWriter, r *http.Request) {
+	if h.notReady.Load() {
+		h.httpHealthResponse(w, http.StatusServiceUnavailable, healthShutdown, "")
+		return
+	}
+	h.healthz(w, r)
+}
@@ -0,0 +1,33 @@
+package http
+
+import "net/http"
+
+// listInvitations returns the events of other users the user is invited to.
+func (h *Handler) listInvitations(w http.ResponseWriter, r *http.Request) {
+	events, err := h.service.GetInvitations(r.PathValue("id"))
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	h.httpEventsResponse(w, http.StatusOK, events)
+}
+
+// respondToInvitation sets the status of the user in the attendees of the
+// event of the organizer.
+func (h *Handler) respondToInvitation(w http.ResponseWriter, r *http.Request) {
+	input, err := h.decodeInvitationBodyJSON(r)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	event, err := h.actingService(r).RespondToInvitation(
+		r.PathValue("id"), r.PathValue("organizer"), r.PathValue("eventId"), input.Status)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	h.httpEventResponse(w, http.StatusOK, event)
+}
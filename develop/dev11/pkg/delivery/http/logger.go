@@ -1,15 +1,104 @@
 package http
 
 import (
-	"log"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"runtime/debug"
 	"time"
+
+	"dev11/pkg/logging"
+)
+
+const (
+	requestIdHeader = "X-Request-ID"
+	// maxRequestIdLength limits ids of requests accepted from clients.
+	maxRequestIdLength = 128
 )
 
-func Log(h http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+// RequestID passes the id of the request from the X-Request-ID header, or a
+// new one if the header is missing or invalid, in the context of the
+// request and returns it in the response.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIdHeader)
+		if !validRequestId(id) {
+			id = newRequestId()
+		}
+		w.Header().Set(requestIdHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+func validRequestId(id string) bool {
+	if id == "" || len(id) > maxRequestIdLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// accessLog logs every request served by next with the route of mux it
+// matches.
+func (h *Handler) accessLog(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		h.ServeHTTP(w, req)
-		log.Printf("[%s] %s  at: %s", req.Method, req.RequestURI, start)
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		status := sw.statusCode()
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.LogAttrs(r.Context(), level, "request",
+			slog.String("method", r.Method),
+			slog.String("route", route(mux, r)),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int64("bytes", sw.bytes),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("remote_addr", r.RemoteAddr),
+		)
+	})
+}
+
+// recoverPanic turns a panic in next into a 500 problem response and logs
+// it with the stack.
+func (h *Handler) recoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				// the server aborts the response on purpose
+				panic(v)
+			}
+			slog.ErrorContext(r.Context(), "panic serving request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("error", fmt.Sprint(v)),
+				slog.String("stack", string(debug.Stack())),
+			)
+			if sw.status == 0 {
+				h.httpProblemResponse(w, newProblemOutput(http.StatusInternalServerError, "", r.URL.Path))
+			}
+		}()
+		next.ServeHTTP(sw, r)
 	})
 }
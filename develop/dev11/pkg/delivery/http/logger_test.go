@@ -0,0 +1,115 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dev11/pkg/logging"
+	"dev11/pkg/repository/cache"
+	"dev11/pkg/service"
+)
+
+// captureLog makes slog write JSON records into the returned buffer until
+// the end of the test.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	prev := slog.Default()
+	slog.SetDefault(slog.New(logging.NewHandler(slog.NewJSONHandler(buf, nil))))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return buf
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"from the client", "abc-123", true},
+		{"missing", "", false},
+		{"with spaces", "a b", false},
+		{"too long", strings.Repeat("a", maxRequestIdLength+1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fromContext string
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fromContext = logging.RequestID(r.Context())
+			}))
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set(requestIdHeader, tt.header)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			got := w.Header().Get(requestIdHeader)
+			if got == "" || got != fromContext {
+				t.Fatalf("response id %q, context id %q", got, fromContext)
+			}
+			if (got == tt.header) != tt.keep {
+				t.Errorf("id %q, header %q, keep = %v", got, tt.header, tt.keep)
+			}
+		})
+	}
+}
+
+func TestHandler_AccessLog(t *testing.T) {
+	buf := captureLog(t)
+	routes := NewHandler(service.NewService(cache.NewUserCache(cache.NewCache())), "secret").InitRoutes()
+	r := httptest.NewRequest("GET", "/users/2/events", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set(requestIdHeader, "req-1")
+	w := httptest.NewRecorder()
+	routes.ServeHTTP(w, r)
+
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("%v: %s", err, buf)
+	}
+	want := map[string]any{
+		"msg":        "request",
+		"method":     "GET",
+		"route":      "/users/{id}/events",
+		"path":       "/users/2/events",
+		"status":     float64(http.StatusNotFound),
+		"bytes":      float64(w.Body.Len()),
+		"request_id": "req-1",
+	}
+	for k, v := range want {
+		if rec[k] != v {
+			t.Errorf("%s = %v, want %v", k, rec[k], v)
+		}
+	}
+	if _, ok := rec["latency_ms"]; !ok {
+		t.Error("latency is not logged")
+	}
+}
+
+func TestHandler_RecoverPanic(t *testing.T) {
+	buf := captureLog(t)
+	h := &Handler{}
+	handler := RequestID(h.recoverPanic(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+	r := httptest.NewRequest("GET", "/panic", nil)
+	r.Header.Set(requestIdHeader, "req-2")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("status = %d, content type = %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("%v: %s", err, buf)
+	}
+	stack, _ := rec["stack"].(string)
+	if rec["error"] != "boom" || rec["request_id"] != "req-2" || !strings.Contains(stack, "TestHandler_RecoverPanic") {
+		t.Errorf("unexpected record %v", rec)
+	}
+}
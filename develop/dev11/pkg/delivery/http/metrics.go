@@ -0,0 +1,113 @@
+package http
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"dev11/pkg/metrics"
+	"dev11/pkg/service"
+)
+
+// unmatchedRoute labels requests which match no route, so that scanning
+// for random paths does not create new series.
+const unmatchedRoute = "unmatched"
+
+type httpMetrics struct {
+	registry *metrics.Registry
+	requests *metrics.CounterVec
+	duration *metrics.HistogramVec
+	inFlight *metrics.Gauge
+}
+
+func newHTTPMetrics(s service.User) *httpMetrics {
+	r := metrics.NewRegistry()
+	m := &httpMetrics{
+		registry: r,
+		requests: r.NewCounterVec("http_requests_total",
+			"Number of HTTP requests by route, method and status code.", "route", "method", "status"),
+		duration: r.NewHistogramVec("http_request_duration_seconds",
+			"Latency of HTTP requests by route, method and status code.", metrics.DefaultBuckets,
+			"route", "method", "status"),
+		inFlight: r.NewGauge("http_requests_in_flight", "Number of HTTP requests being served."),
+	}
+	r.NewGaugeFunc("calendar_users", "Number of users.", func() float64 {
+		users, _, err := s.Stats()
+		if err != nil {
+			return math.NaN()
+		}
+		return float64(users)
+	})
+	r.NewGaugeFunc("calendar_events", "Number of stored events of all users.", func() float64 {
+		_, events, err := s.Stats()
+		if err != nil {
+			return math.NaN()
+		}
+		return float64(events)
+	})
+	return m
+}
+
+// instrument counts requests and measures their latency per route of mux.
+func (h *Handler) instrument(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		h.metrics.inFlight.Inc()
+		defer h.metrics.inFlight.Dec()
+
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		route, status := route(mux, r), strconv.Itoa(sw.statusCode())
+		h.metrics.requests.WithLabelValues(route, r.Method, status).Inc()
+		h.metrics.duration.WithLabelValues(route, r.Method, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// route returns the path pattern of the route of mux the request matches.
+func route(mux *http.ServeMux, r *http.Request) string {
+	_, pattern := mux.Handler(r)
+	if pattern == "" {
+		return unmatchedRoute
+	}
+	// the method is logged on its own
+	_, path, _ := strings.Cut(pattern, " ")
+	return path
+}
+
+// statusWriter records the status code and the size of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the flusher of streams.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *statusWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
@@ -0,0 +1,89 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dev11/pkg/models"
+	"dev11/pkg/repository/cache"
+	"dev11/pkg/service"
+)
+
+func TestHandler_Metrics(t *testing.T) {
+	s := service.NewService(cache.NewUserCache(cache.NewCache()))
+	if _, err := s.CreateEvent("1", models.Event{Name: "standup", Date: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatal(err)
+	}
+	routes := NewHandler(s, "secret").InitRoutes()
+	for _, target := range []string{"/users/1/events", "/users/1/events", "/users/2/events", "/no/such/path"} {
+		r := httptest.NewRequest("GET", target, nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		routes.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	w := httptest.NewRecorder()
+	routes.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`http_requests_total{route="/users/{id}/events",method="GET",status="200"} 2`,
+		`http_requests_total{route="/users/{id}/events",method="GET",status="404"} 1`,
+		`http_requests_total{route="unmatched",method="GET",status="404"} 1`,
+		`http_request_duration_seconds_count{route="/users/{id}/events",method="GET",status="200"} 2`,
+		"http_requests_in_flight 1",
+		"calendar_users 1",
+		"calendar_events 1",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics do not contain %q:\n%s", want, body)
+		}
+	}
+}
+
+// failingRepo is a storage which is unavailable.
+type failingRepo struct {
+	*cache.UserCacheRepo
+}
+
+func (failingRepo) Ping() error {
+	return io.ErrClosedPipe
+}
+
+func TestHandler_Health(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		failing  bool
+		notReady bool
+		want     int
+	}{
+		{"alive", "/healthz", false, false, http.StatusOK},
+		{"ready", "/readyz", false, false, http.StatusOK},
+		{"storage unavailable", "/healthz", true, false, http.StatusServiceUnavailable},
+		{"not ready with unavailable storage", "/readyz", true, false, http.StatusServiceUnavailable},
+		{"alive during shutdown", "/healthz", false, true, http.StatusOK},
+		{"not ready during shutdown", "/readyz", false, true, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := cache.NewUserCache(cache.NewCache())
+			s := service.NewService(repo)
+			if tt.failing {
+				s = service.NewService(failingRepo{repo})
+			}
+			h := NewHandler(s, "")
+			h.SetReady(!tt.notReady)
+			w := httptest.NewRecorder()
+			h.InitRoutes().ServeHTTP(w, httptest.NewRequest("GET", tt.target, nil))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
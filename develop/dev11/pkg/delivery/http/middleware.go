@@ -1,16 +1,202 @@
 package http
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
-	"time"
+	"strconv"
+	"strings"
+
+	"dev11/pkg/models"
+	"dev11/pkg/service"
 )
 
+type contextKey int
+
+const principalKey contextKey = iota
+
+// Authenticate resolves the API key passed either as a bearer token in the
+// Authorization header or in the X-API-Key header into the user making the
+// request. Requests without a key pass through anonymously and are rejected
+// by the routes which require authorization. Invalid keys count against the
+// write limit of the IP address of the request.
+func (h *Handler) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, err := apiKey(r)
+		if err != nil {
+			h.httpErrorResponse(w, r, models.NewError(models.ErrUnauthorized, err))
+			return
+		}
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if h.authenticationLimited(w, r) {
+			return
+		}
+
+		var user *models.User
+		if h.adminKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(h.adminKey)) == 1 {
+			user = &models.User{Role: models.RoleAdmin}
+		} else if user, err = h.service.Authenticate(key); err != nil {
+			if errors.Is(err, models.ErrUnauthorized) {
+				h.limitFailedAuthentication(r)
+			}
+			h.httpErrorResponse(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey, user)))
+	})
+}
+
+func apiKey(r *http.Request) (string, error) {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		scheme, token, _ := strings.Cut(auth, " ")
+		if !strings.EqualFold(scheme, "Bearer") || token == "" {
+			return "", errors.New("authorization scheme must be Bearer")
+		}
+		return strings.TrimSpace(token), nil
+	}
+	return r.Header.Get("X-API-Key"), nil
+}
+
+// principal returns the authenticated user of the request or nil.
+func principal(r *http.Request) *models.User {
+	user, _ := r.Context().Value(principalKey).(*models.User)
+	return user
+}
+
+// authorize checks that the request is allowed to access events of the user.
+func (h *Handler) authorize(r *http.Request, userId string) error {
+	user := principal(r)
+	if user == nil {
+		return models.Errorf(models.ErrUnauthorized, "authentication required")
+	}
+	if !user.IsAdmin() && user.Id != userId {
+		return models.Errorf(models.ErrForbidden, "access to user with id = %s is forbidden", userId)
+	}
+	return nil
+}
+
+// authorizeRead checks that the request is allowed to read events of the
+// user: besides the user and admins, members of the groups the calendar is
+// shared with can read it.
+func (h *Handler) authorizeRead(r *http.Request, userId string) error {
+	err := h.authorize(r, userId)
+	if !errors.Is(err, models.ErrForbidden) {
+		return err
+	}
+	// a missing user is not revealed to those who can not read it
+	ok, readErr := h.service.CanRead(principal(r).Id, userId)
+	if readErr != nil && !errors.Is(readErr, models.ErrNotFound) {
+		return readErr
+	}
+	if !ok {
+		return err
+	}
+	return nil
+}
+
+// actingService returns the service acting on behalf of the user of the
+// request, so that changes are attributed to the user in the histories of
+// events and invitations of a user are shown only to the user, and logging
+// failures with the request id.
+func (h *Handler) actingService(r *http.Request) service.User {
+	s := h.service.WithContext(r.Context())
+	if user := principal(r); user != nil {
+		return s.As(user.Id)
+	}
+	return s
+}
+
+// ownerOnly allows the request only to the user from the path or an admin.
+func (h *Handler) ownerOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h.authorize(r, r.PathValue("id")); err != nil {
+			h.httpErrorResponse(w, r, err)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// readersOnly allows the request to the user from the path, an admin and
+// the users the calendar is shared with.
+func (h *Handler) readersOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h.authorizeRead(r, r.PathValue("id")); err != nil {
+			h.httpErrorResponse(w, r, err)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// feedReaders allows the request to the readers of the calendar and, for
+// calendar applications which can not pass an API key in a header, to
+// anonymous requests with the feed token of the user in the token query
+// parameter. Invalid tokens count against the write limit like API keys.
+func (h *Handler) feedReaders(next http.HandlerFunc) http.HandlerFunc {
+	readers := h.readersOnly(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if token == "" || principal(r) != nil {
+			readers(w, r)
+			return
+		}
+		if h.authenticationLimited(w, r) {
+			return
+		}
+		if err := h.service.AuthenticateFeed(r.PathValue("id"), token); err != nil {
+			if errors.Is(err, models.ErrUnauthorized) {
+				h.limitFailedAuthentication(r)
+			}
+			h.httpErrorResponse(w, r, err)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (h *Handler) adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := principal(r)
+		var err error
+		if user == nil {
+			err = models.Errorf(models.ErrUnauthorized, "authentication required")
+		} else if !user.IsAdmin() {
+			err = models.Errorf(models.ErrForbidden, "admin role required")
+		}
+		if err != nil {
+			h.httpErrorResponse(w, r, err)
+			return
+		}
+		next(w, r)
+	}
+}
+
 func (h *Handler) decodeCreateEventBodyJSON(r *http.Request) (*createEventInput, error) {
 	input := &createEventInput{}
-	err := json.NewDecoder(r.Body).Decode(&input)
-	if err != nil {
+	if err := decodeBodyJSON(r, input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (h *Handler) decodeEventBodyJSON(r *http.Request) (*eventInput, error) {
+	input := &eventInput{}
+	if err := decodeBodyJSON(r, input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (h *Handler) decodeEventPatchBodyJSON(r *http.Request) (*eventPatch, error) {
+	input := &eventPatch{}
+	if err := decodeBodyJSON(r, input); err != nil {
 		return nil, err
 	}
 	return input, nil
@@ -18,8 +204,7 @@ func (h *Handler) decodeCreateEventBodyJSON(r *http.Request) (*createEventInput,
 
 func (h *Handler) decodeUpdateEventBodyJSON(r *http.Request) (*updateEventInput, error) {
 	input := &updateEventInput{}
-	err := json.NewDecoder(r.Body).Decode(&input)
-	if err != nil {
+	if err := decodeBodyJSON(r, input); err != nil {
 		return nil, err
 	}
 	return input, nil
@@ -27,22 +212,167 @@ func (h *Handler) decodeUpdateEventBodyJSON(r *http.Request) (*updateEventInput,
 
 func (h *Handler) decodeDeleteEventBodyJSON(r *http.Request) (*deleteEventInput, error) {
 	input := &deleteEventInput{}
-	err := json.NewDecoder(r.Body).Decode(&input)
+	if err := decodeBodyJSON(r, input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+// decodeBatchBodyJSON validates every operation as well. Invalid fields of
+// operations are named by their path like "operations[2].event.name".
+func (h *Handler) decodeBatchBodyJSON(r *http.Request) (*batchInput, error) {
+	input := &batchInput{}
+	if err := decodeBodyJSON(r, input); err != nil {
+		return nil, err
+	}
+	if errs := validateOperations(input.Operations); len(errs) > 0 {
+		return nil, errs
+	}
+	return input, nil
+}
+
+func (h *Handler) decodeCreateGroupBodyJSON(r *http.Request) (*createGroupInput, error) {
+	input := &createGroupInput{}
+	if err := decodeBodyJSON(r, input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (h *Handler) decodeGroupBodyJSON(r *http.Request) (*groupInput, error) {
+	input := &groupInput{}
+	if err := decodeBodyJSON(r, input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (h *Handler) decodeShareBodyJSON(r *http.Request) (*shareInput, error) {
+	input := &shareInput{}
+	if err := decodeBodyJSON(r, input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (h *Handler) decodeInvitationBodyJSON(r *http.Request) (*invitationInput, error) {
+	input := &invitationInput{}
+	if err := decodeBodyJSON(r, input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (h *Handler) decodeCreateUserBodyJSON(r *http.Request) (*createUserInput, error) {
+	input := &createUserInput{}
+	if err := decodeBodyJSON(r, input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func (h *Handler) decodeUserBodyJSON(r *http.Request) (*userInput, error) {
+	input := &userInput{}
+	if err := decodeBodyJSON(r, input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+func getParamsInput(url *url.URL) (*dateInput, error) {
+	query := url.Query()
+	input := &dateInput{UserId: query.Get("user_id"), TimeZone: query.Get("tz")}
+
+	var err error
+	if input.Date, err = parseQueryDate(query.Get("date")); err != nil {
+		return nil, models.Errorf(models.ErrValidation, "date: %w", err)
+	}
+	return input, nil
+}
+
+func getIntervalParamsInput(url *url.URL) (*intervalInput, error) {
+	query := url.Query()
+	input := &intervalInput{TimeZone: query.Get("tz")}
+
+	var err error
+	if input.From, err = parseQueryDate(query.Get("from")); err != nil {
+		return nil, models.Errorf(models.ErrValidation, "from: %w", err)
+	}
+	if input.To, err = parseQueryDate(query.Get("to")); err != nil {
+		return nil, models.Errorf(models.ErrValidation, "to: %w", err)
+	}
+	return input, nil
+}
+
+func getRangeParamsInput(url *url.URL) (*rangeInput, error) {
+	query := url.Query()
+	input := &rangeInput{UserId: query.Get("user_id"), Limit: defaultPageLimit}
+	if input.UserId == "" {
+		return nil, models.Errorf(models.ErrValidation, "user_id is required")
+	}
+
+	interval, err := getIntervalParamsInput(url)
 	if err != nil {
 		return nil, err
 	}
+	input.From, input.To, input.TimeZone = interval.From, interval.To, interval.TimeZone
+
+	if limit := query.Get("limit"); limit != "" {
+		input.Limit, err = strconv.Atoi(limit)
+		if err != nil || input.Limit < 1 || input.Limit > maxPageLimit {
+			return nil, models.Errorf(models.ErrValidation, "limit must be a number from 1 to %d", maxPageLimit)
+		}
+	}
+	if c := query.Get("cursor"); c != "" {
+		if input.Cursor, err = decodeCursor(c); err != nil {
+			return nil, err
+		}
+	}
 	return input, nil
 }
 
-func getParamsInput(url *url.URL) (string, time.Time, error) {
-	userId := url.Query().Get("user_id")
-	date := url.Query().Get("date")
+// getSearchParamsInput reads the query of a search. The range is optional,
+// but when set both of its ends are required.
+func getSearchParamsInput(url *url.URL) (*searchInput, error) {
+	query := url.Query()
+	input := &searchInput{Text: query.Get("q"), Tags: query["tag"], TimeZone: query.Get("tz")}
+	if len(input.Text) > maxSearchTextLength {
+		return nil, models.Errorf(models.ErrValidation, "q must not be longer than %d bytes", maxSearchTextLength)
+	}
+	if len(input.Tags) > maxSearchTags {
+		return nil, models.Errorf(models.ErrValidation, "at most %d tags can be searched for", maxSearchTags)
+	}
+	if !query.Has("from") && !query.Has("to") {
+		return input, nil
+	}
+	interval, err := getIntervalParamsInput(url)
+	if err != nil {
+		return nil, err
+	}
+	input.From, input.To = interval.From, interval.To
+	return input, nil
+}
 
-	inpDate := InputDate{}
-	err := inpDate.UnmarshalJSON([]byte(date))
+// getOccurrenceParam returns the start of the occurrence of a series the
+// request is addressed to or nil if the request addresses the whole event.
+func getOccurrenceParam(url *url.URL) (*InputDate, error) {
+	occurrence := url.Query().Get("occurrence")
+	if occurrence == "" {
+		return nil, nil
+	}
+	d, err := parseQueryDate(occurrence)
 	if err != nil {
-		return "", time.Time{}, err
+		return nil, models.Errorf(models.ErrValidation, "occurrence: %w", err)
 	}
+	return &d, nil
+}
 
-	return userId, time.Time(inpDate), nil
+// parseQueryDate accepts either an RFC 3339 timestamp or a bare date.
+func parseQueryDate(s string) (InputDate, error) {
+	inpDate := InputDate{}
+	if s == "" {
+		return inpDate, fmt.Errorf("value is required")
+	}
+	err := inpDate.UnmarshalJSON([]byte(s))
+	return inpDate, err
 }
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dev11/pkg/models"
+	"dev11/pkg/repository/cache"
+	"dev11/pkg/service"
+)
+
+func TestHandler_Authorization(t *testing.T) {
+	s := service.NewService(cache.NewUserCache(cache.NewCache()))
+	keys := map[string]string{"admin": "secret"}
+	for _, id := range []string{"alice", "bob"} {
+		_, key, err := s.CreateUser(models.User{Id: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[id] = key
+	}
+	routes := NewHandler(s, "secret").InitRoutes()
+
+	tests := []struct {
+		name   string
+		caller string
+		method string
+		target string
+		body   string
+		want   int
+	}{
+		{"anonymous", "", "GET", "/users/alice/events", "", http.StatusUnauthorized},
+		{"owner", "alice", "GET", "/users/alice/events", "", http.StatusOK},
+		{"foreign user", "bob", "GET", "/users/alice/events", "", http.StatusForbidden},
+		{"admin", "admin", "GET", "/users/alice/events", "", http.StatusOK},
+		{"invalid key", "mallory", "GET", "/users/alice/events", "", http.StatusUnauthorized},
+		{"list users", "alice", "GET", "/users", "", http.StatusForbidden},
+		{"admin lists users", "admin", "GET", "/users", "", http.StatusOK},
+		{"range of foreign user", "bob", "GET", "/events?user_id=alice&from=2024-01-01&to=2024-02-01", "", http.StatusForbidden},
+		{"legacy foreign user", "bob", "POST", "/create_event", `{"userId":"alice","name":"a","date":"2024-01-01"}`, http.StatusForbidden},
+		{"legacy owner", "alice", "POST", "/create_event", `{"userId":"alice","name":"a","date":"2024-01-01"}`, http.StatusOK},
+		{"signup", "", "POST", "/users", `{"id":"carol"}`, http.StatusCreated},
+		{"signup as admin", "", "POST", "/users", `{"id":"dave","role":"admin"}`, http.StatusForbidden},
+		{"admin creates admin", "admin", "POST", "/users", `{"id":"dave","role":"admin"}`, http.StatusCreated},
+		{"existing id", "", "POST", "/users", `{"id":"alice"}`, http.StatusConflict},
+		{"delete foreign user", "alice", "DELETE", "/users/bob", "", http.StatusForbidden},
+		{"delete self", "bob", "DELETE", "/users/bob", "", http.StatusNoContent},
+		{"deleted key", "bob", "GET", "/users/bob", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.caller != "" {
+				key, ok := keys[tt.caller]
+				if !ok {
+					key = "unknown"
+				}
+				r.Header.Set("Authorization", "Bearer "+key)
+			}
+			w := httptest.NewRecorder()
+			routes.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("WWW-Authenticate header is missing")
+			}
+		})
+	}
+}
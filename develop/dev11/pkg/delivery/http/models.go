@@ -2,68 +2,449 @@ package http
 
 import (
 	"encoding/json"
+	"net/http"
 	"strings"
 	"time"
 
 	"dev11/pkg/models"
 )
 
-type InputDate time.Time
+// InputDate is either an RFC 3339 timestamp or a bare date. A bare date
+// does not belong to any time zone until it is resolved with In.
+type InputDate struct {
+	time     time.Time
+	dateOnly bool
+}
 
 type createEventInput struct {
-	UserId      string    `json:"userId"      validate:"required"`
-	Name        string    `json:"name"        validate:"required"`
-	Description string    `json:"description"`
-	Date        InputDate `json:"date"        validate:"required"`
+	UserId      string    `json:"userId"      validate:"required,max=64"`
+	Name        string    `json:"name"        validate:"required,max=256"`
+	Description string    `json:"description" validate:"max=4096"`
+	Date        InputDate `json:"date"        validate:"required,date"`
+	End         InputDate `json:"end"         validate:"date"`
+	Duration    string    `json:"duration"    validate:"max=32"`
+	TimeZone    string    `json:"timezone"    validate:"max=64"`
 }
 
+// updateEventInput is a merge patch of the event with the given id.
 type updateEventInput struct {
-	UserId      string    `json:"userId"      validate:"required"`
-	EventId     string    `json:"eventId"     validate:"required"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Date        InputDate `json:"date"`
+	UserId  string `json:"userId"  validate:"required,max=64"`
+	EventId string `json:"eventId" validate:"required,max=64"`
+	eventPatch
 }
 
 type deleteEventInput struct {
-	UserId  string `json:"userId"  validate:"required"`
-	EventId string `json:"eventId" validate:"required"`
+	UserId  string `json:"userId"  validate:"required,max=64"`
+	EventId string `json:"eventId" validate:"required,max=64"`
+}
+
+type eventInput struct {
+	Name        string            `json:"name"        validate:"required,max=256"`
+	Description string            `json:"description" validate:"max=4096"`
+	Date        InputDate         `json:"date"        validate:"required,date"`
+	End         InputDate         `json:"end"         validate:"date"`
+	Duration    string            `json:"duration"    validate:"max=32"`
+	TimeZone    string            `json:"timezone"    validate:"max=64"`
+	RRule       string            `json:"rrule"       validate:"max=512"`
+	ExDates     []time.Time       `json:"exdates"     validate:"max=1000,date"`
+	Reminders   []models.Duration `json:"reminders"   validate:"max=20"`
+	// Attendees are invited users; their statuses are ignored since only
+	// attendees can respond to invitations.
+	Attendees []models.Attendee `json:"attendees" validate:"max=100"`
+	Tags      []string          `json:"tags"      validate:"max=20"`
+}
+
+// eventPatch is a JSON Merge Patch (RFC 7396) of an event: absent fields are
+// kept and null clears a field. Required fields can not be cleared.
+type eventPatch struct {
+	Name        optional[string]            `json:"name"        validate:"required,max=256"`
+	Description optional[string]            `json:"description" validate:"max=4096"`
+	Date        optional[InputDate]         `json:"date"        validate:"required,date"`
+	End         optional[InputDate]         `json:"end"         validate:"date"`
+	Duration    optional[string]            `json:"duration"    validate:"max=32"`
+	TimeZone    optional[string]            `json:"timezone"    validate:"max=64"`
+	RRule       optional[string]            `json:"rrule"       validate:"max=512"`
+	ExDates     optional[[]time.Time]       `json:"exdates"     validate:"max=1000,date"`
+	Reminders   optional[[]models.Duration] `json:"reminders"   validate:"max=20"`
+	Attendees   optional[[]models.Attendee] `json:"attendees"   validate:"max=100"`
+	Tags        optional[[]string]          `json:"tags"        validate:"max=20"`
+}
+
+// batchInput is a list of changes of events applied in one transaction.
+// Unless Atomic is set, failed operations are skipped.
+type batchInput struct {
+	Atomic     bool             `json:"atomic"`
+	Operations []operationInput `json:"operations" validate:"required,max=1000"`
+}
+
+// operationInput is an operation of a batch. Create and update take the
+// event, update and delete take the id of the event and optionally its
+// expected version.
+type operationInput struct {
+	Op      models.OperationType `json:"op"      validate:"required"`
+	EventId string               `json:"eventId" validate:"max=64"`
+	Version int64                `json:"version"`
+	Event   *eventInput          `json:"event"`
+}
+
+type userInput struct {
+	TimeZone string `json:"timezone" validate:"required,max=64"`
+}
+
+type shareInput struct {
+	Groups []string `json:"groups" validate:"max=100"`
+}
+
+type groupInput struct {
+	Name    string   `json:"name"    validate:"max=256"`
+	Members []string `json:"members" validate:"max=1000"`
+}
+
+type createGroupInput struct {
+	Id string `json:"id" validate:"max=64"`
+	groupInput
+}
+
+type invitationInput struct {
+	Status models.AttendeeStatus `json:"status" validate:"required"`
+}
+
+type createUserInput struct {
+	Id       string      `json:"id"       validate:"max=64"`
+	Name     string      `json:"name"     validate:"max=256"`
+	Role     models.Role `json:"role"`
+	TimeZone string      `json:"timezone" validate:"max=64"`
 }
 
 type successEventOutput struct {
 	Result string `json:"result"`
 }
 
+type eventOutput struct {
+	Result models.Event `json:"result"`
+}
+
 type eventsOutput struct {
 	Result []models.Event `json:"result"`
 }
 
-type errorOutput struct {
+type eventsPageOutput struct {
+	Result     []models.Event `json:"result"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+}
+
+type intervalInput struct {
+	From     InputDate
+	To       InputDate
+	TimeZone string
+}
+
+type dateInput struct {
+	UserId   string
+	Date     InputDate
+	TimeZone string
+}
+
+type searchInput struct {
+	Text     string
+	Tags     []string
+	From     InputDate
+	To       InputDate
+	TimeZone string
+}
+
+type rangeInput struct {
+	UserId   string
+	From     InputDate
+	To       InputDate
+	TimeZone string
+	Limit    int
+	Cursor   *cursor
+}
+
+type importError struct {
+	Index int    `json:"index"`
+	UID   string `json:"uid,omitempty"`
 	Error string `json:"error"`
 }
 
+type importResult struct {
+	Imported int           `json:"imported"`
+	Errors   []importError `json:"errors"`
+}
+
+type importOutput struct {
+	Result importResult `json:"result"`
+}
+
+// operationResult is the outcome of an operation of a batch with the status
+// code it would have as a separate request.
+type operationResult struct {
+	Status int            `json:"status"`
+	Event  *models.Event  `json:"event,omitempty"`
+	Error  *problemOutput `json:"error,omitempty"`
+}
+
+type batchResult struct {
+	Applied int               `json:"applied"`
+	Results []operationResult `json:"results"`
+}
+
+type batchOutput struct {
+	Result batchResult `json:"result"`
+}
+
+type userResult struct {
+	Id       string      `json:"id"`
+	Name     string      `json:"name,omitempty"`
+	Role     models.Role `json:"role"`
+	TimeZone string      `json:"timezone"`
+	// SharedWith are the groups which can read the calendar of the user.
+	SharedWith []string `json:"sharedWith,omitempty"`
+	// APIKey is set only in the response to the creation of the user.
+	APIKey string `json:"apiKey,omitempty"`
+}
+
+type feedTokenResult struct {
+	FeedToken string `json:"feedToken"`
+}
+
+type feedTokenOutput struct {
+	Result feedTokenResult `json:"result"`
+}
+
+type userOutput struct {
+	Result userResult `json:"result"`
+}
+
+type usersOutput struct {
+	Result []userResult `json:"result"`
+}
+
+type groupOutput struct {
+	Result models.Group `json:"result"`
+}
+
+type groupsOutput struct {
+	Result []models.Group `json:"result"`
+}
+
+type trashOutput struct {
+	Result []models.TrashedEvent `json:"result"`
+}
+
+type historyOutput struct {
+	Result []models.AuditEntry `json:"result"`
+}
+
+type healthResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type healthOutput struct {
+	Result healthResult `json:"result"`
+}
+
+type freeBusyResult struct {
+	Busy []models.Interval `json:"busy"`
+	Free []models.Interval `json:"free"`
+}
+
+type freeBusyOutput struct {
+	Result freeBusyResult `json:"result"`
+}
+
+// problemOutput is an RFC 7807 problem details object.
+type problemOutput struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Fields lists the invalid fields of the request body.
+	Fields []fieldError `json:"fields,omitempty"`
+	// Conflicts lists the events the event from the request overlaps.
+	Conflicts []models.Event `json:"conflicts,omitempty"`
+	// Operation is the index of the operation of a batch which failed.
+	Operation *int `json:"operation,omitempty"`
+}
+
 func newSuccessEventOutput(result string) successEventOutput {
 	return successEventOutput{Result: result}
 }
 
+func newEventOutput(result models.Event) eventOutput {
+	return eventOutput{Result: result}
+}
+
 func newEventsOutput(result []models.Event) eventsOutput {
 	return eventsOutput{Result: result}
 }
 
-func newErrorOutput(message string) errorOutput {
-	return errorOutput{Error: message}
+func newEventsPageOutput(result []models.Event, nextCursor string) eventsPageOutput {
+	return eventsPageOutput{Result: result, NextCursor: nextCursor}
+}
+
+func newImportError(index int, uid string, err error) importError {
+	return importError{Index: index, UID: uid, Error: err.Error()}
+}
+
+func newImportOutput(result importResult) importOutput {
+	return importOutput{Result: result}
+}
+
+func newBatchOutput(result batchResult) batchOutput {
+	return batchOutput{Result: result}
+}
+
+func newUserResult(user models.User) userResult {
+	return userResult{
+		Id:         user.Id,
+		Name:       user.Name,
+		Role:       user.Role,
+		TimeZone:   user.TimeZone,
+		SharedWith: user.SharedWith,
+	}
+}
+
+func newUserOutput(user models.User, apiKey string) userOutput {
+	result := newUserResult(user)
+	result.APIKey = apiKey
+	return userOutput{Result: result}
+}
+
+func newUsersOutput(users []models.User) usersOutput {
+	result := make([]userResult, 0, len(users))
+	for _, u := range users {
+		result = append(result, newUserResult(u))
+	}
+	return usersOutput{Result: result}
+}
+
+func newGroupOutput(group models.Group) groupOutput {
+	return groupOutput{Result: group}
+}
+
+func newGroupsOutput(groups []models.Group) groupsOutput {
+	return groupsOutput{Result: groups}
+}
+
+func newTrashOutput(trash []models.TrashedEvent) trashOutput {
+	return trashOutput{Result: trash}
+}
+
+func newHistoryOutput(entries []models.AuditEntry) historyOutput {
+	return historyOutput{Result: entries}
+}
+
+func newHealthOutput(status, err string) healthOutput {
+	return healthOutput{Result: healthResult{Status: status, Error: err}}
+}
+
+func newFreeBusyOutput(busy, free []models.Interval) freeBusyOutput {
+	return freeBusyOutput{Result: freeBusyResult{Busy: busy, Free: free}}
+}
+
+func newProblemOutput(status int, detail, instance string) problemOutput {
+	return problemOutput{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: instance,
+	}
+}
+
+func (i *createGroupInput) group() models.Group {
+	return models.Group{Id: i.Id, Name: i.Name, Members: i.Members}
+}
+
+func (i *createUserInput) user() models.User {
+	return models.User{Id: i.Id, Name: i.Name, Role: i.Role, TimeZone: i.TimeZone}
+}
+
+// event returns the event described by the input with dates resolved in loc.
+func (i *eventInput) event(id string, loc *time.Location) (models.Event, error) {
+	date := i.Date.In(loc)
+	end, err := eventEnd(date, i.End, i.Duration, loc)
+	if err != nil {
+		return models.Event{}, err
+	}
+	return models.Event{
+		Id:          id,
+		Name:        i.Name,
+		Description: i.Description,
+		Date:        date,
+		End:         end,
+		TimeZone:    loc.String(),
+		RRule:       i.RRule,
+		ExDates:     i.ExDates,
+		Reminders:   i.Reminders,
+		Attendees:   i.Attendees,
+		Tags:        i.Tags,
+	}, nil
+}
+
+// eventEnd returns the end of the event starting at start set either as the
+// end date or as the duration like "1h30m".
+func eventEnd(start time.Time, end InputDate, duration string, loc *time.Location) (*time.Time, error) {
+	if duration == "" {
+		return end.OptionalIn(loc), nil
+	}
+	if !end.IsZero() {
+		return nil, models.Errorf(models.ErrValidation, "end and duration are mutually exclusive")
+	}
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return nil, models.Errorf(models.ErrValidation, "duration: %w", err)
+	}
+	if d <= 0 {
+		return nil, models.Errorf(models.ErrValidation, "duration must be positive")
+	}
+	t := start.Add(d)
+	return &t, nil
 }
 
 func (i *InputDate) UnmarshalJSON(b []byte) error {
 	s := strings.Trim(string(b), "\"")
+	if s == "null" {
+		return nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		*i = InputDate{time: t}
+		return nil
+	}
 	t, err := time.Parse("2006-01-02", s)
 	if err != nil {
 		return err
 	}
-	*i = InputDate(t)
+	*i = InputDate{time: t, dateOnly: true}
 	return nil
 }
 
 func (i InputDate) MarshalJSON() ([]byte, error) {
-	return json.Marshal(time.Time(i))
+	return json.Marshal(i.time)
+}
+
+// In returns the time in loc. A bare date is the midnight in loc.
+func (i InputDate) In(loc *time.Location) time.Time {
+	if i.dateOnly {
+		y, m, d := i.time.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, loc)
+	}
+	return i.time.In(loc)
+}
+
+// OptionalIn is In which returns nil for an unset date.
+func (i InputDate) OptionalIn(loc *time.Location) *time.Time {
+	if i.IsZero() {
+		return nil
+	}
+	t := i.In(loc)
+	return &t
+}
+
+func (i InputDate) IsZero() bool {
+	return i.time.IsZero()
 }
@@ -0,0 +1,18 @@
+package http
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPIDocument describes the API. TestOpenAPI_Routes keeps its paths in
+// sync with the routes.
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+func (h *Handler) openAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(openAPIDocument)
+}
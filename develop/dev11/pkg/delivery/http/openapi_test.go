@@ -0,0 +1,160 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"dev11/pkg/models"
+	"dev11/pkg/repository/cache"
+	"dev11/pkg/service"
+)
+
+type openAPISpec struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func loadOpenAPISpec(t *testing.T) openAPISpec {
+	t.Helper()
+	var spec openAPISpec
+	if err := json.Unmarshal(openAPIDocument, &spec); err != nil {
+		t.Fatalf("openapi.json: %v", err)
+	}
+	return spec
+}
+
+// TestOpenAPI_Routes checks that the document describes exactly the routes
+// registered by InitRoutes.
+func TestOpenAPI_Routes(t *testing.T) {
+	spec := loadOpenAPISpec(t)
+	var documented []string
+	for path, item := range spec.Paths {
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	h := NewHandler(service.NewService(cache.NewUserCache(cache.NewCache())), "")
+	var registered []string
+	for _, e := range h.routes() {
+		registered = append(registered, e.pattern)
+	}
+
+	for _, p := range registered {
+		if !slices.Contains(documented, p) {
+			t.Errorf("route %q is not documented", p)
+		}
+	}
+	for _, p := range documented {
+		if !slices.Contains(registered, p) {
+			t.Errorf("documented route %q is not registered", p)
+		}
+	}
+}
+
+// TestOpenAPI_Schemas checks that the schemas have the same properties as
+// the JSON encoding of the types they describe.
+func TestOpenAPI_Schemas(t *testing.T) {
+	spec := loadOpenAPISpec(t)
+	tests := []struct {
+		schema string
+		value  any
+	}{
+		{"Event", models.Event{}},
+		{"EventInput", eventInput{}},
+		{"EventPatch", eventPatch{}},
+		{"CreateEventInput", createEventInput{}},
+		{"UpdateEventInput", updateEventInput{}},
+		{"DeleteEventInput", deleteEventInput{}},
+		{"User", userResult{}},
+		{"CreateUserInput", createUserInput{}},
+		{"UserInput", userInput{}},
+		{"EventsPage", eventsPageOutput{}},
+		{"Interval", models.Interval{}},
+		{"FreeBusy", freeBusyResult{}},
+		{"ImportResult", importResult{}},
+		{"ImportError", importError{}},
+		{"BatchInput", batchInput{}},
+		{"BatchOperation", operationInput{}},
+		{"BatchResult", batchResult{}},
+		{"OperationResult", operationResult{}},
+		{"Change", models.Change{}},
+		{"Health", healthResult{}},
+		{"FieldError", fieldError{}},
+		{"Problem", problemOutput{}},
+		{"Attendee", models.Attendee{}},
+		{"Group", models.Group{}},
+		{"GroupInput", groupInput{}},
+		{"CreateGroupInput", createGroupInput{}},
+		{"ShareInput", shareInput{}},
+		{"InvitationInput", invitationInput{}},
+		{"TrashedEvent", models.TrashedEvent{}},
+		{"AuditEntry", models.AuditEntry{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			schema, ok := spec.Components.Schemas[tt.schema]
+			if !ok {
+				t.Fatalf("schema %s is not documented", tt.schema)
+			}
+			var documented []string
+			for name := range schema.Properties {
+				documented = append(documented, name)
+			}
+			slices.Sort(documented)
+			if want := jsonFields(reflect.TypeOf(tt.value)); !slices.Equal(documented, want) {
+				t.Errorf("properties = %v, want %v", documented, want)
+			}
+		})
+	}
+}
+
+// jsonFields returns sorted names of the JSON fields of the struct type
+// including the fields of embedded structs.
+func jsonFields(typ reflect.Type) []string {
+	var names []string
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.Anonymous {
+			names = append(names, jsonFields(f.Type)...)
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestHandler_OpenAPI(t *testing.T) {
+	h := NewHandler(service.NewService(cache.NewUserCache(cache.NewCache())), "")
+	w := httptest.NewRecorder()
+	h.InitRoutes().ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	if !json.Valid(w.Body.Bytes()) {
+		t.Error("document is not valid JSON")
+	}
+}
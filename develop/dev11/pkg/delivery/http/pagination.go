@@ -0,0 +1,56 @@
+package http
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"dev11/pkg/models"
+)
+
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// cursor points at the last event of a page. It is passed to clients as an
+// opaque base64 string.
+type cursor struct {
+	Date time.Time `json:"d"`
+	Id   string    `json:"i"`
+}
+
+func encodeCursor(event models.Event) string {
+	b, _ := json.Marshal(cursor{Date: event.Date, Id: event.Id})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, models.Errorf(models.ErrValidation, "invalid cursor")
+	}
+	c := &cursor{}
+	if err = json.Unmarshal(b, c); err != nil {
+		return nil, models.Errorf(models.ErrValidation, "invalid cursor")
+	}
+	return c, nil
+}
+
+// paginate returns at most limit events following the one pointed by c and
+// the cursor of the next page, which is empty on the last page. Events must
+// be sorted with models.SortEvents.
+func paginate(events []models.Event, limit int, c *cursor) ([]models.Event, string) {
+	if c != nil {
+		last := models.Event{Id: c.Id, Date: c.Date}
+		i := 0
+		for i < len(events) && !last.Before(events[i]) {
+			i++
+		}
+		events = events[i:]
+	}
+	if len(events) <= limit {
+		return events, ""
+	}
+	return events[:limit], encodeCursor(events[limit-1])
+}
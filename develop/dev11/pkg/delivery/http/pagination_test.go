@@ -0,0 +1,87 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"dev11/pkg/models"
+)
+
+func TestHandler_Pagination(t *testing.T) {
+	ts := newTestServer(t, models.User{Id: "alice"})
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	// pages split events starting at the same time, which are ordered by id
+	for i, d := range []time.Duration{0, 0, 0, 0, 0, time.Hour, 24 * time.Hour} {
+		if _, err := ts.service.CreateEvent("alice", models.Event{Name: string(rune('a' + i)), Date: start.Add(d)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events, _ := ts.service.GetEvents("alice")
+	models.SortEvents(events)
+	var want []string
+	for _, e := range events {
+		want = append(want, e.Id)
+	}
+
+	var got []string
+	next, pages := "", 0
+	for {
+		target := "/events?user_id=alice&from=2024-03-01&to=2024-04-01&limit=2"
+		if next != "" {
+			target += "&cursor=" + url.QueryEscape(next)
+		}
+		w := ts.do("alice", "GET", target, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status of page %d = %d: %s", pages+1, w.Code, w.Body)
+		}
+		var output struct {
+			Result     []models.Event `json:"result"`
+			NextCursor string         `json:"nextCursor"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &output); err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		if len(output.Result) > 2 || output.NextCursor != "" && len(output.Result) != 2 {
+			t.Errorf("page %d has %d events", pages, len(output.Result))
+		}
+		for _, e := range output.Result {
+			got = append(got, e.Id)
+		}
+		if next = output.NextCursor; next == "" || pages > len(want) {
+			break
+		}
+	}
+	if pages != 4 || !slices.Equal(got, want) {
+		t.Errorf("%d pages of ids %q, want 4 pages of %q", pages, got, want)
+	}
+}
+
+func TestHandler_PaginationParams(t *testing.T) {
+	ts := newTestServer(t, models.User{Id: "alice"})
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"smallest limit", "&limit=1", http.StatusOK},
+		{"largest limit", "&limit=1000", http.StatusOK},
+		{"zero limit", "&limit=0", http.StatusBadRequest},
+		{"limit too large", "&limit=1001", http.StatusBadRequest},
+		{"limit not a number", "&limit=ten", http.StatusBadRequest},
+		{"cursor not base64", "&cursor=%21%21", http.StatusBadRequest},
+		{"cursor not JSON", "&cursor=garbage", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := ts.do("alice", "GET", "/events?user_id=alice&from=2024-03-01&to=2024-04-01"+tt.query, "")
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"dev11/pkg/models"
+)
+
+// optional is a field of a JSON Merge Patch (RFC 7396). Set reports whether
+// the field is present in the patch and Null whether it is null, which
+// clears the field.
+type optional[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+func (o *optional[T]) UnmarshalJSON(b []byte) error {
+	o.Set = true
+	if string(b) == "null" {
+		o.Null = true
+		return nil
+	}
+	return json.Unmarshal(b, &o.Value)
+}
+
+// state lets validate check the value of the field.
+func (o optional[T]) state() (value reflect.Value, set, null bool) {
+	return reflect.ValueOf(o.Value), o.Set, o.Null
+}
+
+// apply changes the event by the patch. Dates are resolved in loc, which
+// must be the time zone of the patched event. Moving the event keeps its
+// duration unless the end is patched too.
+func (p *eventPatch) apply(event *models.Event, loc *time.Location) error {
+	if p.TimeZone.Set {
+		event.TimeZone = loc.String()
+	}
+	if p.Name.Set {
+		event.Name = p.Name.Value
+	}
+	if p.Description.Set {
+		event.Description = p.Description.Value
+	}
+	original := event.Date
+	if p.Date.Set {
+		event.Date = p.Date.Value.In(loc)
+	}
+	switch {
+	case p.End.Set || p.Duration.Set:
+		end, err := eventEnd(event.Date, p.End.Value, p.Duration.Value, loc)
+		if err != nil {
+			return err
+		}
+		event.End = end
+	case event.End != nil && p.Date.Set:
+		end := event.Date.Add(event.End.Sub(original))
+		event.End = &end
+	}
+	if p.RRule.Set {
+		event.RRule = p.RRule.Value
+	}
+	if p.ExDates.Set {
+		event.ExDates = p.ExDates.Value
+	}
+	if p.Reminders.Set {
+		event.Reminders = p.Reminders.Value
+	}
+	if p.Attendees.Set {
+		event.Attendees = p.Attendees.Value
+	}
+	if p.Tags.Set {
+		event.Tags = p.Tags.Value
+	}
+	return nil
+}
+
+// timeZone returns the time zone the patched event will have, empty for the
+// default time zone of the user.
+func (p *eventPatch) timeZone(event *models.Event) string {
+	if p.TimeZone.Set {
+		return p.TimeZone.Value
+	}
+	return event.TimeZone
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dev11/pkg/models"
+	"dev11/pkg/repository/cache"
+	"dev11/pkg/service"
+)
+
+func TestHandler_MergePatch(t *testing.T) {
+	date := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	end := date.Add(time.Hour)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   int
+		check  func(e models.Event) bool
+	}{
+		{"absent fields are kept", "PATCH", "", `{"name":"daily"}`, http.StatusOK, func(e models.Event) bool {
+			return e.Name == "daily" && e.Description == "notes" && e.End != nil && e.RRule == "FREQ=DAILY"
+		}},
+		{"null clears a field", "PATCH", "", `{"description":null,"rrule":null}`, http.StatusOK, func(e models.Event) bool {
+			return e.Name == "standup" && e.Description == "" && e.RRule == "" && e.End != nil
+		}},
+		{"null clears the end", "PATCH", "", `{"end":null}`, http.StatusOK, func(e models.Event) bool {
+			return e.End == nil
+		}},
+		{"moving keeps the duration", "PATCH", "", `{"date":"2024-03-05T10:00:00Z"}`, http.StatusOK, func(e models.Event) bool {
+			return e.End != nil && e.End.Sub(e.Date) == time.Hour
+		}},
+		{"required field can not be null", "PATCH", "", `{"name":null}`, http.StatusBadRequest, nil},
+		{"legacy update returns the event", "POST", "/update_event", `{"userId":"1","eventId":"%s","description":null}`, http.StatusOK, func(e models.Event) bool {
+			return e.Name == "standup" && e.Description == "" && e.RRule == "FREQ=DAILY"
+		}},
+		{"legacy update requires the id", "POST", "/update_event", `{"userId":"1","name":"daily"}`, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := service.NewService(cache.NewUserCache(cache.NewCache()))
+			event, err := s.CreateEvent("1", models.Event{
+				Name: "standup", Description: "notes", Date: date, End: &end, RRule: "FREQ=DAILY",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			target := tt.target
+			if target == "" {
+				target = "/users/1/events/" + event.Id
+			}
+			body := strings.ReplaceAll(tt.body, "%s", event.Id)
+			r := httptest.NewRequest(tt.method, target, strings.NewReader(body))
+			r.Header.Set("Authorization", "Bearer secret")
+			w := httptest.NewRecorder()
+			NewHandler(s, "secret").InitRoutes().ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.check == nil {
+				return
+			}
+			var out eventOutput
+			if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(out.Result) {
+				t.Errorf("unexpected event %+v", out.Result)
+			}
+		})
+	}
+}
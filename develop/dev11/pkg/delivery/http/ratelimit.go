@@ -0,0 +1,110 @@
+package http
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"dev11/pkg/models"
+	"dev11/pkg/ratelimit"
+)
+
+// SetRateLimits limits requests which read and which change data
+// separately; nil disables the limit. It must be called before InitRoutes.
+func (h *Handler) SetRateLimits(read, write *ratelimit.Limiter) {
+	h.readLimiter, h.writeLimiter = read, write
+}
+
+// rateLimit limits requests of every user, or of every IP address for
+// anonymous requests, and reports the state of the limit in RateLimit-*
+// headers. Health checks and metrics are not limited.
+func (h *Handler) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiter := h.writeLimiter
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			limiter = h.readLimiter
+		}
+		switch r.URL.Path {
+		case "/metrics", "/healthz", "/readyz":
+			limiter = nil
+		}
+		if limiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		d := limiter.Allow(rateLimitKey(r))
+		setRateLimitHeaders(w, d)
+		if !d.Allowed {
+			h.httpRateLimitedResponse(w, r, d)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitFailedAuthentication counts the failed authentication of the request
+// against the write limit of its IP address, so that API keys can not be
+// guessed faster than the address may change data.
+func (h *Handler) limitFailedAuthentication(r *http.Request) {
+	if h.writeLimiter != nil {
+		h.writeLimiter.Allow(ipRateLimitKey(r))
+	}
+}
+
+// authenticationLimited reports whether the IP address of the request has
+// used up its write limit, in which case its API key is not checked and the
+// request is rejected.
+func (h *Handler) authenticationLimited(w http.ResponseWriter, r *http.Request) bool {
+	if h.writeLimiter == nil {
+		return false
+	}
+	d := h.writeLimiter.Peek(ipRateLimitKey(r))
+	if d.Allowed {
+		return false
+	}
+	setRateLimitHeaders(w, d)
+	h.httpRateLimitedResponse(w, r, d)
+	return true
+}
+
+func setRateLimitHeaders(w http.ResponseWriter, d ratelimit.Decision) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(seconds(d.Reset)))
+}
+
+func (h *Handler) httpRateLimitedResponse(w http.ResponseWriter, r *http.Request, d ratelimit.Decision) {
+	w.Header().Set("Retry-After", strconv.Itoa(seconds(d.RetryAfter)))
+	h.httpErrorResponse(w, r, models.Errorf(models.ErrRateLimited,
+		"rate limit exceeded, retry in %d seconds", seconds(d.RetryAfter)))
+}
+
+// rateLimitKey returns the user making the request or, for anonymous
+// requests, its IP address.
+func rateLimitKey(r *http.Request) string {
+	if user := principal(r); user != nil {
+		if user.IsAdmin() && user.Id == "" {
+			return "admin"
+		}
+		return "user:" + user.Id
+	}
+	return ipRateLimitKey(r)
+}
+
+// ipRateLimitKey returns the IP address the request comes from.
+func ipRateLimitKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// seconds rounds d up to whole seconds.
+func seconds(d time.Duration) int {
+	return int(math.Min(math.Ceil(d.Seconds()), math.MaxInt32))
+}
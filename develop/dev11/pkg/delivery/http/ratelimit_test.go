@@ -0,0 +1,102 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dev11/pkg/models"
+	"dev11/pkg/ratelimit"
+	"dev11/pkg/repository/cache"
+	"dev11/pkg/service"
+)
+
+func TestHandler_RateLimit(t *testing.T) {
+	s := service.NewService(cache.NewUserCache(cache.NewCache()))
+	if _, _, err := s.CreateUser(models.User{Id: "2"}); err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(s, "secret")
+	// a rate this low does not refill a token during the test
+	h.SetRateLimits(ratelimit.New(0.001, 2), ratelimit.New(0.001, 1))
+	routes := h.InitRoutes()
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		key        string
+		remoteAddr string
+		want       int
+		remaining  string
+	}{
+		{"first read", "GET", "/users/1/events", "secret", "10.0.0.1:1", http.StatusOK, "1"},
+		{"second read", "GET", "/users/1/events", "secret", "10.0.0.1:1", http.StatusOK, "0"},
+		{"read limit exceeded", "GET", "/users/1/events", "secret", "10.0.0.2:1", http.StatusTooManyRequests, "0"},
+		{"writes are limited separately", "DELETE", "/users/1/events/x", "secret", "10.0.0.1:1", http.StatusNotFound, "0"},
+		{"write limit exceeded", "DELETE", "/users/1/events/x", "secret", "10.0.0.1:1", http.StatusTooManyRequests, "0"},
+		{"anonymous requests are limited per IP", "GET", "/users/1/events", "", "10.0.0.1:1", http.StatusUnauthorized, "1"},
+		{"another IP", "GET", "/users/1/events", "", "10.0.0.2:2", http.StatusUnauthorized, "1"},
+		{"health checks are not limited", "GET", "/healthz", "secret", "10.0.0.1:1", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.key != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.key)
+			}
+			w := httptest.NewRecorder()
+			routes.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if got := w.Header().Get("RateLimit-Remaining"); got != tt.remaining {
+				t.Errorf("RateLimit-Remaining = %q, want %q", got, tt.remaining)
+			}
+			if w.Code == http.StatusTooManyRequests {
+				if w.Header().Get("Retry-After") == "" || w.Header().Get("RateLimit-Reset") == "" {
+					t.Errorf("missing Retry-After or RateLimit-Reset in %v", w.Header())
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_RateLimitFailedAuthentication(t *testing.T) {
+	s := service.NewService(cache.NewUserCache(cache.NewCache()))
+	h := NewHandler(s, "secret")
+	// a rate this low does not refill a token during the test
+	h.SetRateLimits(nil, ratelimit.New(0.001, 2))
+	routes := h.InitRoutes()
+
+	tests := []struct {
+		name       string
+		key        string
+		remoteAddr string
+		want       int
+	}{
+		{"first guess", "guess-1", "10.0.0.1:1", http.StatusUnauthorized},
+		{"second guess", "guess-2", "10.0.0.1:2", http.StatusUnauthorized},
+		{"guesses exceeded", "guess-3", "10.0.0.1:3", http.StatusTooManyRequests},
+		{"valid key after guesses", "secret", "10.0.0.1:1", http.StatusTooManyRequests},
+		{"another IP", "secret", "10.0.0.2:1", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/users/1/events", nil)
+			r.RemoteAddr = tt.remoteAddr
+			r.Header.Set("Authorization", "Bearer "+tt.key)
+			w := httptest.NewRecorder()
+			routes.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+				t.Errorf("missing Retry-After in %v", w.Header())
+			}
+		})
+	}
+}
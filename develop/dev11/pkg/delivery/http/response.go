@@ -25,6 +25,19 @@ func (h *Handler) httpSuccessEventActionResponse(
 	}
 }
 
+func (h *Handler) httpEventResponse(w http.ResponseWriter, statusCode int, event models.Event) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", eventETag(event.Version))
+	w.WriteHeader(statusCode)
+
+	data := newEventOutput(event)
+	response, _ := json.MarshalIndent(data, " ", "")
+	_, err := w.Write(response)
+	if err != nil {
+		http.Error(w, fmt.Errorf("error: %v", err).Error(), http.StatusInternalServerError)
+	}
+}
+
 func (h *Handler) httpEventsResponse(w http.ResponseWriter, statusCode int, events []models.Event) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -37,11 +50,173 @@ func (h *Handler) httpEventsResponse(w http.ResponseWriter, statusCode int, even
 	}
 }
 
-func (h *Handler) httpErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+func (h *Handler) httpEventsPageResponse(
+	w http.ResponseWriter,
+	statusCode int,
+	events []models.Event,
+	nextCursor string,
+) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	data := newEventsPageOutput(events, nextCursor)
+	response, _ := json.MarshalIndent(data, " ", "")
+	_, err := w.Write(response)
+	if err != nil {
+		http.Error(w, fmt.Errorf("error: %v", err).Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) httpImportResponse(w http.ResponseWriter, statusCode int, result importResult) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	data := newImportOutput(result)
+	response, _ := json.MarshalIndent(data, " ", "")
+	_, err := w.Write(response)
+	if err != nil {
+		http.Error(w, fmt.Errorf("error: %v", err).Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) httpBatchResponse(w http.ResponseWriter, statusCode int, result batchResult) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	data := newBatchOutput(result)
+	response, _ := json.MarshalIndent(data, " ", "")
+	_, err := w.Write(response)
+	if err != nil {
+		http.Error(w, fmt.Errorf("error: %v", err).Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) httpUserResponse(w http.ResponseWriter, statusCode int, user models.User) {
+	h.httpCreatedUserResponse(w, statusCode, user, "")
+}
+
+func (h *Handler) httpCreatedUserResponse(
+	w http.ResponseWriter,
+	statusCode int,
+	user models.User,
+	apiKey string,
+) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	data := newUserOutput(user, apiKey)
+	response, _ := json.MarshalIndent(data, " ", "")
+	_, err := w.Write(response)
+	if err != nil {
+		http.Error(w, fmt.Errorf("error: %v", err).Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) httpFeedTokenResponse(w http.ResponseWriter, statusCode int, token string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	data := feedTokenOutput{Result: feedTokenResult{FeedToken: token}}
+	response, _ := json.MarshalIndent(data, " ", "")
+	_, err := w.Write(response)
+	if err != nil {
+		http.Error(w, fmt.Errorf("error: %v", err).Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) httpUsersResponse(w http.ResponseWriter, statusCode int, users []models.User) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	data := newErrorOutput(message)
-	resp, _ := json.MarshalIndent(data, " ", "")
+
+	data := newUsersOutput(users)
+	response, _ := json.MarshalIndent(data, " ", "")
+	_, err := w.Write(response)
+	if err != nil {
+		http.Error(w, fmt.Errorf("error: %v", err).Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) httpGroupResponse(w http.ResponseWriter, statusCode int, group models.Group) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	data := newGroupOutput(group)
+	response, _ := json.MarshalIndent(data, " ", "")
+	_, err := w.Write(response)
+	if err != nil {
+		http.Error(w, fmt.Errorf("error: %v", err).Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) httpGroupsResponse(w http.ResponseWriter, statusCode int, groups []models.Group) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	data := newGroupsOutput(groups)
+	response, _ := json.MarshalIndent(data, " ", "")
+	_, err := w.Write(response)
+	if err != nil {
+		http.Error(w, fmt.Errorf("error: %v", err).Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) httpTrashResponse(w http.ResponseWriter, statusCode int, trash []models.TrashedEvent) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	data := newTrashOutput(trash)
+	response, _ := json.MarshalIndent(data, " ", "")
+	_, err := w.Write(response)
+	if err != nil {
+		http.Error(w, fmt.Errorf("error: %v", err).Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) httpHistoryResponse(w http.ResponseWriter, statusCode int, entries []models.AuditEntry) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	data := newHistoryOutput(entries)
+	response, _ := json.MarshalIndent(data, " ", "")
+	_, err := w.Write(response)
+	if err != nil {
+		http.Error(w, fmt.Errorf("error: %v", err).Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) httpFreeBusyResponse(
+	w http.ResponseWriter,
+	statusCode int,
+	busy, free []models.Interval,
+) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	data := newFreeBusyOutput(busy, free)
+	response, _ := json.MarshalIndent(data, " ", "")
+	_, err := w.Write(response)
+	if err != nil {
+		http.Error(w, fmt.Errorf("error: %v", err).Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) httpHealthResponse(w http.ResponseWriter, statusCode int, status, err string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+
+	data := newHealthOutput(status, err)
+	response, _ := json.MarshalIndent(data, " ", "")
+	_, werr := w.Write(response)
+	if werr != nil {
+		http.Error(w, fmt.Errorf("error: %v", werr).Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) httpProblemResponse(w http.ResponseWriter, problem problemOutput) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	resp, _ := json.MarshalIndent(problem, " ", "")
 	_, err := w.Write(resp)
 	if err != nil {
 		http.Error(w, fmt.Errorf("error: %v", err).Error(), http.StatusInternalServerError)
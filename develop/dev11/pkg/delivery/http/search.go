@@ -0,0 +1,43 @@
+package http
+
+import (
+	"net/http"
+
+	"dev11/pkg/models"
+)
+
+const (
+	// maxSearchTextLength limits the q parameter of a search in bytes.
+	maxSearchTextLength = 256
+	// maxSearchTags limits the number of tag parameters of a search.
+	maxSearchTags = 20
+)
+
+// searchUserEvents returns the events matching the words of q by prefix and
+// all of the tag parameters. Within from and to occurrences of series are
+// returned instead of series.
+func (h *Handler) searchUserEvents(w http.ResponseWriter, r *http.Request) {
+	input, err := getSearchParamsInput(r.URL)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	userId := r.PathValue("id")
+	query := models.SearchQuery{Text: input.Text, Tags: input.Tags}
+	if !input.From.IsZero() {
+		loc, err := h.service.Location(userId, input.TimeZone)
+		if err != nil {
+			h.httpErrorResponse(w, r, err)
+			return
+		}
+		query.From, query.To = input.From.In(loc), input.To.In(loc)
+	}
+	events, err := h.service.SearchEvents(userId, query)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	h.httpEventsResponse(w, http.StatusOK, events)
+}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"dev11/pkg/models"
+)
+
+func TestHandler_SearchUserEvents(t *testing.T) {
+	ts := newTestServer(t, models.User{Id: "alice", TimeZone: "Europe/Moscow"})
+	for _, body := range []string{
+		`{"name":"Планёрка","date":"2024-03-04T10:00:00+03:00","rrule":"FREQ=DAILY;COUNT=3","tags":["work"]}`,
+		`{"name":"Обед","description":"план на выходные","date":"2024-03-05T13:00:00+03:00","tags":["personal"]}`,
+		`{"name":"Review","date":"2024-03-06T15:00:00+03:00","tags":["Work","review"]}`,
+	} {
+		if w := ts.do("alice", "POST", "/users/alice/events", body); w.Code != http.StatusCreated {
+			t.Fatalf("create status = %d: %s", w.Code, w.Body)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query url.Values
+		code  int
+		want  []string
+	}{
+		{"prefix", url.Values{"q": {"ПЛАН"}}, http.StatusOK, []string{"Планёрка", "Обед"}},
+		{"tag", url.Values{"tag": {"work"}}, http.StatusOK, []string{"Планёрка", "Review"}},
+		{"tags", url.Values{"tag": {"work", "REVIEW"}}, http.StatusOK, []string{"Review"}},
+		{
+			"range",
+			url.Values{"tag": {"work"}, "from": {"2024-03-05"}, "to": {"2024-03-07"}},
+			http.StatusOK,
+			[]string{"Планёрка", "Планёрка", "Review"},
+		},
+		{"range in the user's time zone", url.Values{"q": {"план"}, "from": {"2024-03-06"}, "to": {"2024-03-07"}}, http.StatusOK, []string{"Планёрка"}},
+		{"range in time zone", url.Values{"q": {"план"}, "from": {"2024-03-06"}, "to": {"2024-03-07"}, "tz": {"Pacific/Honolulu"}}, http.StatusOK, []string{}},
+		{"no query", url.Values{}, http.StatusBadRequest, nil},
+		{"half range", url.Values{"q": {"план"}, "from": {"2024-03-05"}}, http.StatusBadRequest, nil},
+		{"unknown time zone", url.Values{"q": {"план"}, "from": {"2024-03-05"}, "to": {"2024-03-06"}, "tz": {"Mars/Base"}}, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := ts.do("alice", "GET", "/users/alice/events/search?"+tt.query.Encode(), "")
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+			var output struct {
+				Result []models.Event `json:"result"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &output); err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, e := range output.Result {
+				names = append(names, e.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("events = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
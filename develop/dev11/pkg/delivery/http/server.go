@@ -2,24 +2,82 @@ package http
 
 import (
 	"context"
+	"errors"
+	"net"
 	"net/http"
-	"time"
+	"sync"
+
+	"dev11/pkg/config"
 )
 
 type Server struct {
+	mu         sync.Mutex
 	httpServer *http.Server
+	// closed is set by Shutdown, so a server started after it stops at
+	// once.
+	closed bool
+}
+
+// Run listens on the address from cfg and serves handler until Shutdown.
+func (s *Server) Run(cfg config.Server, handler http.Handler) error {
+	l, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l, cfg, handler)
 }
 
-func (s *Server) Run(port string, handler http.Handler) error {
-	s.httpServer = &http.Server{
-		Addr:         ":" + port,
-		Handler:      handler,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
+// Serve serves handler on l with HTTPS and HTTP/2 if TLS is configured,
+// plain HTTP/1.1 otherwise. It always returns an error, which is
+// http.ErrServerClosed after Shutdown.
+func (s *Server) Serve(l net.Listener, cfg config.Server, handler http.Handler) error {
+	srv := &http.Server{
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+	if cfg.TLS.Enabled() {
+		tlsConfig, err := newTLSConfig(cfg.TLS, l.Addr())
+		if err != nil {
+			l.Close()
+			return err
+		}
+		srv.TLSConfig = tlsConfig
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return http.ErrServerClosed
+	}
+	s.httpServer = srv
+	s.mu.Unlock()
+
+	if srv.TLSConfig != nil {
+		// the certificate is in TLSConfig already
+		return srv.ServeTLS(l, "", "")
 	}
-	return s.httpServer.ListenAndServe()
+	return srv.Serve(l)
 }
 
+// Shutdown stops accepting connections and waits for the requests in
+// flight to finish. If ctx is done first, the remaining connections are
+// closed and the error of ctx is returned.
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
+	s.mu.Lock()
+	s.closed = true
+	srv := s.httpServer
+	s.mu.Unlock()
+	if srv == nil {
+		return nil
+	}
+
+	err := srv.Shutdown(ctx)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		srv.Close()
+	}
+	return err
 }
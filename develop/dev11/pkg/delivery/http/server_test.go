@@ -0,0 +1,171 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"dev11/pkg/config"
+)
+
+// startServer serves handler on a free local port and returns its address
+// and the channel with the result of Serve.
+func startServer(t *testing.T, srv *Server, cfg config.Server, handler http.Handler) (string, <-chan error) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(l, cfg, handler) }()
+	return l.Addr().String(), served
+}
+
+func TestServer_ShutdownDrains(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	})
+	srv := new(Server)
+	addr, served := startServer(t, srv, config.Server{ReadHeaderTimeout: time.Second}, handler)
+
+	type result struct {
+		body string
+		err  error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			inFlight <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		inFlight <- result{string(body), err}
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- srv.Shutdown(ctx) }()
+
+	// the listener is closed first, while the request is still in flight
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err != nil {
+			break
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("new connections are accepted after Shutdown()")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case err := <-shutdown:
+		t.Fatalf("Shutdown() = %v before the request in flight finished", err)
+	default:
+	}
+
+	close(release)
+	if res := <-inFlight; res.err != nil || res.body != "done" {
+		t.Errorf("request in flight = %q, %v, want done", res.body, res.err)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown() = %v", err)
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("Serve() = %v, want %v", err, http.ErrServerClosed)
+	}
+}
+
+func TestServer_ShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})
+	srv := new(Server)
+	addr, served := startServer(t, srv, config.Server{}, handler)
+
+	inFlight := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/stuck")
+		if err == nil {
+			resp.Body.Close()
+		}
+		inFlight <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := srv.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := <-inFlight; err == nil {
+		t.Error("request in flight succeeded after its connection was closed")
+	}
+	<-served
+}
+
+func TestServer_ShutdownBeforeServe(t *testing.T) {
+	srv := new(Server)
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	_, served := startServer(t, srv, config.Server{}, http.NotFoundHandler())
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("Serve() after Shutdown() = %v, want %v", err, http.ErrServerClosed)
+	}
+}
+
+func TestServer_SelfSignedHTTP2(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	})
+	srv := new(Server)
+	cfg := config.Server{TLS: config.TLS{SelfSigned: true}}
+	addr, served := startServer(t, srv, cfg, handler)
+	defer func() {
+		srv.Shutdown(context.Background())
+		<-served
+	}()
+
+	// trust the certificate the server presents, as a developer would
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(conn.ConnectionState().PeerCertificates[0])
+	conn.Close()
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots},
+		ForceAttemptHTTP2: true,
+	}}
+	for _, host := range []string{"127.0.0.1", "localhost"} {
+		_, port, _ := net.SplitHostPort(addr)
+		resp, err := client.Get("https://" + net.JoinHostPort(host, port) + "/")
+		if err != nil {
+			t.Fatalf("GET via %s: %v", host, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.ProtoMajor != 2 || string(body) != "HTTP/2.0" {
+			t.Errorf("GET via %s used %s, want HTTP/2.0", host, body)
+		}
+	}
+}
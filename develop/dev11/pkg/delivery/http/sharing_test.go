@@ -0,0 +1,76 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"dev11/pkg/models"
+)
+
+func TestHandler_Sharing(t *testing.T) {
+	ts := newTestServer(t, models.User{Id: "alice"}, models.User{Id: "bob"}, models.User{Id: "carol"})
+	// alice can invite bob as a member of the same group
+	if _, err := ts.service.CreateGroup(models.Group{Id: "pair", Members: []string{"alice", "bob"}}); err != nil {
+		t.Fatal(err)
+	}
+	event, err := ts.service.CreateEvent("alice", models.Event{
+		Name:      "review",
+		Date:      time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC),
+		Attendees: []models.Attendee{{UserId: "bob"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		caller string
+		method string
+		target string
+		body   string
+		want   int
+	}{
+		{"user creates group", "alice", "POST", "/groups", `{"id":"team","members":["carol"]}`, http.StatusForbidden},
+		{"admin creates group", "admin", "POST", "/groups", `{"id":"team","members":["carol"]}`, http.StatusCreated},
+		{"member gets group", "carol", "GET", "/groups/team", "", http.StatusOK},
+		{"foreign user gets group", "bob", "GET", "/groups/team", "", http.StatusForbidden},
+		{"not shared yet", "carol", "GET", "/users/alice/events", "", http.StatusForbidden},
+		{"share with unknown group", "alice", "PUT", "/users/alice/shared-with", `{"groups":["other"]}`, http.StatusBadRequest},
+		{"share foreign calendar", "carol", "PUT", "/users/alice/shared-with", `{"groups":["team"]}`, http.StatusForbidden},
+		{"share", "alice", "PUT", "/users/alice/shared-with", `{"groups":["team"]}`, http.StatusOK},
+		{"member reads", "carol", "GET", "/users/alice/events", "", http.StatusOK},
+		{"member reads range", "carol", "GET", "/events?user_id=alice&from=2024-03-01&to=2024-04-01", "", http.StatusOK},
+		{"member reads day", "carol", "GET", "/events_for_day?user_id=alice&date=2024-03-04", "", http.StatusOK},
+		{"member writes", "carol", "POST", "/users/alice/events", `{"name":"a","date":"2024-03-05"}`, http.StatusForbidden},
+		{"member writes legacy", "carol", "POST", "/create_event", `{"userId":"alice","name":"a","date":"2024-03-05"}`, http.StatusForbidden},
+		{"foreign user reads", "bob", "GET", "/users/alice/events", "", http.StatusForbidden},
+		{"reads unknown user", "carol", "GET", "/users/nobody/events", "", http.StatusForbidden},
+		{"invitations", "bob", "GET", "/users/bob/invitations", "", http.StatusOK},
+		{"foreign invitations", "carol", "GET", "/users/bob/invitations", "", http.StatusForbidden},
+		{"respond", "bob", "PUT", "/users/bob/invitations/alice/" + event.Id, `{"status":"accepted"}`, http.StatusOK},
+		{"respond with unknown status", "bob", "PUT", "/users/bob/invitations/alice/" + event.Id, `{"status":"maybe"}`, http.StatusBadRequest},
+		{"respond without invitation", "carol", "PUT", "/users/carol/invitations/alice/" + event.Id, `{"status":"accepted"}`, http.StatusNotFound},
+		{"delete group", "admin", "DELETE", "/groups/team", "", http.StatusNoContent},
+		{"read after group deletion", "carol", "GET", "/users/alice/events", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := ts.do(tt.caller, tt.method, tt.target, tt.body)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+
+	w := ts.do("bob", "GET", "/events_for_day?user_id=bob&date=2024-03-04", "")
+	var output eventsOutput
+	if err = json.Unmarshal(w.Body.Bytes(), &output); err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Result) != 1 || output.Result[0].Organizer != "alice" ||
+		output.Result[0].Attendees[0].Status != models.StatusAccepted {
+		t.Errorf("day of the attendee = %+v", output.Result)
+	}
+}
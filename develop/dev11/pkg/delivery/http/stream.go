@@ -0,0 +1,93 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"dev11/pkg/models"
+)
+
+// streamHeartbeat is the interval of comments which keep idle streams from
+// being closed by proxies.
+const streamHeartbeat = 15 * time.Second
+
+// streamUserEvents streams changes of events of the user as server-sent
+// events. A client resuming with Last-Event-ID first receives the changes it
+// missed or, if they are no longer kept, a reset event after which it has to
+// reload the events.
+func (h *Handler) streamUserEvents(w http.ResponseWriter, r *http.Request) {
+	lastId, err := lastEventId(r)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	sub, err := h.service.SubscribeChanges(r.PathValue("id"), lastId)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	// the stream outlives the write timeout of the server
+	if err = rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if sub.Reset {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, c := range sub.Replay {
+		writeChangeEvent(w, c)
+	}
+	if err = rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case c, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			writeChangeEvent(w, c)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		if err = rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeChangeEvent(w http.ResponseWriter, c models.Change) {
+	data, _ := json.Marshal(c)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", c.Id, c.Type, data)
+}
+
+// lastEventId returns the id of the last change received by a resuming
+// client or nil.
+func lastEventId(r *http.Request) (*uint64, error) {
+	header := r.Header.Get("Last-Event-ID")
+	if header == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(header, 10, 64)
+	if err != nil {
+		return nil, models.Errorf(models.ErrValidation, "Last-Event-ID must be an id of a change")
+	}
+	return &id, nil
+}
@@ -0,0 +1,109 @@
+package http
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dev11/pkg/models"
+	"dev11/pkg/repository/cache"
+	"dev11/pkg/service"
+)
+
+// readEvent reads a server-sent event and returns its fields.
+func readEvent(t *testing.T, r *bufio.Reader) map[string]string {
+	t.Helper()
+	fields := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return fields
+		}
+		k, v, _ := strings.Cut(line, ": ")
+		fields[k] = v
+	}
+}
+
+func TestHandler_StreamUserEvents(t *testing.T) {
+	s := service.NewService(cache.NewUserCache(cache.NewCache()))
+	srv := httptest.NewServer(NewHandler(s, "secret").InitRoutes())
+	defer srv.Close()
+
+	subscribe := func(lastId string) (*http.Response, *bufio.Reader) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", srv.URL+"/users/1/events/stream", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		if lastId != "" {
+			req.Header.Set("Last-Event-ID", lastId)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("status = %d, content type = %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		return resp, bufio.NewReader(resp.Body)
+	}
+
+	resp, r := subscribe("")
+	defer resp.Body.Close()
+	event, err := s.CreateEvent("1", models.Event{Name: "standup", Date: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := readEvent(t, r)
+	if created["event"] != "created" || created["id"] == "" || !strings.Contains(created["data"], event.Id) {
+		t.Fatalf("unexpected event %v", created)
+	}
+	if err = s.DeleteEvent("1", event.Id, 0); err != nil {
+		t.Fatal(err)
+	}
+	if deleted := readEvent(t, r); deleted["event"] != "deleted" {
+		t.Fatalf("unexpected event %v", deleted)
+	}
+
+	resumed, r := subscribe(created["id"])
+	defer resumed.Body.Close()
+	if replayed := readEvent(t, r); replayed["event"] != "deleted" {
+		t.Errorf("replayed %v, want the deleted event", replayed)
+	}
+
+	reset, r := subscribe("1000")
+	defer reset.Body.Close()
+	if got := readEvent(t, r); got["event"] != "reset" {
+		t.Errorf("got %v, want the reset event", got)
+	}
+}
+
+func TestHandler_StreamUserEventsErrors(t *testing.T) {
+	routes := NewHandler(service.NewService(cache.NewUserCache(cache.NewCache())), "secret").InitRoutes()
+	tests := []struct {
+		name   string
+		target string
+		lastId string
+		want   int
+	}{
+		{"unknown user", "/users/2/events/stream", "", http.StatusNotFound},
+		{"invalid Last-Event-ID", "/users/1/events/stream", "abc", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.target, nil)
+			r.Header.Set("Authorization", "Bearer secret")
+			r.Header.Set("Last-Event-ID", tt.lastId)
+			w := httptest.NewRecorder()
+			routes.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
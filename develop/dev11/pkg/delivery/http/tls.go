@@ -0,0 +1,90 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"time"
+
+	"dev11/pkg/config"
+)
+
+// selfSignedValidity is how long a generated certificate is valid.
+const selfSignedValidity = 30 * 24 * time.Hour
+
+// newTLSConfig returns the TLS configuration serving HTTP/2 and HTTP/1.1
+// with the certificate from the files or, if cfg.SelfSigned is set, with a
+// new self-signed certificate for the local host and the address of the
+// listener.
+func newTLSConfig(cfg config.TLS, addr net.Addr) (*tls.Config, error) {
+	var cert tls.Certificate
+	var err error
+	if cfg.SelfSigned {
+		cert, err = selfSignedCertificate(addr)
+	} else {
+		cert, err = tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}, nil
+}
+
+// selfSignedCertificate generates a certificate for localhost, the loopback
+// addresses, the host name and the IP address of addr unless it listens on
+// all interfaces. Clients have to trust it explicitly, so its fingerprint
+// is logged.
+func selfSignedCertificate(addr net.Addr) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"dev11 development"}, CommonName: "localhost"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if host, err := os.Hostname(); err == nil && host != "localhost" {
+		template.DNSNames = append(template.DNSNames, host)
+	}
+	if tcp, ok := addr.(*net.TCPAddr); ok && !tcp.IP.IsUnspecified() && !tcp.IP.IsLoopback() {
+		template.IPAddresses = append(template.IPAddresses, tcp.IP)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	fingerprint := sha256.Sum256(der)
+	slog.Warn("serving HTTPS with a self-signed certificate, do not use it in production",
+		slog.String("sha256", hex.EncodeToString(fingerprint[:])),
+		slog.Time("notAfter", template.NotAfter),
+	)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
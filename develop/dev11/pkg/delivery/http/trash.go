@@ -0,0 +1,39 @@
+package http
+
+import "net/http"
+
+// listTrash returns the deleted events of the user which are not purged
+// yet.
+func (h *Handler) listTrash(w http.ResponseWriter, r *http.Request) {
+	trash, err := h.service.GetTrash(r.PathValue("id"))
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	h.httpTrashResponse(w, http.StatusOK, trash)
+}
+
+// restoreEvent returns the deleted event from the trash.
+func (h *Handler) restoreEvent(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("id")
+	event, err := h.actingService(r).RestoreEvent(userId, r.PathValue("eventId"))
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/users/"+userId+"/events/"+event.Id)
+	h.httpEventResponse(w, http.StatusOK, event)
+}
+
+// getEventHistory returns who changed the event, when and how.
+func (h *Handler) getEventHistory(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.service.GetEventHistory(r.PathValue("id"), r.PathValue("eventId"))
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	h.httpHistoryResponse(w, http.StatusOK, entries)
+}
@@ -0,0 +1,65 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"dev11/pkg/models"
+)
+
+func TestHandler_Trash(t *testing.T) {
+	ts := newTestServer(t, models.User{Id: "alice"}, models.User{Id: "bob"})
+	event, err := ts.service.As("alice").CreateEvent("alice", models.Event{Name: "review", Date: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := "/users/alice/events/" + event.Id
+
+	tests := []struct {
+		name   string
+		caller string
+		method string
+		target string
+		want   int
+	}{
+		{"delete", "alice", "DELETE", events, http.StatusNoContent},
+		{"get deleted", "alice", "GET", events, http.StatusNotFound},
+		{"trash", "alice", "GET", "/users/alice/trash", http.StatusOK},
+		{"foreign trash", "bob", "GET", "/users/alice/trash", http.StatusForbidden},
+		{"foreign restore", "bob", "POST", "/users/alice/trash/" + event.Id + "/restore", http.StatusForbidden},
+		{"restore unknown", "alice", "POST", "/users/alice/trash/unknown/restore", http.StatusNotFound},
+		{"restore", "alice", "POST", "/users/alice/trash/" + event.Id + "/restore", http.StatusOK},
+		{"restore twice", "alice", "POST", "/users/alice/trash/" + event.Id + "/restore", http.StatusNotFound},
+		{"get restored", "alice", "GET", events, http.StatusOK},
+		{"foreign history", "bob", "GET", events + "/history", http.StatusForbidden},
+		{"history of unknown event", "alice", "GET", "/users/alice/events/unknown/history", http.StatusNotFound},
+		{"admin history", "admin", "GET", events + "/history", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := ts.do(tt.caller, tt.method, tt.target, "")
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+
+	w := ts.do("alice", "GET", events+"/history", "")
+	var output struct {
+		Result []models.AuditEntry `json:"result"`
+	}
+	if err = json.Unmarshal(w.Body.Bytes(), &output); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range output.Result {
+		got = append(got, string(e.Type)+" by "+e.Actor)
+	}
+	want := []string{"created by alice", "deleted by alice", "restored by alice"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("history = %q, want %q", got, want)
+	}
+}
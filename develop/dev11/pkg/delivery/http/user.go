@@ -1,33 +1,42 @@
 package http
 
 import (
-	"log"
 	"net/http"
-	"time"
 
 	"dev11/pkg/models"
 )
 
 func (h *Handler) createEvent(w http.ResponseWriter, r *http.Request) {
-	if httpMethodErrorCheck(w, http.MethodPost, r.Method) {
+	input, err := h.decodeCreateEventBodyJSON(r)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	if err = h.authorize(r, input.UserId); err != nil {
+		h.httpErrorResponse(w, r, err)
 		return
 	}
 
-	input, err := h.decodeCreateEventBodyJSON(r)
+	loc, err := h.service.Location(input.UserId, input.TimeZone)
 	if err != nil {
-		h.httpErrorResponse(w, http.StatusBadRequest, err.Error())
-		log.Println(err.Error())
+		h.httpErrorResponse(w, r, err)
 		return
 	}
-
-	err = h.service.CreateEvent(input.UserId, models.Event{
+	date := input.Date.In(loc)
+	end, err := eventEnd(date, input.End, input.Duration, loc)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	_, err = h.actingService(r).CreateEvent(input.UserId, models.Event{
 		Name:        input.Name,
 		Description: input.Description,
-		Date:        time.Time(input.Date),
+		Date:        date,
+		End:         end,
+		TimeZone:    loc.String(),
 	})
 	if err != nil {
-		h.httpErrorResponse(w, http.StatusServiceUnavailable, err.Error())
-		log.Println(err.Error())
+		h.httpErrorResponse(w, r, err)
 		return
 	}
 
@@ -35,63 +44,123 @@ func (h *Handler) createEvent(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) updateEvent(w http.ResponseWriter, r *http.Request) {
-	if httpMethodErrorCheck(w, http.MethodPost, r.Method) {
-		return
-	}
-
 	input, err := h.decodeUpdateEventBodyJSON(r)
 	if err != nil {
-		h.httpErrorResponse(w, http.StatusBadRequest, err.Error())
+		h.httpErrorResponse(w, r, err)
 		return
 	}
-	err = h.service.UpdateEvent(input.UserId, models.Event{
-		Id:          input.EventId,
-		Name:        input.Name,
-		Description: input.Description,
-		Date:        time.Time(input.Date),
+	if err = h.authorize(r, input.UserId); err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	updated, err := h.actingService(r).PatchEvent(input.UserId, input.EventId, 0, func(event *models.Event) error {
+		loc, err := h.service.Location(input.UserId, input.timeZone(event))
+		if err != nil {
+			return err
+		}
+		return input.apply(event, loc)
 	})
 	if err != nil {
-		h.httpErrorResponse(w, http.StatusServiceUnavailable, err.Error())
+		h.httpErrorResponse(w, r, err)
 		return
 	}
 
-	h.httpSuccessEventActionResponse(w, http.StatusOK, "Event was updated")
+	h.httpEventResponse(w, http.StatusOK, updated)
 }
 
 func (h *Handler) deleteEvent(w http.ResponseWriter, r *http.Request) {
-	if httpMethodErrorCheck(w, http.MethodPost, r.Method) {
+	input, err := h.decodeDeleteEventBodyJSON(r)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	if err = h.authorize(r, input.UserId); err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	err = h.actingService(r).DeleteEvent(input.UserId, input.EventId, 0)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
 		return
 	}
 
-	input, err := h.decodeDeleteEventBodyJSON(r)
+	h.httpSuccessEventActionResponse(w, http.StatusOK, "Event was deleted")
+}
+
+func (h *Handler) getEventsInRange(w http.ResponseWriter, r *http.Request) {
+	input, err := getRangeParamsInput(r.URL)
 	if err != nil {
-		h.httpErrorResponse(w, http.StatusBadRequest, err.Error())
+		h.httpErrorResponse(w, r, err)
 		return
 	}
-	err = h.service.DeleteEvent(input.UserId, input.EventId)
+	if err = h.authorizeRead(r, input.UserId); err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	loc, err := h.service.Location(input.UserId, input.TimeZone)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	from, to := input.From.In(loc), input.To.In(loc)
+	if !from.Before(to) {
+		h.httpErrorResponse(w, r, models.Errorf(models.ErrValidation, "from must be before to"))
+		return
+	}
+	events, err := h.actingService(r).GetEventsInRange(input.UserId, from, to)
 	if err != nil {
-		h.httpErrorResponse(w, http.StatusServiceUnavailable, err.Error())
+		h.httpErrorResponse(w, r, err)
 		return
 	}
 
-	h.httpSuccessEventActionResponse(w, http.StatusOK, "Event was deleted")
+	page, nextCursor := paginate(events, input.Limit, input.Cursor)
+	h.httpEventsPageResponse(w, http.StatusOK, page, nextCursor)
 }
 
-func (h *Handler) getEventsForDay(w http.ResponseWriter, r *http.Request) {
-	if httpMethodErrorCheck(w, http.MethodGet, r.Method) {
+func (h *Handler) getFreeBusy(w http.ResponseWriter, r *http.Request) {
+	input, err := getIntervalParamsInput(r.URL)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	userId := r.PathValue("id")
+	loc, err := h.service.Location(userId, input.TimeZone)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
 		return
 	}
+	from, to := input.From.In(loc), input.To.In(loc)
+	if !from.Before(to) {
+		h.httpErrorResponse(w, r, models.Errorf(models.ErrValidation, "from must be before to"))
+		return
+	}
+	busy, free, err := h.service.GetFreeBusy(userId, from, to)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	h.httpFreeBusyResponse(w, http.StatusOK, busy, free)
+}
 
-	userId, date, err := getParamsInput(r.URL)
+func (h *Handler) getEventsForDay(w http.ResponseWriter, r *http.Request) {
+	input, err := getParamsInput(r.URL)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	if err = h.authorizeRead(r, input.UserId); err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	loc, err := h.service.Location(input.UserId, input.TimeZone)
 	if err != nil {
-		h.httpErrorResponse(w, http.StatusBadRequest, err.Error())
-		log.Println(err.Error())
+		h.httpErrorResponse(w, r, err)
 		return
 	}
-	events, err := h.service.GetEventsForDay(userId, date)
+	events, err := h.actingService(r).GetEventsForDay(input.UserId, input.Date.In(loc))
 	if err != nil {
-		h.httpErrorResponse(w, http.StatusServiceUnavailable, err.Error())
-		log.Println(err.Error())
+		h.httpErrorResponse(w, r, err)
 		return
 	}
 
@@ -99,18 +168,23 @@ func (h *Handler) getEventsForDay(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) getEventsForWeek(w http.ResponseWriter, r *http.Request) {
-	if httpMethodErrorCheck(w, http.MethodGet, r.Method) {
+	input, err := getParamsInput(r.URL)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
 		return
 	}
-
-	userId, date, err := getParamsInput(r.URL)
+	if err = h.authorizeRead(r, input.UserId); err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	loc, err := h.service.Location(input.UserId, input.TimeZone)
 	if err != nil {
-		h.httpErrorResponse(w, http.StatusBadRequest, err.Error())
+		h.httpErrorResponse(w, r, err)
 		return
 	}
-	events, err := h.service.GetEventsForWeek(userId, date)
+	events, err := h.actingService(r).GetEventsForWeek(input.UserId, input.Date.In(loc))
 	if err != nil {
-		h.httpErrorResponse(w, http.StatusServiceUnavailable, err.Error())
+		h.httpErrorResponse(w, r, err)
 		return
 	}
 
@@ -118,20 +192,104 @@ func (h *Handler) getEventsForWeek(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) getEventsForMonth(w http.ResponseWriter, r *http.Request) {
-	if httpMethodErrorCheck(w, http.MethodGet, r.Method) {
+	input, err := getParamsInput(r.URL)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
 		return
 	}
-
-	userId, date, err := getParamsInput(r.URL)
+	if err = h.authorizeRead(r, input.UserId); err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	loc, err := h.service.Location(input.UserId, input.TimeZone)
 	if err != nil {
-		h.httpErrorResponse(w, http.StatusBadRequest, err.Error())
+		h.httpErrorResponse(w, r, err)
 		return
 	}
-	events, err := h.service.GetEventsForMonth(userId, date)
+	events, err := h.actingService(r).GetEventsForMonth(input.UserId, input.Date.In(loc))
 	if err != nil {
-		h.httpErrorResponse(w, http.StatusServiceUnavailable, err.Error())
+		h.httpErrorResponse(w, r, err)
 		return
 	}
 
 	h.httpEventsResponse(w, http.StatusOK, events)
 }
+
+func (h *Handler) setUserTimeZone(w http.ResponseWriter, r *http.Request) {
+	input, err := h.decodeUserBodyJSON(r)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	user, err := h.service.SetUserTimeZone(r.PathValue("id"), input.TimeZone)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	h.httpUserResponse(w, http.StatusOK, *user)
+}
+
+// resetFeedToken replaces the token with which the exported calendar of the
+// user can be read, e.g. by calendar applications subscribed to it.
+func (h *Handler) resetFeedToken(w http.ResponseWriter, r *http.Request) {
+	token, err := h.service.ResetFeedToken(r.PathValue("id"))
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	h.httpFeedTokenResponse(w, http.StatusOK, token)
+}
+
+// createUser creates a user. Only an admin can create another admin.
+func (h *Handler) createUser(w http.ResponseWriter, r *http.Request) {
+	input, err := h.decodeCreateUserBodyJSON(r)
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+	if caller := principal(r); input.Role == models.RoleAdmin && (caller == nil || !caller.IsAdmin()) {
+		h.httpErrorResponse(w, r, models.Errorf(models.ErrForbidden, "admin role required to create an admin"))
+		return
+	}
+
+	user, key, err := h.service.CreateUser(input.user())
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/users/"+user.Id)
+	h.httpCreatedUserResponse(w, http.StatusCreated, *user, key)
+}
+
+func (h *Handler) listUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.service.GetUsers()
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	h.httpUsersResponse(w, http.StatusOK, users)
+}
+
+func (h *Handler) getUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.service.GetUser(r.PathValue("id"))
+	if err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	h.httpUserResponse(w, http.StatusOK, *user)
+}
+
+func (h *Handler) deleteUser(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteUser(r.PathValue("id")); err != nil {
+		h.httpErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
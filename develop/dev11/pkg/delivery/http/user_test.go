@@ -0,0 +1,186 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"dev11/pkg/models"
+)
+
+// newLegacyTestServer returns a server with events of alice in Moscow on
+// Monday 4 March 2024 from 9 to 10, the Sunday of that week, the next Monday and in
+// April, and the id of the first one.
+func newLegacyTestServer(t *testing.T) (*testServer, string) {
+	t.Helper()
+	ts := newTestServer(t, models.User{Id: "alice", TimeZone: "Europe/Moscow"})
+	moscow := time.FixedZone("MSK", 3*60*60)
+	standupEnd := time.Date(2024, 3, 4, 10, 0, 0, 0, moscow)
+	var first string
+	for i, e := range []models.Event{
+		{Name: "standup", Date: time.Date(2024, 3, 4, 9, 0, 0, 0, moscow), End: &standupEnd},
+		{Name: "demo", Date: time.Date(2024, 3, 10, 18, 0, 0, 0, moscow)},
+		{Name: "sync", Date: time.Date(2024, 3, 11, 0, 30, 0, 0, moscow)},
+		{Name: "offsite", Date: time.Date(2024, 4, 1, 10, 0, 0, 0, moscow)},
+	} {
+		created, err := ts.service.CreateEvent("alice", e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			first = created.Id
+		}
+	}
+	return ts, first
+}
+
+// storedEvents describes the events of alice without their ids.
+func storedEvents(t *testing.T, ts *testServer) []string {
+	t.Helper()
+	events, err := ts.service.GetEvents("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := make([]string, 0, len(events))
+	for _, e := range events {
+		var end time.Time
+		if e.End != nil {
+			end = e.End.UTC()
+		}
+		stored = append(stored, fmt.Sprintf("%s %q %s %s", e.Name, e.Description, e.Date.UTC(), end))
+	}
+	return stored
+}
+
+// responseEventNames returns the names of the events in the body of a
+// response.
+func responseEventNames(t *testing.T, body []byte) []string {
+	t.Helper()
+	var output struct {
+		Result []models.Event `json:"result"`
+	}
+	if err := json.Unmarshal(body, &output); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, e := range output.Result {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+// TestHandler_LegacyAliases checks that every legacy RPC-style endpoint
+// behaves as the resource-oriented route it is an alias of. {id} in targets
+// and bodies is replaced with the id of the first event.
+func TestHandler_LegacyAliases(t *testing.T) {
+	type request struct {
+		method, target, body string
+		want                 int
+	}
+	tests := []struct {
+		name   string
+		legacy request
+		rest   request
+		// events are the names of the events returned by queries.
+		events []string
+	}{
+		{
+			name:   "create",
+			legacy: request{"POST", "/create_event", `{"userId":"alice","name":"retro","date":"2024-03-05T10:00:00+03:00","duration":"1h"}`, http.StatusOK},
+			rest:   request{"POST", "/users/alice/events", `{"name":"retro","date":"2024-03-05T10:00:00+03:00","duration":"1h"}`, http.StatusCreated},
+		},
+		{
+			name:   "create in time zone",
+			legacy: request{"POST", "/create_event", `{"userId":"alice","name":"retro","date":"2024-03-05","timezone":"Asia/Tokyo"}`, http.StatusOK},
+			rest:   request{"POST", "/users/alice/events", `{"name":"retro","date":"2024-03-05","timezone":"Asia/Tokyo"}`, http.StatusCreated},
+		},
+		{
+			name:   "create conflicting",
+			legacy: request{"POST", "/create_event", `{"userId":"alice","name":"retro","date":"2024-03-04T09:30:00+03:00","duration":"1h"}`, http.StatusConflict},
+			rest:   request{"POST", "/users/alice/events", `{"name":"retro","date":"2024-03-04T09:30:00+03:00","duration":"1h"}`, http.StatusConflict},
+		},
+		{
+			name:   "create without name",
+			legacy: request{"POST", "/create_event", `{"userId":"alice","date":"2024-03-05"}`, http.StatusBadRequest},
+			rest:   request{"POST", "/users/alice/events", `{"date":"2024-03-05"}`, http.StatusBadRequest},
+		},
+		{
+			name:   "update",
+			legacy: request{"POST", "/update_event", `{"userId":"alice","eventId":"{id}","description":"agenda","date":"2024-03-05"}`, http.StatusOK},
+			rest:   request{"PATCH", "/users/alice/events/{id}", `{"description":"agenda","date":"2024-03-05"}`, http.StatusOK},
+		},
+		{
+			name:   "update unknown",
+			legacy: request{"POST", "/update_event", `{"userId":"alice","eventId":"unknown","description":"agenda"}`, http.StatusNotFound},
+			rest:   request{"PATCH", "/users/alice/events/unknown", `{"description":"agenda"}`, http.StatusNotFound},
+		},
+		{
+			name:   "delete",
+			legacy: request{"POST", "/delete_event", `{"userId":"alice","eventId":"{id}"}`, http.StatusOK},
+			rest:   request{"DELETE", "/users/alice/events/{id}", "", http.StatusNoContent},
+		},
+		{
+			name:   "delete unknown",
+			legacy: request{"POST", "/delete_event", `{"userId":"alice","eventId":"unknown"}`, http.StatusNotFound},
+			rest:   request{"DELETE", "/users/alice/events/unknown", "", http.StatusNotFound},
+		},
+		{
+			name:   "day",
+			legacy: request{"GET", "/events_for_day?user_id=alice&date=2024-03-04", "", http.StatusOK},
+			rest:   request{"GET", "/events?user_id=alice&from=2024-03-04&to=2024-03-05", "", http.StatusOK},
+			events: []string{"standup"},
+		},
+		{
+			name:   "week",
+			legacy: request{"GET", "/events_for_week?user_id=alice&date=2024-03-06", "", http.StatusOK},
+			rest:   request{"GET", "/events?user_id=alice&from=2024-03-04&to=2024-03-11", "", http.StatusOK},
+			events: []string{"standup", "demo"},
+		},
+		{
+			name:   "week in time zone",
+			legacy: request{"GET", "/events_for_week?user_id=alice&date=2024-03-06&tz=UTC", "", http.StatusOK},
+			rest:   request{"GET", "/events?user_id=alice&from=2024-03-04&to=2024-03-11&tz=UTC", "", http.StatusOK},
+			events: []string{"standup", "demo", "sync"},
+		},
+		{
+			name:   "month",
+			legacy: request{"GET", "/events_for_month?user_id=alice&date=2024-03-15", "", http.StatusOK},
+			rest:   request{"GET", "/events?user_id=alice&from=2024-03-01&to=2024-04-01", "", http.StatusOK},
+			events: []string{"standup", "demo", "sync"},
+		},
+		{
+			name:   "unknown user",
+			legacy: request{"GET", "/events_for_day?user_id=bob&date=2024-03-04", "", http.StatusForbidden},
+			rest:   request{"GET", "/events?user_id=bob&from=2024-03-04&to=2024-03-05", "", http.StatusForbidden},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored, names [2][]string
+			for i, req := range []request{tt.legacy, tt.rest} {
+				ts, id := newLegacyTestServer(t)
+				w := ts.do("alice", req.method, strings.ReplaceAll(req.target, "{id}", id), strings.ReplaceAll(req.body, "{id}", id))
+				if w.Code != req.want {
+					t.Fatalf("%s %s: status = %d, want %d: %s", req.method, req.target, w.Code, req.want, w.Body)
+				}
+				stored[i] = storedEvents(t, ts)
+				if req.method == "GET" && w.Code == http.StatusOK {
+					names[i] = responseEventNames(t, w.Body.Bytes())
+				}
+			}
+			if !reflect.DeepEqual(stored[0], stored[1]) {
+				t.Errorf("events after the legacy request = %q, after the route = %q", stored[0], stored[1])
+			}
+			if !reflect.DeepEqual(names[0], names[1]) {
+				t.Errorf("legacy request returned %q, the route %q", names[0], names[1])
+			}
+			if tt.events != nil && !reflect.DeepEqual(names[1], tt.events) {
+				t.Errorf("events = %q, want %q", names[1], tt.events)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"dev11/pkg/models"
+)
+
+// maxBodySize limits the size of JSON request bodies.
+const maxBodySize = 1 << 20
+
+// Dates outside of [minDate, maxDate) are rejected by the "date" rule.
+var (
+	minDate = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+	maxDate = time.Date(2200, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+type fieldError struct {
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// validationError lists the fields of the request which are invalid.
+type validationError []fieldError
+
+func (e validationError) Error() string {
+	parts := make([]string, 0, len(e))
+	for _, f := range e {
+		parts = append(parts, f.Field+": "+f.Error)
+	}
+	return "invalid request: " + strings.Join(parts, "; ")
+}
+
+func (e validationError) Is(target error) bool {
+	return target == models.ErrValidation
+}
+
+// decodeBodyJSON decodes the JSON body of the request into input and
+// validates it. Unknown fields and bodies larger than maxBodySize are
+// rejected.
+func decodeBodyJSON(r *http.Request, input any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(input); err != nil {
+		return decodeError(err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return err
+		}
+		return models.Errorf(models.ErrValidation, "request body must contain a single JSON object")
+	}
+	return validate(input)
+}
+
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return validationError{{Field: typeErr.Field, Error: "must be " + jsonType(typeErr.Type)}}
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return validationError{{Field: strings.Trim(field, `"`), Error: "unknown field"}}
+	}
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return err
+	case errors.Is(err, io.EOF):
+		return models.Errorf(models.ErrValidation, "request body is empty")
+	default:
+		return models.NewError(models.ErrValidation, err)
+	}
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Slice:
+		return "an array"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	case reflect.Bool:
+		return "a boolean"
+	default:
+		return "a number"
+	}
+}
+
+// optionalField is implemented by fields of merge patches. Rules apply to
+// their value only if they are present in the patch; a null value breaks the
+// required rule.
+type optionalField interface {
+	state() (value reflect.Value, set, null bool)
+}
+
+// validate checks the fields of the struct v points to against the rules in
+// their validate tags:
+//
+//	required  the field is set; strings must not be blank
+//	max=N     a string has at most N characters, a slice at most N items
+//	date      dates are within [minDate, maxDate)
+//
+// Fields of embedded structs are checked as well.
+func validate(v any) error {
+	errs := validateStruct(reflect.Indirect(reflect.ValueOf(v)))
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateStruct(rv reflect.Value) validationError {
+	rt := rv.Type()
+	var errs validationError
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			errs = append(errs, validateStruct(rv.Field(i))...)
+			continue
+		}
+		tag := f.Tag.Get("validate")
+		if tag == "" || !f.IsExported() {
+			continue
+		}
+		if msg := checkField(rv.Field(i), tag); msg != "" {
+			errs = append(errs, fieldError{Field: jsonName(f), Error: msg})
+		}
+	}
+	return errs
+}
+
+// validateOperations checks the operations of a batch and the events they
+// take.
+func validateOperations(ops []operationInput) validationError {
+	var errs validationError
+	for i, op := range ops {
+		prefix := fmt.Sprintf("operations[%d].", i)
+		for _, f := range validateStruct(reflect.ValueOf(op)) {
+			errs = append(errs, fieldError{Field: prefix + f.Field, Error: f.Error})
+		}
+		if op.Event != nil {
+			for _, f := range validateStruct(reflect.ValueOf(*op.Event)) {
+				errs = append(errs, fieldError{Field: prefix + "event." + f.Field, Error: f.Error})
+			}
+		}
+
+		switch op.Op {
+		case models.OperationCreate, models.OperationUpdate, models.OperationDelete:
+		default:
+			if op.Op != "" {
+				errs = append(errs, fieldError{Field: prefix + "op", Error: "must be one of create, update, delete"})
+			}
+			continue
+		}
+		if op.Event == nil && op.Op != models.OperationDelete {
+			errs = append(errs, fieldError{Field: prefix + "event", Error: "is required"})
+		}
+		if strings.TrimSpace(op.EventId) == "" && op.Op != models.OperationCreate {
+			errs = append(errs, fieldError{Field: prefix + "eventId", Error: "is required"})
+		}
+	}
+	return errs
+}
+
+// checkField returns the reason the value breaks the first of the rules or
+// an empty string.
+func checkField(v reflect.Value, rules string) string {
+	if o, ok := v.Interface().(optionalField); ok {
+		value, set, null := o.state()
+		if null && slices.Contains(strings.Split(rules, ","), "required") {
+			return "can not be null"
+		}
+		if !set || null {
+			return ""
+		}
+		v = value
+	}
+	for _, rule := range strings.Split(rules, ",") {
+		if msg := checkRule(v, rule); msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+// checkRule returns the reason the value breaks the rule or an empty string.
+func checkRule(v reflect.Value, rule string) string {
+	name, arg, _ := strings.Cut(rule, "=")
+	switch name {
+	case "required":
+		if isBlank(v) {
+			return "is required"
+		}
+	case "max":
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			panic(fmt.Sprintf("validate: invalid rule %q", rule))
+		}
+		switch v.Kind() {
+		case reflect.String:
+			if utf8.RuneCountInString(v.String()) > n {
+				return fmt.Sprintf("must be at most %d characters long", n)
+			}
+		case reflect.Slice:
+			if v.Len() > n {
+				return fmt.Sprintf("must contain at most %d items", n)
+			}
+		}
+	case "date":
+		for _, t := range dates(v) {
+			if t.Before(minDate) || !t.Before(maxDate) {
+				return fmt.Sprintf("must be between %s and %s",
+					minDate.Format(time.DateOnly), maxDate.Format(time.DateOnly))
+			}
+		}
+	default:
+		panic(fmt.Sprintf("validate: unknown rule %q", rule))
+	}
+	return ""
+}
+
+func isBlank(v reflect.Value) bool {
+	switch x := v.Interface().(type) {
+	case InputDate:
+		return x.IsZero()
+	case string:
+		return strings.TrimSpace(x) == ""
+	}
+	if v.Kind() == reflect.Slice {
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// dates returns the set dates held by v.
+func dates(v reflect.Value) []time.Time {
+	switch x := v.Interface().(type) {
+	case InputDate:
+		if !x.IsZero() {
+			return []time.Time{x.time}
+		}
+	case time.Time:
+		if !x.IsZero() {
+			return []time.Time{x}
+		}
+	case []time.Time:
+		return x
+	}
+	return nil
+}
+
+func jsonName(f reflect.StructField) string {
+	if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" {
+		return name
+	}
+	return f.Name
+}
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeBodyJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		fields []string
+		errMsg string
+	}{
+		{"valid", `{"name":"standup","date":"2024-03-04T10:00:00Z"}`, nil, ""},
+		{"missing fields", `{}`, []string{"name", "date"}, ""},
+		{"blank name", `{"name":"  ","date":"2024-03-04"}`, []string{"name"}, ""},
+		{"long name", `{"name":"` + strings.Repeat("я", 257) + `","date":"2024-03-04"}`, []string{"name"}, ""},
+		{"date out of bounds", `{"name":"a","date":"2400-01-01"}`, []string{"date"}, ""},
+		{"exdate out of bounds", `{"name":"a","date":"2024-03-04","exdates":["1800-01-01T00:00:00Z"]}`, []string{"exdates"}, ""},
+		{"unknown field", `{"name":"a","date":"2024-03-04","title":"b"}`, []string{"title"}, ""},
+		{"wrong type", `{"name":1,"date":"2024-03-04"}`, []string{"name"}, ""},
+		{"empty body", ``, nil, "request body is empty"},
+		{"trailing data", `{"name":"a","date":"2024-03-04"} {}`, nil, "request body must contain a single JSON object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			err := decodeBodyJSON(r, &eventInput{})
+
+			var got []string
+			var fields validationError
+			if errors.As(err, &fields) {
+				for _, f := range fields {
+					got = append(got, f.Field)
+				}
+			} else if err != nil && err.Error() != tt.errMsg {
+				t.Fatalf("error = %v, want %q", err, tt.errMsg)
+			}
+			if !reflect.DeepEqual(got, tt.fields) {
+				t.Errorf("invalid fields = %v, want %v (%v)", got, tt.fields, err)
+			}
+		})
+	}
+}
+
+func TestHandler_ValidationErrorResponse(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"date":"2024-03-04"}`))
+	w := httptest.NewRecorder()
+	h := &Handler{}
+	if _, err := h.decodeEventBodyJSON(r); err != nil {
+		h.httpErrorResponse(w, r, err)
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	var out problemOutput
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	want := []fieldError{{Field: "name", Error: "is required"}}
+	if !reflect.DeepEqual(out.Fields, want) {
+		t.Errorf("fields = %v, want %v", out.Fields, want)
+	}
+
+	for _, body := range []string{
+		`{"name":"` + strings.Repeat("a", maxBodySize) + `"}`,
+		`{"name":"a","date":"2024-03-04"}` + strings.Repeat(" ", maxBodySize),
+	} {
+		r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		w = httptest.NewRecorder()
+		if _, err := h.decodeEventBodyJSON(r); err != nil {
+			h.httpErrorResponse(w, r, err)
+		}
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+// Package ical encodes and decodes events in the iCalendar format (RFC 5545).
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"dev11/pkg/models"
+)
+
+const (
+	prodId        = "-//dev11//calendar//EN"
+	maxLineOctets = 75
+
+	dateTimeUTCLayout = "20060102T150405Z"
+	dateTimeLayout    = "20060102T150405"
+	dateLayout        = "20060102"
+)
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", "")
+
+var textUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+var ErrNoCalendar = errors.New("ical: VCALENDAR component not found")
+
+// Encode writes events as a VCALENDAR with the given name. Occurrences
+// detached from a series are written with the UID of the series and
+// RECURRENCE-ID.
+func Encode(w io.Writer, name string, events []models.Event) error {
+	uids := make(map[string]string, len(events))
+	for _, e := range events {
+		uids[e.Id] = uid(e)
+	}
+
+	bw := bufio.NewWriter(w)
+	write := func(line string) {
+		fold(bw, line)
+	}
+	write("BEGIN:VCALENDAR")
+	write("VERSION:2.0")
+	write("PRODID:" + prodId)
+	write("CALSCALE:GREGORIAN")
+	if name != "" {
+		write("X-WR-CALNAME:" + textEscaper.Replace(name))
+	}
+
+	stamp := time.Now().UTC().Format(dateTimeUTCLayout)
+	for _, e := range events {
+		write("BEGIN:VEVENT")
+		if e.SeriesId != "" && e.RecurrenceId != nil {
+			seriesUid, ok := uids[e.SeriesId]
+			if !ok {
+				seriesUid = uid(models.Event{Id: e.SeriesId})
+			}
+			write("UID:" + seriesUid)
+			write(timeProperty("RECURRENCE-ID", *e.RecurrenceId, e.TimeZone))
+		} else {
+			write("UID:" + uids[e.Id])
+		}
+		write("DTSTAMP:" + stamp)
+		write(timeProperty("DTSTART", e.Date, e.TimeZone))
+		if e.End != nil {
+			write(timeProperty("DTEND", *e.End, e.TimeZone))
+		}
+		write("SUMMARY:" + textEscaper.Replace(e.Name))
+		if e.Description != "" {
+			write("DESCRIPTION:" + textEscaper.Replace(e.Description))
+		}
+		if len(e.Tags) > 0 {
+			tags := make([]string, len(e.Tags))
+			for i, t := range e.Tags {
+				tags[i] = textEscaper.Replace(t)
+			}
+			write("CATEGORIES:" + strings.Join(tags, ","))
+		}
+		if e.RRule != "" {
+			write("RRULE:" + strings.TrimPrefix(e.RRule, "RRULE:"))
+		}
+		for _, d := range e.ExDates {
+			write(timeProperty("EXDATE", d, e.TimeZone))
+		}
+		write("END:VEVENT")
+	}
+	write("END:VCALENDAR")
+	return bw.Flush()
+}
+
+func uid(e models.Event) string {
+	if e.UID != "" {
+		return e.UID
+	}
+	return e.Id + "@dev11"
+}
+
+// timeProperty formats a DATE-TIME property as a local time with TZID if tz
+// is set or as an UTC time otherwise.
+func timeProperty(name string, t time.Time, tz string) string {
+	if tz != "" && tz != "UTC" {
+		if loc, err := models.LoadLocation(tz); err == nil {
+			return name + ";TZID=" + tz + ":" + t.In(loc).Format(dateTimeLayout)
+		}
+	}
+	return name + ":" + t.UTC().Format(dateTimeUTCLayout)
+}
+
+// fold writes a content line splitting it into lines of at most 75 octets
+// without breaking UTF-8 sequences.
+func fold(w *bufio.Writer, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// Event is a decoded VEVENT. Err is set if the component could not be mapped
+// onto models.Event.
+type Event struct {
+	Event models.Event
+	Err   error
+}
+
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Decode reads VEVENT components of the first VCALENDAR in r. Errors in a
+// single component are reported in its Event.Err and do not stop decoding.
+func Decode(r io.Reader) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		events     []Event
+		inCalendar bool
+		component  []property
+		depth      int
+	)
+	for i, line := range lines {
+		p, err := parseProperty(line)
+		if err != nil {
+			if component != nil {
+				component = append(component, property{name: "X-ERROR", value: err.Error()})
+				continue
+			}
+			return nil, fmt.Errorf("ical: line %d: %w", i+1, err)
+		}
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VCALENDAR"):
+			inCalendar = true
+		case p.name == "END" && strings.EqualFold(p.value, "VCALENDAR"):
+			if !inCalendar {
+				return nil, ErrNoCalendar
+			}
+			return events, nil
+		case !inCalendar:
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT") && component == nil:
+			component = []property{}
+		case p.name == "END" && strings.EqualFold(p.value, "VEVENT") && depth == 0 && component != nil:
+			events = append(events, decodeEvent(component))
+			component = nil
+		case component != nil && p.name == "BEGIN":
+			// nested components like VALARM are skipped
+			depth++
+		case component != nil && p.name == "END":
+			depth--
+		case component != nil && depth == 0:
+			component = append(component, p)
+		}
+	}
+	if !inCalendar {
+		return nil, ErrNoCalendar
+	}
+	return nil, errors.New("ical: unexpected end of VCALENDAR")
+}
+
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, sc.Err()
+}
+
+func parseProperty(line string) (property, error) {
+	p := property{params: map[string]string{}}
+	colon := -1
+	inQuotes := false
+	for i, c := range line {
+		if c == '"' {
+			inQuotes = !inQuotes
+		} else if c == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return p, fmt.Errorf("malformed content line %q", line)
+	}
+	p.value = line[colon+1:]
+	parts := strings.Split(line[:colon], ";")
+	p.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		k, v, _ := strings.Cut(param, "=")
+		p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+	}
+	return p, nil
+}
+
+func decodeEvent(props []property) Event {
+	var (
+		e    models.Event
+		errs []error
+	)
+	for _, p := range props {
+		var err error
+		switch p.name {
+		case "UID":
+			e.UID = p.value
+		case "SUMMARY":
+			e.Name = textUnescaper.Replace(p.value)
+		case "DESCRIPTION":
+			e.Description = textUnescaper.Replace(p.value)
+		case "CATEGORIES":
+			for _, v := range splitText(p.value) {
+				if v = textUnescaper.Replace(v); v != "" {
+					e.Tags = append(e.Tags, v)
+				}
+			}
+		case "DTSTART":
+			e.Date, err = parseTime(p)
+			e.TimeZone = p.params["TZID"]
+		case "DTEND":
+			var t time.Time
+			if t, err = parseTime(p); err == nil {
+				e.End = &t
+			}
+		case "RRULE":
+			e.RRule = p.value
+		case "EXDATE":
+			for _, v := range strings.Split(p.value, ",") {
+				var t time.Time
+				if t, err = parseTime(property{params: p.params, value: v}); err != nil {
+					break
+				}
+				e.ExDates = append(e.ExDates, t)
+			}
+		case "RECURRENCE-ID":
+			var t time.Time
+			if t, err = parseTime(p); err == nil {
+				e.RecurrenceId = &t
+			}
+		case "X-ERROR":
+			err = errors.New(p.value)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.name, err))
+		}
+	}
+	if e.Date.IsZero() && len(errs) == 0 {
+		errs = append(errs, errors.New("DTSTART is required"))
+	}
+	return Event{Event: e, Err: errors.Join(errs...)}
+}
+
+// splitText splits a list of text values at commas which are not escaped.
+func splitText(value string) []string {
+	var values []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ',':
+			values = append(values, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(values, value[start:])
+}
+
+func parseTime(p property) (time.Time, error) {
+	loc := time.UTC
+	if tzid, ok := p.params["TZID"]; ok {
+		var err error
+		if loc, err = models.LoadLocation(tzid); err != nil {
+			return time.Time{}, err
+		}
+	}
+	switch {
+	case strings.EqualFold(p.params["VALUE"], "DATE") || len(p.value) == len(dateLayout):
+		return time.ParseInLocation(dateLayout, p.value, loc)
+	case strings.HasSuffix(p.value, "Z"):
+		return time.Parse(dateTimeUTCLayout, p.value)
+	default:
+		return time.ParseInLocation(dateTimeLayout, p.value, loc)
+	}
+}
@@ -0,0 +1,123 @@
+package ical
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"dev11/pkg/models"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)
+	moved := start.AddDate(0, 0, 7)
+	events := []models.Event{
+		{
+			Id:          "1",
+			Name:        "Планёрка; weekly, with team",
+			Description: strings.Repeat("длинное описание ", 10) + "\nsecond line",
+			Date:        start,
+			RRule:       "FREQ=WEEKLY;BYDAY=MO",
+			ExDates:     []time.Time{start.AddDate(0, 0, 14)},
+			Tags:        []string{"work", "team, weekly"},
+		},
+		{Id: "2", Name: "moved", Date: moved.Add(time.Hour), SeriesId: "1", RecurrenceId: &moved},
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, "test", events); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line is longer than %d octets: %q", maxLineOctets, line)
+		}
+	}
+
+	decoded, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != len(events) {
+		t.Fatalf("decoded %d events, want %d", len(decoded), len(events))
+	}
+	for _, d := range decoded {
+		if d.Err != nil {
+			t.Fatal(d.Err)
+		}
+	}
+
+	series, override := decoded[0].Event, decoded[1].Event
+	if series.Name != events[0].Name || series.Description != events[0].Description {
+		t.Errorf("text was not preserved: %q, %q", series.Name, series.Description)
+	}
+	if !slices.Equal(series.Tags, events[0].Tags) {
+		t.Errorf("categories = %q, want %q", series.Tags, events[0].Tags)
+	}
+	if !series.Date.Equal(start) || series.RRule != events[0].RRule ||
+		len(series.ExDates) != 1 || !series.ExDates[0].Equal(events[0].ExDates[0]) {
+		t.Errorf("recurrence was not preserved: %+v", series)
+	}
+	if override.UID != series.UID || override.RecurrenceId == nil || !override.RecurrenceId.Equal(moved) {
+		t.Errorf("override does not refer to the series: %+v", override)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skip(err)
+	}
+	tests := []struct {
+		name     string
+		input    string
+		wantDate []time.Time
+		wantErrs []bool
+		wantErr  bool
+	}{
+		{
+			name: "date and tzid values",
+			input: "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:a\nDTSTART;VALUE=DATE:20240102\nSUMMARY:a\n" +
+				"BEGIN:VALARM\nTRIGGER:-PT15M\nEND:VALARM\nEND:VEVENT\n" +
+				"BEGIN:VEVENT\nUID:b\nDTSTART;TZID=Europe/Moscow:20240102T100000\nSUMMARY:b\nEND:VEVENT\nEND:VCALENDAR\n",
+			wantDate: []time.Time{
+				time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 1, 2, 10, 0, 0, 0, moscow),
+			},
+			wantErrs: []bool{false, false},
+		},
+		{
+			name: "broken event",
+			input: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:a\r\nSUMMARY:no start\r\nEND:VEVENT\r\n" +
+				"BEGIN:VEVENT\r\nUID:b\r\nDTSTART:2024-01-02\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+			wantDate: []time.Time{{}, {}},
+			wantErrs: []bool{true, true},
+		},
+		{
+			name:    "not a calendar",
+			input:   "BEGIN:VEVENT\nEND:VEVENT\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.wantDate) {
+				t.Fatalf("Decode() returned %d events, want %d", len(got), len(tt.wantDate))
+			}
+			for i, e := range got {
+				if (e.Err != nil) != tt.wantErrs[i] {
+					t.Errorf("event %d: error = %v, wantErr %v", i, e.Err, tt.wantErrs[i])
+				}
+				if !e.Event.Date.Equal(tt.wantDate[i]) {
+					t.Errorf("event %d: date = %v, want %v", i, e.Event.Date, tt.wantDate[i])
+				}
+			}
+		})
+	}
+}
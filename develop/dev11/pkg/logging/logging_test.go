@@ -0,0 +1,29 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestHandler_RequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(slog.NewJSONHandler(&buf, nil))).With("component", "test")
+
+	logger.InfoContext(WithRequestID(context.Background(), "abc"), "with id")
+	logger.InfoContext(context.Background(), "without id")
+
+	dec := json.NewDecoder(&buf)
+	for _, want := range []string{"abc", ""} {
+		var rec map[string]any
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatal(err)
+		}
+		got, _ := rec["request_id"].(string)
+		if got != want || rec["component"] != "test" {
+			t.Errorf("record %v, want request_id %q", rec, want)
+		}
+	}
+}
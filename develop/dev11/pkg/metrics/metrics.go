@@ -0,0 +1,347 @@
+// Package metrics collects counters, gauges and histograms and exposes them
+// in the Prometheus text format.
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets are upper bounds of histogram buckets suitable for latencies
+// of HTTP requests in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// Registry is a set of metrics. It is an http.Handler which serves their
+// current values.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteTo writes all metrics in the Prometheus text format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = r.WriteTo(w)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// desc is the name, the help and the label names of a metric.
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+func (d desc) writeHeader(w *bufio.Writer) {
+	w.WriteString("# HELP " + d.name + " " + strings.ReplaceAll(d.help, "\n", `\n`) + "\n")
+	w.WriteString("# TYPE " + d.name + " " + d.typ + "\n")
+}
+
+// series is a labeled series of a metric.
+type series[T any] struct {
+	labels []string
+	value  T
+}
+
+// vec is a set of series of a metric with the same label names.
+type vec[T any] struct {
+	desc
+	newValue func() T
+
+	mu     sync.RWMutex
+	series map[string]*series[T]
+}
+
+func newVec[T any](d desc, newValue func() T) *vec[T] {
+	return &vec[T]{desc: d, newValue: newValue, series: make(map[string]*series[T])}
+}
+
+// with returns the value of the series with the label values, which must
+// match the label names of the metric.
+func (v *vec[T]) with(values []string) T {
+	if len(values) != len(v.labels) {
+		panic("metrics: " + v.name + " expects " + strconv.Itoa(len(v.labels)) + " label values")
+	}
+	key := strings.Join(values, "\xff")
+	v.mu.RLock()
+	s, ok := v.series[key]
+	v.mu.RUnlock()
+	if ok {
+		return s.value
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, ok = v.series[key]; !ok {
+		s = &series[T]{labels: append([]string(nil), values...), value: v.newValue()}
+		v.series[key] = s
+	}
+	return s.value
+}
+
+// sorted returns the series ordered by their label values.
+func (v *vec[T]) sorted() []*series[T] {
+	v.mu.RLock()
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	result := make([]*series[T], 0, len(keys))
+	for _, k := range keys {
+		result = append(result, v.series[k])
+	}
+	v.mu.RUnlock()
+	return result
+}
+
+// Counter is a value which only grows.
+type Counter struct {
+	value atomicFloat
+}
+
+func (c *Counter) Inc() {
+	c.value.add(1)
+}
+
+// Add adds v, which must not be negative.
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		panic("metrics: counter can not decrease")
+	}
+	c.value.add(v)
+}
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct {
+	*vec[*Counter]
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{newVec(desc{name, help, "counter", labels}, func() *Counter { return &Counter{} })}
+	r.register(c)
+	return c
+}
+
+func (c *CounterVec) WithLabelValues(values ...string) *Counter {
+	return c.with(values)
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.writeHeader(w)
+	for _, s := range c.sorted() {
+		writeSample(w, c.name, c.labels, s.labels, "", "", s.value.value.load())
+	}
+}
+
+// Gauge is a value which can go up and down.
+type Gauge struct {
+	desc
+	value atomicFloat
+}
+
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	g := &Gauge{desc: desc{name: name, help: help, typ: "gauge"}}
+	r.register(g)
+	return g
+}
+
+func (g *Gauge) Set(v float64) {
+	g.value.store(v)
+}
+
+func (g *Gauge) Inc() {
+	g.value.add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.value.add(-1)
+}
+
+func (g *Gauge) write(w *bufio.Writer) {
+	g.writeHeader(w)
+	writeSample(w, g.name, nil, nil, "", "", g.value.load())
+}
+
+// GaugeFunc is a gauge whose value is computed when it is collected.
+type GaugeFunc struct {
+	desc
+	value func() float64
+}
+
+func (r *Registry) NewGaugeFunc(name, help string, value func() float64) *GaugeFunc {
+	g := &GaugeFunc{desc: desc{name: name, help: help, typ: "gauge"}, value: value}
+	r.register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	g.writeHeader(w)
+	writeSample(w, g.name, nil, nil, "", "", g.value())
+}
+
+// Histogram counts observed values in buckets.
+type Histogram struct {
+	buckets []float64
+	counts  []atomic.Uint64
+	sum     atomicFloat
+	count   atomic.Uint64
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{buckets: buckets, counts: make([]atomic.Uint64, len(buckets))}
+}
+
+func (h *Histogram) Observe(v float64) {
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		h.counts[i].Add(1)
+	}
+	h.sum.add(v)
+	h.count.Add(1)
+}
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct {
+	*vec[*Histogram]
+	buckets []float64
+}
+
+// NewHistogramVec registers a histogram with buckets, which are upper bounds
+// sorted in increasing order.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &HistogramVec{
+		vec:     newVec(desc{name, help, "histogram", labels}, func() *Histogram { return newHistogram(buckets) }),
+		buckets: buckets,
+	}
+	r.register(h)
+	return h
+}
+
+func (h *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return h.with(values)
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.writeHeader(w)
+	for _, s := range h.sorted() {
+		var cumulative uint64
+		for i, le := range h.buckets {
+			cumulative += s.value.counts[i].Load()
+			writeSample(w, h.name+"_bucket", h.labels, s.labels, "le", formatFloat(le), float64(cumulative))
+		}
+		count := s.value.count.Load()
+		writeSample(w, h.name+"_bucket", h.labels, s.labels, "le", "+Inf", float64(count))
+		writeSample(w, h.name+"_sum", h.labels, s.labels, "", "", s.value.sum.load())
+		writeSample(w, h.name+"_count", h.labels, s.labels, "", "", float64(count))
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeSample writes a line of a sample with the labels and, if extraName is
+// set, an extra label like le of histogram buckets.
+func writeSample(w *bufio.Writer, name string, names, values []string, extraName, extraValue string, v float64) {
+	w.WriteString(name)
+	if len(names) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, n := range names {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(n + `="` + labelEscaper.Replace(values[i]) + `"`)
+		}
+		if extraName != "" {
+			if len(names) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extraName + `="` + extraValue + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// atomicFloat is a float64 which can be changed concurrently.
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+func (f *atomicFloat) store(v float64) {
+	f.bits.Store(math.Float64bits(v))
+}
+
+func (f *atomicFloat) add(v float64) {
+	for {
+		old := f.bits.Load()
+		if f.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("requests_total", "Number of requests.", "route", "status")
+	requests.WithLabelValues("/b", "200").Inc()
+	requests.WithLabelValues("/a", "404").Add(2)
+	requests.WithLabelValues("/a", "200").Inc()
+	requests.WithLabelValues("/b", "200").Inc()
+
+	inFlight := r.NewGauge("in_flight", "Requests being served.")
+	inFlight.Inc()
+	inFlight.Inc()
+	inFlight.Dec()
+
+	r.NewGaugeFunc("users", "Number of users.", func() float64 { return 3 })
+
+	latency := r.NewHistogramVec("latency_seconds", "Latency.", []float64{1, 0.1}, "route")
+	for _, v := range []float64{0.05, 0.1, 0.5, 2} {
+		latency.WithLabelValues(`/"q"`).Observe(v)
+	}
+
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP requests_total Number of requests.
+# TYPE requests_total counter
+requests_total{route="/a",status="200"} 1
+requests_total{route="/a",status="404"} 2
+requests_total{route="/b",status="200"} 2
+# HELP in_flight Requests being served.
+# TYPE in_flight gauge
+in_flight 1
+# HELP users Number of users.
+# TYPE users gauge
+users 3
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/\"q\"",le="0.1"} 2
+latency_seconds_bucket{route="/\"q\"",le="1"} 3
+latency_seconds_bucket{route="/\"q\"",le="+Inf"} 4
+latency_seconds_sum{route="/\"q\""} 2.65
+latency_seconds_count{route="/\"q\""} 4
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCounterVec_WrongLabels(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("no panic on a wrong number of label values")
+		}
+	}()
+	NewRegistry().NewCounterVec("c", "c", "a", "b").WithLabelValues("x")
+}
@@ -0,0 +1,36 @@
+package models
+
+// AttendeeStatus is the response of an attendee to an invitation.
+type AttendeeStatus string
+
+const (
+	StatusNeedsAction AttendeeStatus = "needs-action"
+	StatusAccepted    AttendeeStatus = "accepted"
+	StatusDeclined    AttendeeStatus = "declined"
+	StatusTentative   AttendeeStatus = "tentative"
+)
+
+// IsValid reports whether s is one of the known statuses.
+func (s AttendeeStatus) IsValid() bool {
+	switch s {
+	case StatusNeedsAction, StatusAccepted, StatusDeclined, StatusTentative:
+		return true
+	}
+	return false
+}
+
+// Attendee is a user invited to an event of another user.
+type Attendee struct {
+	UserId string         `json:"userId"`
+	Status AttendeeStatus `json:"status"`
+}
+
+// Attendee returns the attendee of e with the user id or nil.
+func (e *Event) Attendee(userId string) *Attendee {
+	for i := range e.Attendees {
+		if e.Attendees[i].UserId == userId {
+			return &e.Attendees[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// AuditEntry is a change in the history of an event.
+type AuditEntry struct {
+	Type ChangeType `json:"type"`
+	// Actor is the id of the user who made the change, empty for changes
+	// made with the admin key.
+	Actor string    `json:"actor,omitempty"`
+	Time  time.Time `json:"time"`
+	// Before and After are the event before and after the change; Before is
+	// nil for created and restored events and After for deleted ones.
+	Before *Event `json:"before,omitempty"`
+	After  *Event `json:"after,omitempty"`
+}
+
+// Clone returns a deep copy of the entry.
+func (a AuditEntry) Clone() AuditEntry {
+	if a.Before != nil {
+		before := a.Before.Clone()
+		a.Before = &before
+	}
+	if a.After != nil {
+		after := a.After.Clone()
+		a.After = &after
+	}
+	return a
+}
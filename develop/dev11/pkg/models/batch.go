@@ -0,0 +1,27 @@
+package models
+
+type OperationType string
+
+const (
+	OperationCreate OperationType = "create"
+	OperationUpdate OperationType = "update"
+	OperationDelete OperationType = "delete"
+)
+
+// EventOperation is a change of an event within a batch. Create and update
+// take Event, which for update carries the id and the expected version as
+// in an update of a single event. Delete takes EventId and Version.
+type EventOperation struct {
+	Type    OperationType
+	Event   Event
+	EventId string
+	Version int64
+}
+
+// OperationResult is the outcome of an operation of a batch: the created or
+// updated event, nil for a deletion, or the error the operation failed
+// with.
+type OperationResult struct {
+	Event *Event
+	Err   error
+}
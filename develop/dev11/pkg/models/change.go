@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+type ChangeType string
+
+const (
+	ChangeCreated ChangeType = "created"
+	ChangeUpdated ChangeType = "updated"
+	ChangeDeleted ChangeType = "deleted"
+	// ChangeRestored is a change returning a deleted event from the trash.
+	ChangeRestored ChangeType = "restored"
+)
+
+// Change is a change of an event of the user. Event is the event after the
+// change and is nil for deleted events.
+type Change struct {
+	// Id orders changes of all users; later changes have greater ids.
+	Id      uint64     `json:"id"`
+	Type    ChangeType `json:"type"`
+	UserId  string     `json:"userId"`
+	EventId string     `json:"eventId"`
+	Event   *Event     `json:"event,omitempty"`
+	Time    time.Time  `json:"time"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of domain errors. Errors returned by the repository and the service
+// match one of them with errors.Is.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrValidation   = errors.New("validation failed")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	// ErrPreconditionFailed means the stored version of an entity differs
+	// from the one the caller expects.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrRateLimited means the client sends requests too fast.
+	ErrRateLimited = errors.New("too many requests")
+	// ErrQuotaExceeded means the user has stored as much as allowed.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+)
+
+// Error is a domain error of a kind.
+type Error struct {
+	Kind error
+	Err  error
+}
+
+// NewError returns err marked as an error of the kind.
+func NewError(kind, err error) error {
+	return &Error{Kind: kind, Err: err}
+}
+
+// Errorf is NewError with the error formatted by fmt.Errorf.
+func Errorf(kind error, format string, args ...any) error {
+	return &Error{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
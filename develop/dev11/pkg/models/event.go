@@ -1,10 +1,193 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+var locations sync.Map
+
+// LoadLocation is time.LoadLocation which caches loaded time zones.
+func LoadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
 
 type Event struct {
 	Id          string    `json:"id"`
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
 	Date        time.Time `json:"date"`
+	// End is the optional end of the event.
+	End *time.Time `json:"end,omitempty"`
+	// TimeZone is the IANA time zone the event is scheduled in. Recurrence
+	// rules are expanded in it.
+	TimeZone string `json:"timezone,omitempty"`
+	// UID is the iCalendar UID of the event, it is kept for imported events.
+	UID string `json:"uid,omitempty"`
+	// RRule is an RFC 5545 recurrence rule; Date is the start of the series.
+	RRule string `json:"rrule,omitempty"`
+	// ExDates are the starts of occurrences excluded from the series.
+	ExDates []time.Time `json:"exdates,omitempty"`
+	// Reminders are offsets before the start of the event (or of every
+	// occurrence of a series) at which the user is notified.
+	Reminders []Duration `json:"reminders,omitempty"`
+	// Tags are categories of the event set by the user.
+	Tags []string `json:"tags,omitempty"`
+	// SeriesId and RecurrenceId are set for an occurrence of a series: the
+	// id of the series and the original start of the occurrence.
+	SeriesId     string     `json:"seriesId,omitempty"`
+	RecurrenceId *time.Time `json:"recurrenceId,omitempty"`
+	// Organizer is the id of the user who owns the event. Attendees are the
+	// other users invited to it with their responses.
+	Organizer string     `json:"organizer,omitempty"`
+	Attendees []Attendee `json:"attendees,omitempty"`
+	// Version is incremented by the repository on every change of the
+	// event.
+	Version int64 `json:"version"`
+}
+
+// Clone returns a deep copy of the event which shares no memory with it.
+func (e Event) Clone() Event {
+	if e.End != nil {
+		end := *e.End
+		e.End = &end
+	}
+	if e.RecurrenceId != nil {
+		recurrenceId := *e.RecurrenceId
+		e.RecurrenceId = &recurrenceId
+	}
+	e.ExDates = slices.Clone(e.ExDates)
+	e.Reminders = slices.Clone(e.Reminders)
+	e.Tags = slices.Clone(e.Tags)
+	e.Attendees = slices.Clone(e.Attendees)
+	return e
+}
+
+// IsRecurring reports whether e is a series of events.
+func (e Event) IsRecurring() bool {
+	return e.RRule != ""
+}
+
+// Location returns the time zone of e.
+func (e Event) Location() *time.Location {
+	if e.TimeZone != "" {
+		if loc, err := LoadLocation(e.TimeZone); err == nil {
+			return loc
+		}
+	}
+	return e.Date.Location()
+}
+
+// Rule parses the recurrence rule of e.
+func (e Event) Rule() (*RecurrenceRule, error) {
+	return ParseRecurrenceRule(e.RRule, e.Location())
+}
+
+// Occurrences returns events of e which start in [from, to). A series is
+// expanded into separate occurrences. An error matching ErrTooManyPeriods
+// is returned if the range is too far from the start of the series.
+func (e Event) Occurrences(from, to time.Time) ([]Event, error) {
+	if !e.IsRecurring() {
+		if !e.Date.Before(from) && e.Date.Before(to) {
+			return []Event{e}, nil
+		}
+		return nil, nil
+	}
+	rule, err := e.Rule()
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	err = rule.Expand(e.Date.In(e.Location()), func(t time.Time) bool {
+		if !t.Before(to) {
+			return false
+		}
+		if !t.Before(from) && !e.IsExcluded(t) {
+			events = append(events, e.occurrence(t))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("event with id = %s: %w", e.Id, err)
+	}
+	return events, nil
+}
+
+// HasOccurrence reports whether the series e has a not excluded occurrence
+// starting at t.
+func (e Event) HasOccurrence(t time.Time) (bool, error) {
+	if !e.IsRecurring() {
+		return false, nil
+	}
+	events, err := e.Occurrences(t, t.Add(time.Nanosecond))
+	return len(events) > 0, err
+}
+
+// IsExcluded reports whether the occurrence starting at t is excluded.
+func (e Event) IsExcluded(t time.Time) bool {
+	for _, d := range e.ExDates {
+		if d.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// occurrence returns the occurrence of the series e starting at t.
+func (e Event) occurrence(t time.Time) Event {
+	o := e
+	o.Date = t
+	if e.End != nil {
+		end := t.Add(e.End.Sub(e.Date))
+		o.End = &end
+	}
+	o.RRule = ""
+	o.ExDates = nil
+	o.SeriesId = e.Id
+	o.RecurrenceId = &t
+	return o
+}
+
+// Duration returns the duration of e, zero if e has no end.
+func (e Event) Duration() time.Duration {
+	if e.End == nil {
+		return 0
+	}
+	return e.End.Sub(e.Date)
+}
+
+// Overlaps reports whether e and other take common time. Events without end
+// do not take any time.
+func (e Event) Overlaps(other Event) bool {
+	if e.End == nil || other.End == nil {
+		return false
+	}
+	return e.Date.Before(*other.End) && other.Date.Before(*e.End)
+}
+
+// Before reports whether e goes before other in a calendar: events are
+// ordered by date, and events with the same date by id.
+func (e Event) Before(other Event) bool {
+	if !e.Date.Equal(other.Date) {
+		return e.Date.Before(other.Date)
+	}
+	return e.Id < other.Id
+}
+
+// SortEvents sorts events in calendar order.
+func SortEvents(events []Event) {
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Before(events[j])
+	})
 }
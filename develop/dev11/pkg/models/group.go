@@ -0,0 +1,22 @@
+package models
+
+import "slices"
+
+// Group is a named set of users. A user can share its calendar with groups
+// whose members can then read it.
+type Group struct {
+	Id      string   `json:"id"`
+	Name    string   `json:"name,omitempty"`
+	Members []string `json:"members"`
+}
+
+// HasMember reports whether the user is a member of g.
+func (g Group) HasMember(userId string) bool {
+	return slices.Contains(g.Members, userId)
+}
+
+// Clone returns a deep copy of the group.
+func (g Group) Clone() Group {
+	g.Members = slices.Clone(g.Members)
+	return g
+}
@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// Interval is a time span [Start, End).
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
@@ -0,0 +1,262 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRecurrencePeriods bounds expansion of rules which never produce an
+// occurrence in the requested range.
+const maxRecurrencePeriods = 100000
+
+// ErrTooManyPeriods is returned when the requested range lies more than
+// maxRecurrencePeriods periods of a rule after its start, so occurrences
+// would be missed.
+var ErrTooManyPeriods = NewError(ErrValidation,
+	fmt.Errorf("recurrence can not be expanded over more than %d periods", maxRecurrencePeriods))
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// ByDay is a BYDAY rule part. N is the ordinal of the weekday within a month
+// (1 is the first one, -1 is the last one), zero means every such weekday.
+type ByDay struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// RecurrenceRule is a subset of the RFC 5545 RRULE: FREQ, INTERVAL, BYDAY,
+// COUNT and UNTIL are supported.
+type RecurrenceRule struct {
+	Freq     Frequency
+	Interval int
+	ByDay    []ByDay
+	Count    int
+	Until    time.Time
+}
+
+// ParseRecurrenceRule parses a rule like "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10".
+// A floating UNTIL is interpreted in loc.
+func ParseRecurrenceRule(s string, loc *time.Location) (*RecurrenceRule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	rule := &RecurrenceRule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("rrule: malformed part %q", part)
+		}
+		var err error
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			rule.Freq = Frequency(strings.ToUpper(value))
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(value)
+			if err == nil && rule.Interval < 1 {
+				err = errors.New("must be positive")
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(value)
+			if err == nil && rule.Count < 1 {
+				err = errors.New("must be positive")
+			}
+		case "UNTIL":
+			rule.Until, err = parseICalTime(value, loc)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(value)
+		case "WKST":
+			if strings.ToUpper(value) != "MO" {
+				err = errors.New("only MO is supported")
+			}
+		default:
+			err = errors.New("not supported")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rrule: %s: %w", name, err)
+		}
+	}
+
+	switch rule.Freq {
+	case Daily, Weekly, Monthly, Yearly:
+	case "":
+		return nil, errors.New("rrule: FREQ is required")
+	default:
+		return nil, fmt.Errorf("rrule: FREQ %q is not supported", rule.Freq)
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, errors.New("rrule: COUNT and UNTIL are mutually exclusive")
+	}
+	for _, d := range rule.ByDay {
+		if d.N != 0 && rule.Freq != Monthly {
+			return nil, errors.New("rrule: ordinal BYDAY is supported only for MONTHLY")
+		}
+	}
+	if rule.Freq == Yearly && len(rule.ByDay) > 0 {
+		return nil, errors.New("rrule: BYDAY is not supported for YEARLY")
+	}
+	return rule, nil
+}
+
+func parseByDay(value string) ([]ByDay, error) {
+	var days []ByDay
+	for _, s := range strings.Split(strings.ToUpper(value), ",") {
+		if len(s) < 2 {
+			return nil, fmt.Errorf("malformed weekday %q", s)
+		}
+		wd, ok := weekdays[s[len(s)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("malformed weekday %q", s)
+		}
+		d := ByDay{Weekday: wd}
+		if n := s[:len(s)-2]; n != "" {
+			var err error
+			if d.N, err = strconv.Atoi(n); err != nil || d.N == 0 || d.N < -5 || d.N > 5 {
+				return nil, fmt.Errorf("malformed weekday %q", s)
+			}
+		}
+		days = append(days, d)
+	}
+	return days, nil
+}
+
+// parseICalTime parses RFC 5545 DATE and DATE-TIME values.
+func parseICalTime(value string, loc *time.Location) (time.Time, error) {
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse("20060102T150405Z", value)
+	case strings.Contains(value, "T"):
+		return time.ParseInLocation("20060102T150405", value, loc)
+	default:
+		return time.ParseInLocation("20060102", value, loc)
+	}
+}
+
+// Expand calls yield for every occurrence of the rule starting at dtstart in
+// chronological order until yield returns false or the rule is exhausted.
+// dtstart itself is always the first occurrence. If neither happens within
+// maxRecurrencePeriods periods, ErrTooManyPeriods is returned.
+func (r *RecurrenceRule) Expand(dtstart time.Time, yield func(time.Time) bool) error {
+	count := 0
+	emit := func(t time.Time) bool {
+		if !r.Until.IsZero() && t.After(r.Until) {
+			return false
+		}
+		count++
+		if !yield(t) {
+			return false
+		}
+		return r.Count == 0 || count < r.Count
+	}
+
+	if !emit(dtstart) {
+		return nil
+	}
+	for k := 0; k < maxRecurrencePeriods; k++ {
+		for _, t := range r.period(dtstart, k) {
+			if !t.After(dtstart) {
+				continue
+			}
+			if !emit(t) {
+				return nil
+			}
+		}
+	}
+	return ErrTooManyPeriods
+}
+
+// period returns sorted candidate occurrences of the k-th period.
+func (r *RecurrenceRule) period(dtstart time.Time, k int) []time.Time {
+	y, m, d := dtstart.Date()
+	h, mi, s := dtstart.Clock()
+	ns, loc := dtstart.Nanosecond(), dtstart.Location()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, h, mi, s, ns, loc)
+	}
+	n := k * r.Interval
+
+	var ts []time.Time
+	switch r.Freq {
+	case Daily:
+		t := at(y, m, d+n)
+		if r.matchesWeekday(t.Weekday()) {
+			ts = append(ts, t)
+		}
+	case Weekly:
+		if len(r.ByDay) == 0 {
+			return []time.Time{at(y, m, d+7*n)}
+		}
+		monday := d - (int(dtstart.Weekday())+6)%7 + 7*n
+		for _, bd := range r.ByDay {
+			ts = append(ts, at(y, m, monday+(int(bd.Weekday)+6)%7))
+		}
+	case Monthly:
+		first := time.Date(y, m+time.Month(n), 1, 0, 0, 0, 0, loc)
+		py, pm, _ := first.Date()
+		days := daysIn(py, pm)
+		if len(r.ByDay) == 0 {
+			if d <= days {
+				ts = append(ts, at(py, pm, d))
+			}
+			break
+		}
+		for _, bd := range r.ByDay {
+			offset := (int(bd.Weekday) - int(first.Weekday()) + 7) % 7
+			switch {
+			case bd.N > 0:
+				if day := 1 + offset + 7*(bd.N-1); day <= days {
+					ts = append(ts, at(py, pm, day))
+				}
+			case bd.N < 0:
+				last := 1 + offset + 7*((days-1-offset)/7)
+				if day := last + 7*(bd.N+1); day >= 1 {
+					ts = append(ts, at(py, pm, day))
+				}
+			default:
+				for day := 1 + offset; day <= days; day += 7 {
+					ts = append(ts, at(py, pm, day))
+				}
+			}
+		}
+	case Yearly:
+		if d <= daysIn(y+n, m) {
+			ts = append(ts, at(y+n, m, d))
+		}
+	}
+	sort.Slice(ts, func(i, j int) bool { return ts[i].Before(ts[j]) })
+	return ts
+}
+
+func (r *RecurrenceRule) matchesWeekday(wd time.Weekday) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, bd := range r.ByDay {
+		if bd.Weekday == wd {
+			return true
+		}
+	}
+	return false
+}
+
+func daysIn(y int, m time.Month) int {
+	return time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
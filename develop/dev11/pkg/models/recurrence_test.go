@@ -0,0 +1,109 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEvent_Occurrences(t *testing.T) {
+	day := func(m time.Month, d int) time.Time {
+		return time.Date(2024, m, d, 10, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name    string
+		event   Event
+		from    time.Time
+		to      time.Time
+		want    []time.Time
+		wantErr bool
+	}{
+		{
+			name:  "single event",
+			event: Event{Date: day(1, 1)},
+			from:  day(1, 1), to: day(1, 2),
+			want: []time.Time{day(1, 1)},
+		},
+		{
+			name:  "daily with count",
+			event: Event{Date: day(1, 30), RRule: "FREQ=DAILY;COUNT=3"},
+			from:  day(1, 1), to: day(3, 1),
+			want: []time.Time{day(1, 30), day(1, 31), day(2, 1)},
+		},
+		{
+			name:  "weekly by day with exdate",
+			event: Event{Date: day(1, 1), RRule: "FREQ=WEEKLY;BYDAY=MO,WE", ExDates: []time.Time{day(1, 3)}},
+			from:  day(1, 1), to: day(1, 15),
+			want: []time.Time{day(1, 1), day(1, 8), day(1, 10)},
+		},
+		{
+			name:  "biweekly until",
+			event: Event{Date: day(1, 5), RRule: "FREQ=WEEKLY;INTERVAL=2;UNTIL=20240202T100000Z"},
+			from:  day(1, 1), to: day(12, 31),
+			want: []time.Time{day(1, 5), day(1, 19), day(2, 2)},
+		},
+		{
+			name:  "range in the middle of a series",
+			event: Event{Date: day(1, 1), RRule: "FREQ=DAILY;INTERVAL=10"},
+			from:  day(2, 1), to: day(2, 20),
+			want: []time.Time{day(2, 10)},
+		},
+		{
+			name:  "monthly skips short months",
+			event: Event{Date: day(1, 31), RRule: "FREQ=MONTHLY;COUNT=3"},
+			from:  day(1, 1), to: day(12, 31),
+			want: []time.Time{day(1, 31), day(3, 31), day(5, 31)},
+		},
+		{
+			name:  "last friday of a month",
+			event: Event{Date: day(1, 26), RRule: "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3"},
+			from:  day(1, 1), to: day(12, 31),
+			want: []time.Time{day(1, 26), day(2, 23), day(3, 29)},
+		},
+		{
+			name:  "first monday of a month",
+			event: Event{Date: day(1, 1), RRule: "FREQ=MONTHLY;BYDAY=1MO;COUNT=2"},
+			from:  day(1, 1), to: day(12, 31),
+			want: []time.Time{day(1, 1), day(2, 5)},
+		},
+		{
+			name:  "yearly on leap day",
+			event: Event{Date: day(2, 29), RRule: "FREQ=YEARLY;COUNT=2"},
+			from:  day(1, 1), to: day(1, 1).AddDate(10, 0, 0),
+			want: []time.Time{day(2, 29), day(2, 29).AddDate(4, 0, 0)},
+		},
+		{
+			name:  "unsupported frequency",
+			event: Event{Date: day(1, 1), RRule: "FREQ=HOURLY"},
+			from:  day(1, 1), to: day(1, 2),
+			wantErr: true,
+		},
+		{
+			name:  "range beyond the expansion limit",
+			event: Event{Date: day(1, 1), RRule: "FREQ=DAILY"},
+			from:  day(1, 1).AddDate(300, 0, 0), to: day(1, 2).AddDate(300, 0, 0),
+			wantErr: true,
+		},
+		{
+			name:  "count and until",
+			event: Event{Date: day(1, 1), RRule: "FREQ=DAILY;COUNT=2;UNTIL=20240110"},
+			from:  day(1, 1), to: day(1, 2),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := tt.event.Occurrences(tt.from, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Occurrences() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []time.Time
+			for _, e := range events {
+				got = append(got, e.Date)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Occurrences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
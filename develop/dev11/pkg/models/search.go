@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// SearchQuery selects events of a user. Text matches events having words
+// starting with every word of it in the name or the description; Tags match
+// events having all of them. From and To, if set, limit the result to
+// occurrences starting in [From, To).
+type SearchQuery struct {
+	Text string
+	Tags []string
+	From time.Time
+	To   time.Time
+}
+
+// HasRange reports whether the query is limited to a time range.
+func (q SearchQuery) HasRange() bool {
+	return !q.From.IsZero() || !q.To.IsZero()
+}
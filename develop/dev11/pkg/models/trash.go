@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// TrashedEvent is a deleted event kept in the trash of its user until it is
+// restored or purged.
+type TrashedEvent struct {
+	Event     Event     `json:"event"`
+	DeletedAt time.Time `json:"deletedAt"`
+	// DeletedBy is the id of the user who deleted the event, empty if it
+	// was deleted with the admin key.
+	DeletedBy string `json:"deletedBy,omitempty"`
+	// PurgeAt is when the event is removed from the trash for good.
+	PurgeAt time.Time `json:"purgeAt"`
+}
+
+// Clone returns a deep copy of the trashed event.
+func (t TrashedEvent) Clone() TrashedEvent {
+	t.Event = t.Event.Clone()
+	return t
+}
@@ -1,15 +1,59 @@
 package models
 
+import "slices"
+
 const initialEventsMapSize = 10
 
+// Role defines what a user is allowed to access.
+type Role string
+
+const (
+	// RoleUser can access only its own events.
+	RoleUser Role = "user"
+	// RoleAdmin can access events of every user and manage users.
+	RoleAdmin Role = "admin"
+)
+
 type User struct {
-	Id     string           `json:"id"`
-	Events map[string]Event `json:"events"`
+	Id   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	Role Role   `json:"role,omitempty"`
+	// APIKeyHash is the hex encoded SHA-256 of the API key of the user. The
+	// key itself is never stored.
+	APIKeyHash string `json:"apiKeyHash,omitempty"`
+	// FeedTokenHash is the hex encoded SHA-256 of the token which grants
+	// read-only access to the exported calendar of the user, none if empty.
+	FeedTokenHash string `json:"feedTokenHash,omitempty"`
+	// TimeZone is the IANA time zone used by default for events and queries
+	// of the user, UTC if empty.
+	TimeZone string `json:"timezone,omitempty"`
+	// SharedWith are the ids of the groups whose members can read the
+	// calendar of the user.
+	SharedWith []string         `json:"sharedWith,omitempty"`
+	Events     map[string]Event `json:"events"`
 }
 
 func NewUser(id string) User {
 	return User{
 		Id:     id,
+		Role:   RoleUser,
 		Events: make(map[string]Event, initialEventsMapSize),
 	}
 }
+
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// Clone returns a deep copy of the user and its events.
+func (u User) Clone() User {
+	u.SharedWith = slices.Clone(u.SharedWith)
+	if u.Events != nil {
+		events := make(map[string]Event, len(u.Events))
+		for id, e := range u.Events {
+			events[id] = e.Clone()
+		}
+		u.Events = events
+	}
+	return u
+}
@@ -0,0 +1,119 @@
+// Package ratelimit limits the rate of requests per key with token buckets.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often buckets which have been refilled completely,
+// and so are equal to new ones, are dropped.
+const sweepInterval = time.Minute
+
+// Decision is the result of taking a token from a bucket.
+type Decision struct {
+	Allowed bool
+	// Limit is the size of the bucket.
+	Limit int
+	// Remaining is the number of tokens left in the bucket.
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next token if the request was not
+	// allowed.
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter allows on average Rate requests per second per key with bursts of
+// up to Burst requests.
+type Limiter struct {
+	rate  float64
+	burst int
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// New returns a limiter of rate requests per second with bursts of burst
+// requests. A burst less than 1 is set to 1.
+func New(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{rate: rate, burst: burst, now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+// Allow takes a token from the bucket of the key.
+func (l *Limiter) Allow(key string) Decision {
+	return l.decide(key, true)
+}
+
+// Peek returns the decision Allow would make for the key without taking a
+// token.
+func (l *Limiter) Peek(key string) Decision {
+	return l.decide(key, false)
+}
+
+// decide checks for a token in the bucket of the key and takes it if take
+// is set.
+func (l *Limiter) decide(key string, take bool) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		if take {
+			l.buckets[key] = b
+		}
+	}
+	b.tokens = l.refill(b, now)
+	b.last = now
+
+	d := Decision{Limit: l.burst}
+	if b.tokens >= 1 {
+		if take {
+			b.tokens--
+		}
+		d.Allowed = true
+	} else {
+		d.RetryAfter = l.duration(1 - b.tokens)
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = l.duration(float64(l.burst) - b.tokens)
+	return d
+}
+
+func (l *Limiter) refill(b *bucket, now time.Time) float64 {
+	return math.Min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*l.rate)
+}
+
+// duration returns the time it takes to refill the tokens.
+func (l *Limiter) duration(tokens float64) time.Duration {
+	if l.rate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= float64(l.burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	l := New(2, 3)
+	l.now = func() time.Time { return now }
+
+	tests := []struct {
+		name       string
+		key        string
+		advance    time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}{
+		{"burst 1", "a", 0, true, 2, 0},
+		{"burst 2", "a", 0, true, 1, 0},
+		{"burst 3", "a", 0, true, 0, 0},
+		{"empty bucket", "a", 0, false, 0, 500 * time.Millisecond},
+		{"other key", "b", 0, true, 2, 0},
+		{"refilled token", "a", 500 * time.Millisecond, true, 0, 0},
+		{"partly refilled", "a", 250 * time.Millisecond, false, 0, 250 * time.Millisecond},
+		{"full again", "a", time.Hour, true, 2, 0},
+	}
+	for _, tt := range tests {
+		now = now.Add(tt.advance)
+		d := l.Allow(tt.key)
+		if d.Allowed != tt.allowed || d.Remaining != tt.remaining || d.RetryAfter != tt.retryAfter || d.Limit != 3 {
+			t.Errorf("%s: got %+v, want allowed = %v, remaining = %d, retry after %s",
+				tt.name, d, tt.allowed, tt.remaining, tt.retryAfter)
+		}
+	}
+}
+
+func TestLimiter_Peek(t *testing.T) {
+	now := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	l := New(1, 1)
+	l.now = func() time.Time { return now }
+	if d := l.Peek("a"); !d.Allowed || d.Remaining != 1 {
+		t.Errorf("Peek() of a new key = %+v, want allowed with 1 remaining", d)
+	}
+	if _, ok := l.buckets["a"]; ok {
+		t.Error("Peek() stored a bucket")
+	}
+	l.Allow("a")
+	if d := l.Peek("a"); d.Allowed || d.RetryAfter != time.Second {
+		t.Errorf("Peek() of an empty bucket = %+v, want not allowed for a second", d)
+	}
+	now = now.Add(time.Second)
+	if d := l.Peek("a"); !d.Allowed || !l.Allow("a").Allowed {
+		t.Errorf("Peek() of a refilled bucket = %+v, want allowed", d)
+	}
+}
+
+func TestLimiter_Sweep(t *testing.T) {
+	now := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	l := New(1, 1)
+	l.now = func() time.Time { return now }
+	l.Allow("a")
+	now = now.Add(sweepInterval)
+	l.Allow("b")
+	if _, ok := l.buckets["a"]; ok {
+		t.Error("refilled bucket is kept")
+	}
+	if _, ok := l.buckets["b"]; !ok {
+		t.Error("used bucket is dropped")
+	}
+}
@@ -0,0 +1,103 @@
+package reminder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"dev11/pkg/models"
+)
+
+// Notification is a reminder about an upcoming event.
+type Notification struct {
+	UserId string        `json:"userId"`
+	Event  models.Event  `json:"event"`
+	Offset time.Duration `json:"offset"`
+	At     time.Time     `json:"at"`
+}
+
+func (n Notification) String() string {
+	return fmt.Sprintf("event %q of user %s starts at %s",
+		n.Event.Name, n.UserId, n.Event.Date.Format(time.RFC3339))
+}
+
+// Notifier delivers notifications to users.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// LogNotifier writes notifications to the log.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(_ context.Context, n Notification) error {
+	log.Printf("reminder: %s", n)
+	return nil
+}
+
+// WebhookNotifier posts notifications as JSON to URL.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (o *WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// SMTPNotifier sends notifications by mail through an SMTP server without
+// authentication, e.g. a local relay.
+type SMTPNotifier struct {
+	Addr string
+	From string
+	To   []string
+}
+
+func (o *SMTPNotifier) Notify(_ context.Context, n Notification) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", o.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(o.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", encodeHeader("Reminder: "+n.Event.Name))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(n.String() + "\r\n")
+	if n.Event.Description != "" {
+		msg.WriteString("\r\n" + n.Event.Description + "\r\n")
+	}
+	return smtp.SendMail(o.Addr, nil, o.From, o.To, []byte(msg.String()))
+}
+
+// encodeHeader returns s as the value of a mail header. Line breaks, which
+// would start new headers, are replaced with spaces and non-ASCII text is
+// encoded as RFC 2047 words.
+func encodeHeader(s string) string {
+	s = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(s)
+	return mime.QEncoding.Encode("utf-8", s)
+}
@@ -0,0 +1,23 @@
+package reminder
+
+import "testing"
+
+func TestEncodeHeader(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"ascii", "Reminder: standup", "Reminder: standup"},
+		{"injected header", "standup\r\nBcc: victim@example.com", "standup Bcc: victim@example.com"},
+		{"bare line feeds", "a\nb\rc", "a b c"},
+		{"non-ascii", "Reminder: планёрка", "=?utf-8?q?Reminder:_=D0=BF=D0=BB=D0=B0=D0=BD=D1=91=D1=80=D0=BA=D0=B0?="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encodeHeader(tt.value); got != tt.want {
+				t.Errorf("encodeHeader(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package reminder
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"dev11/pkg/repository"
+)
+
+const (
+	// rescanInterval is the longest time the scheduler sleeps without
+	// looking at the repository.
+	rescanInterval = time.Minute
+	notifyTimeout  = 10 * time.Second
+)
+
+// Scheduler fires reminders of events stored in the repository. It keeps no
+// state besides the time of the last check, so after a restart reminders are
+// rescheduled from the repository. Reminders which fell due before the
+// start, e.g. while the server was down, are fired late only within the
+// catch-up window set with SetCatchUp.
+type Scheduler struct {
+	repo     repository.User
+	notifier Notifier
+	wake     chan struct{}
+	now      func() time.Time
+	catchUp  time.Duration
+
+	wg sync.WaitGroup
+}
+
+func NewScheduler(repo repository.User, notifier Notifier) *Scheduler {
+	return &Scheduler{
+		repo:     repo,
+		notifier: notifier,
+		wake:     make(chan struct{}, 1),
+		now:      time.Now,
+	}
+}
+
+// SetCatchUp sets how long before the start of the scheduler missed
+// reminders are still fired; 0, the default, fires only the ones due after
+// the start. Since the scheduler does not know which reminders were fired
+// before a restart, the ones within the window are fired again. It must be
+// called before Start.
+func (o *Scheduler) SetCatchUp(d time.Duration) {
+	o.catchUp = d
+}
+
+// Wake makes the scheduler look at the repository again, it should be
+// called after events were changed.
+func (o *Scheduler) Wake() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start runs the scheduler in background until ctx is done.
+func (o *Scheduler) Start(ctx context.Context) {
+	o.wg.Add(1)
+	go func() {
+		defer o.wg.Done()
+		o.run(ctx)
+	}()
+}
+
+// Wait blocks until the scheduler started with Start stops.
+func (o *Scheduler) Wait() {
+	o.wg.Wait()
+}
+
+func (o *Scheduler) run(ctx context.Context) {
+	last := o.now().Add(-o.catchUp)
+	for {
+		now := o.now()
+		due, next, err := o.scan(last, now)
+		if err != nil {
+			log.Printf("reminder: failed to scan events: %s", err.Error())
+		}
+		for _, n := range due {
+			o.fire(ctx, n)
+		}
+		last = now
+
+		wait := rescanInterval
+		if !next.IsZero() && next.Sub(now) < wait {
+			wait = next.Sub(now)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-o.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+func (o *Scheduler) fire(ctx context.Context, n Notification) {
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	if err := o.notifier.Notify(ctx, n); err != nil {
+		log.Printf("reminder: failed to notify about %s: %s", n, err.Error())
+	}
+}
+
+// scan returns reminders due in (from, to] sorted by time and the time of
+// the nearest reminder after to within rescanInterval, zero if there is none.
+func (o *Scheduler) scan(from, to time.Time) ([]Notification, time.Time, error) {
+	users, err := o.repo.GetUsers()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var (
+		due  []Notification
+		next time.Time
+	)
+	horizon := to.Add(rescanInterval)
+	for _, user := range users {
+		for _, event := range user.Events {
+			if len(event.Reminders) == 0 {
+				continue
+			}
+			var maxOffset time.Duration
+			for _, r := range event.Reminders {
+				maxOffset = max(maxOffset, time.Duration(r))
+			}
+			occurrences, err := event.Occurrences(from, horizon.Add(maxOffset))
+			if err != nil {
+				continue
+			}
+			for _, occurrence := range occurrences {
+				for _, r := range event.Reminders {
+					at := occurrence.Date.Add(-time.Duration(r))
+					switch {
+					case at.After(from) && !at.After(to):
+						due = append(due, Notification{
+							UserId: user.Id,
+							Event:  occurrence,
+							Offset: time.Duration(r),
+							At:     at,
+						})
+					case at.After(to) && !at.After(horizon) && (next.IsZero() || at.Before(next)):
+						next = at
+					}
+				}
+			}
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].At.Before(due[j].At) })
+	return due, next, nil
+}
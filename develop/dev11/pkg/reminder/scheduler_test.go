@@ -0,0 +1,169 @@
+package reminder
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"dev11/pkg/models"
+	"dev11/pkg/repository/cache"
+)
+
+type notifierFunc func(ctx context.Context, n Notification) error
+
+func (f notifierFunc) Notify(ctx context.Context, n Notification) error {
+	return f(ctx, n)
+}
+
+func TestScheduler_scan(t *testing.T) {
+	repo := cache.NewUserCache(cache.NewCache())
+	start := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	events := []models.Event{
+		{Id: "1", Name: "daily", Date: start, RRule: "FREQ=DAILY",
+			Reminders: []models.Duration{models.Duration(15 * time.Minute), models.Duration(24 * time.Hour)}},
+		{Id: "2", Name: "once", Date: start.Add(time.Hour), Reminders: []models.Duration{0}},
+		{Id: "3", Name: "silent", Date: start},
+	}
+	for _, e := range events {
+		if err := repo.PutUsersEvent("1", e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := NewScheduler(repo, LogNotifier{})
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []string
+		wantNext time.Time
+	}{
+		{
+			name: "nothing is due yet",
+			from: start.Add(-30 * time.Minute), to: start.Add(-15*time.Minute - time.Second),
+			want:     nil,
+			wantNext: start.Add(-15 * time.Minute),
+		},
+		{
+			name: "15 minutes before",
+			from: start.Add(-20 * time.Minute), to: start.Add(-10 * time.Minute),
+			want:     []string{"daily 2024-03-04T10:00:00Z"},
+			wantNext: time.Time{},
+		},
+		{
+			name: "day before",
+			from: start.Add(-time.Minute), to: start,
+			want:     []string{"daily 2024-03-05T10:00:00Z"},
+			wantNext: time.Time{},
+		},
+		{
+			name: "at the start",
+			from: start.Add(50 * time.Minute), to: start.Add(time.Hour),
+			want:     []string{"once 2024-03-04T11:00:00Z"},
+			wantNext: time.Time{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			due, next, err := s.scan(tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, n := range due {
+				got = append(got, n.Event.Name+" "+n.Event.Date.Format(time.RFC3339))
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("scan() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("scan() = %v, want %v", got, tt.want)
+				}
+			}
+			if !next.Equal(tt.wantNext) {
+				t.Errorf("next = %v, want %v", next, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestScheduler_Start(t *testing.T) {
+	repo := cache.NewUserCache(cache.NewCache())
+	var (
+		mu    sync.Mutex
+		fired []string
+	)
+	s := NewScheduler(repo, notifierFunc(func(_ context.Context, n Notification) error {
+		mu.Lock()
+		defer mu.Unlock()
+		fired = append(fired, n.Event.Name)
+		return nil
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+
+	event := models.Event{
+		Id:        "1",
+		Name:      "soon",
+		Date:      time.Now().Add(150 * time.Millisecond),
+		Reminders: []models.Duration{models.Duration(100 * time.Millisecond)},
+	}
+	if err := repo.PutUsersEvent("1", event); err != nil {
+		t.Fatal(err)
+	}
+	s.Wake()
+
+	time.Sleep(300 * time.Millisecond)
+	cancel()
+	s.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(fired) != 1 || fired[0] != "soon" {
+		t.Errorf("fired = %v, want [soon]", fired)
+	}
+}
+
+func TestScheduler_CatchUp(t *testing.T) {
+	tests := []struct {
+		name    string
+		catchUp time.Duration
+		want    int
+	}{
+		{"within the window", time.Hour, 1},
+		{"disabled by default", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := cache.NewUserCache(cache.NewCache())
+			// the reminder fell due while the scheduler was not running
+			event := models.Event{
+				Id:        "1",
+				Name:      "missed",
+				Date:      time.Now().Add(5 * time.Minute),
+				Reminders: []models.Duration{models.Duration(15 * time.Minute)},
+			}
+			if err := repo.PutUsersEvent("1", event); err != nil {
+				t.Fatal(err)
+			}
+			fired := make(chan Notification, 1)
+			s := NewScheduler(repo, notifierFunc(func(_ context.Context, n Notification) error {
+				fired <- n
+				return nil
+			}))
+			if tt.catchUp > 0 {
+				s.SetCatchUp(tt.catchUp)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			s.Start(ctx)
+			time.Sleep(100 * time.Millisecond)
+			cancel()
+			s.Wait()
+
+			if len(fired) != tt.want {
+				t.Errorf("fired %d reminders, want %d", len(fired), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"slices"
+
+	"dev11/pkg/models"
+)
+
+// maxAuditEntries is the number of the latest entries kept in the history
+// of an event.
+const maxAuditEntries = 100
+
+func (o *UserCacheRepo) AddAuditEntry(userId, eventId string, entry models.AuditEntry) error {
+	o.cch.Mutex.Lock()
+	defer o.cch.Mutex.Unlock()
+
+	if _, err := o.getUser(userId); err != nil {
+		return err
+	}
+	histories, found := o.cch.Audit[userId]
+	if !found {
+		histories = make(map[string][]models.AuditEntry)
+		o.cch.Audit[userId] = histories
+	}
+	history := histories[eventId]
+	history = append(history, entry.Clone())
+	if len(history) > maxAuditEntries {
+		history = slices.Delete(history, 0, len(history)-maxAuditEntries)
+	}
+	histories[eventId] = history
+	return nil
+}
+
+func (o *UserCacheRepo) GetAuditEntries(userId, eventId string) ([]models.AuditEntry, error) {
+	o.cch.Mutex.RLock()
+	defer o.cch.Mutex.RUnlock()
+
+	if _, err := o.getUser(userId); err != nil {
+		return nil, err
+	}
+	history := o.cch.Audit[userId][eventId]
+	entries := make([]models.AuditEntry, 0, len(history))
+	for _, v := range history {
+		entries = append(entries, v.Clone())
+	}
+	return entries, nil
+}
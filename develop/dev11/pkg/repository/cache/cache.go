@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"dev11/pkg/models"
+	"dev11/pkg/search"
 )
 
 const initialMapSize = 10
@@ -11,10 +12,30 @@ const initialMapSize = 10
 type Cache struct {
 	Mutex sync.RWMutex
 	Data  map[string]models.User
+	// Groups are groups of users by id.
+	Groups map[string]models.Group
+	// Indexes are full-text indexes of events by user id.
+	Indexes map[string]*search.Index
+	// Trash are deleted events by user id and event id.
+	Trash map[string]map[string]models.TrashedEvent
+	// Audit are histories of events by user id and event id.
+	Audit map[string]map[string][]models.AuditEntry
 }
 
 func NewCache() *Cache {
 	var cache Cache
 	cache.Data = make(map[string]models.User, initialMapSize)
+	cache.Groups = make(map[string]models.Group)
+	cache.Indexes = make(map[string]*search.Index)
+	cache.Trash = make(map[string]map[string]models.TrashedEvent)
+	cache.Audit = make(map[string]map[string][]models.AuditEntry)
 	return &cache
 }
+
+// Restore replaces the data of c with the data of from, which must not be
+// used afterwards.
+func (c *Cache) Restore(from *Cache) {
+	c.Mutex.Lock()
+	defer c.Mutex.Unlock()
+	c.Data, c.Groups, c.Indexes, c.Trash, c.Audit = from.Data, from.Groups, from.Indexes, from.Trash, from.Audit
+}
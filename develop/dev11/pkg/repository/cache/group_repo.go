@@ -0,0 +1,69 @@
+package cache
+
+import "dev11/pkg/models"
+
+func (o *UserCacheRepo) CreateGroup(group models.Group) error {
+	o.cch.Mutex.Lock()
+	defer o.cch.Mutex.Unlock()
+
+	if _, found := o.cch.Groups[group.Id]; found {
+		return models.Errorf(models.ErrConflict, "group with id = %s already exists", group.Id)
+	}
+	o.cch.Groups[group.Id] = group.Clone()
+	return nil
+}
+
+func (o *UserCacheRepo) UpdateGroup(id string, update func(group *models.Group) error) (*models.Group, error) {
+	o.cch.Mutex.Lock()
+	defer o.cch.Mutex.Unlock()
+
+	stored, found := o.cch.Groups[id]
+	if !found {
+		return nil, groupNotFoundError(id)
+	}
+	updated := stored.Clone()
+	if err := update(&updated); err != nil {
+		return nil, err
+	}
+	updated.Id = id
+	o.cch.Groups[id] = updated.Clone()
+	return &updated, nil
+}
+
+func (o *UserCacheRepo) GetGroup(id string) (*models.Group, error) {
+	o.cch.Mutex.RLock()
+	defer o.cch.Mutex.RUnlock()
+
+	group, found := o.cch.Groups[id]
+	if !found {
+		return nil, groupNotFoundError(id)
+	}
+	result := group.Clone()
+	return &result, nil
+}
+
+func (o *UserCacheRepo) GetGroups() ([]models.Group, error) {
+	o.cch.Mutex.RLock()
+	defer o.cch.Mutex.RUnlock()
+
+	groups := make([]models.Group, 0, len(o.cch.Groups))
+	for _, g := range o.cch.Groups {
+		groups = append(groups, g.Clone())
+	}
+	return groups, nil
+}
+
+func (o *UserCacheRepo) DeleteGroup(id string) error {
+	o.cch.Mutex.Lock()
+	defer o.cch.Mutex.Unlock()
+
+	if _, found := o.cch.Groups[id]; !found {
+		return groupNotFoundError(id)
+	}
+	delete(o.cch.Groups, id)
+	return nil
+}
+
+func groupNotFoundError(id string) error {
+	return models.Errorf(models.ErrNotFound, "failed to find group with id = %s", id)
+}
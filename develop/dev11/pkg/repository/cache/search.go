@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"dev11/pkg/models"
+	"dev11/pkg/search"
+)
+
+// SearchUsersEvents returns the events of the user which match text by
+// prefixes of words of their names and descriptions and have all of tags.
+func (o *UserCacheRepo) SearchUsersEvents(userId, text string, tags []string) ([]models.Event, error) {
+	o.cch.Mutex.RLock()
+	defer o.cch.Mutex.RUnlock()
+
+	user, err := o.getUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]models.Event, 0)
+	if index, found := o.cch.Indexes[userId]; found {
+		for _, id := range index.Search(text, tags) {
+			events = append(events, user.Events[id].Clone())
+		}
+	}
+	return events, nil
+}
+
+// indexUser rebuilds the index of the events of the user. It must be called
+// with o.cch.Mutex held.
+func (o *UserCacheRepo) indexUser(id string, user models.User) {
+	index := search.NewIndex()
+	for _, event := range user.Events {
+		index.Add(event.Id, eventText(event), event.Tags)
+	}
+	o.cch.Indexes[id] = index
+}
+
+// indexEvent adds the event to the index of the user replacing its previous
+// version. It must be called with o.cch.Mutex held.
+func (o *UserCacheRepo) indexEvent(userId string, event models.Event) {
+	index, found := o.cch.Indexes[userId]
+	if !found {
+		index = search.NewIndex()
+		o.cch.Indexes[userId] = index
+	}
+	index.Add(event.Id, eventText(event), event.Tags)
+}
+
+// unindexEvent removes the event from the index of the user. It must be
+// called with o.cch.Mutex held.
+func (o *UserCacheRepo) unindexEvent(userId, eventId string) {
+	if index, found := o.cch.Indexes[userId]; found {
+		index.Remove(eventId)
+	}
+}
+
+func eventText(event models.Event) string {
+	return event.Name + "\n" + event.Description
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"dev11/pkg/models"
+)
+
+func (o *UserCacheRepo) TrashUsersEvent(
+	userId, eventId string,
+	version int64,
+	deletion models.TrashedEvent,
+) (*models.TrashedEvent, error) {
+	o.cch.Mutex.Lock()
+	defer o.cch.Mutex.Unlock()
+
+	tx, err := o.begin(userId)
+	if err != nil {
+		return nil, err
+	}
+	return tx.TrashEvent(eventId, version, deletion)
+}
+
+func (o *UserCacheRepo) RestoreUsersEvent(userId, eventId string) (*models.Event, error) {
+	o.cch.Mutex.Lock()
+	defer o.cch.Mutex.Unlock()
+
+	tx, err := o.begin(userId)
+	if err != nil {
+		return nil, err
+	}
+	return tx.RestoreEvent(eventId)
+}
+
+func (o *UserCacheRepo) GetUsersTrashedEvent(userId, eventId string) (*models.TrashedEvent, error) {
+	o.cch.Mutex.RLock()
+	defer o.cch.Mutex.RUnlock()
+
+	if _, err := o.getUser(userId); err != nil {
+		return nil, err
+	}
+	if trashed, found := o.cch.Trash[userId][eventId]; found {
+		result := trashed.Clone()
+		return &result, nil
+	}
+	return nil, trashedEventNotFoundError(userId, eventId)
+}
+
+func (o *UserCacheRepo) GetUsersTrash(userId string) ([]models.TrashedEvent, error) {
+	o.cch.Mutex.RLock()
+	defer o.cch.Mutex.RUnlock()
+
+	if _, err := o.getUser(userId); err != nil {
+		return nil, err
+	}
+	trash := make([]models.TrashedEvent, 0, len(o.cch.Trash[userId]))
+	for _, v := range o.cch.Trash[userId] {
+		trash = append(trash, v.Clone())
+	}
+	return trash, nil
+}
+
+func (o *UserCacheRepo) PurgeTrash(now time.Time) (map[string][]models.TrashedEvent, error) {
+	o.cch.Mutex.Lock()
+	defer o.cch.Mutex.Unlock()
+
+	purged := make(map[string][]models.TrashedEvent)
+	for userId, trash := range o.cch.Trash {
+		for eventId, v := range trash {
+			if !v.PurgeAt.After(now) {
+				o.purge(userId, eventId)
+				purged[userId] = append(purged[userId], v.Clone())
+			}
+		}
+	}
+	return purged, nil
+}
+
+func (o *UserCacheRepo) TrimUsersTrash(userId string, n int) (int, error) {
+	o.cch.Mutex.Lock()
+	defer o.cch.Mutex.Unlock()
+
+	if _, err := o.getUser(userId); err != nil {
+		return 0, err
+	}
+	trash := o.cch.Trash[userId]
+	if len(trash) <= n {
+		return 0, nil
+	}
+	ids := make([]string, 0, len(trash))
+	for eventId := range trash {
+		ids = append(ids, eventId)
+	}
+	// the latest deleted first
+	slices.SortFunc(ids, func(a, b string) int {
+		if c := trash[b].DeletedAt.Compare(trash[a].DeletedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	for _, eventId := range ids[n:] {
+		o.purge(userId, eventId)
+	}
+	return len(ids) - n, nil
+}
+
+// purge removes the event from the trash of the user for good together
+// with its history. It must be called with o.cch.Mutex held.
+func (o *UserCacheRepo) purge(userId, eventId string) {
+	delete(o.cch.Trash[userId], eventId)
+	delete(o.cch.Audit[userId], eventId)
+}
+
+func trashedEventNotFoundError(userId, eventId string) error {
+	return models.Errorf(models.ErrNotFound,
+		"failed to find deleted event with id = %s of user id = %s", eventId, userId)
+}
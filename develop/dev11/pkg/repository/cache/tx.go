@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"dev11/pkg/models"
+	"dev11/pkg/repository"
+)
+
+// eventsTx changes the stored events of a user in place and keeps the
+// steps undoing the changes until the transaction is over. It must be used
+// with o.cch.Mutex held.
+type eventsTx struct {
+	o      *UserCacheRepo
+	userId string
+	events map[string]models.Event
+	undo   []func()
+}
+
+// begin starts a transaction over the events of the user. It must be
+// called with o.cch.Mutex held.
+func (o *UserCacheRepo) begin(userId string) (*eventsTx, error) {
+	user, err := o.getUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	return &eventsTx{o: o, userId: userId, events: user.Events}, nil
+}
+
+func (o *UserCacheRepo) UpdateUsersEvents(userId string, fn func(tx repository.EventsTx) error) error {
+	o.cch.Mutex.Lock()
+	defer o.cch.Mutex.Unlock()
+
+	tx, err := o.begin(userId)
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		tx.rollback(0)
+		return err
+	}
+	return nil
+}
+
+func (tx *eventsTx) GetEvents() ([]models.Event, error) {
+	events := make([]models.Event, 0, len(tx.events))
+	for _, v := range tx.events {
+		events = append(events, v.Clone())
+	}
+	return events, nil
+}
+
+func (tx *eventsTx) CreateEvent(event models.Event) error {
+	if _, found := tx.events[event.Id]; found {
+		return models.Errorf(models.ErrConflict,
+			"event with id = %s of user id = %s already exists", event.Id, tx.userId)
+	}
+
+	event = event.Clone()
+	event.Version = 1
+	tx.put(event)
+	return nil
+}
+
+func (tx *eventsTx) UpdateEvent(event models.Event) (*models.Event, error) {
+	stored, found := tx.events[event.Id]
+	if !found {
+		return nil, eventNotFoundError(tx.userId, event.Id)
+	}
+	if err := checkVersion(tx.userId, stored, event.Version); err != nil {
+		return nil, err
+	}
+
+	event = event.Clone()
+	event.Version = stored.Version + 1
+	tx.put(event)
+
+	result := event.Clone()
+	return &result, nil
+}
+
+func (tx *eventsTx) TrashEvent(
+	eventId string,
+	version int64,
+	deletion models.TrashedEvent,
+) (*models.TrashedEvent, error) {
+	stored, found := tx.events[eventId]
+	if !found {
+		return nil, eventNotFoundError(tx.userId, eventId)
+	}
+	if err := checkVersion(tx.userId, stored, version); err != nil {
+		return nil, err
+	}
+
+	tx.remove(eventId)
+	trash, found := tx.o.cch.Trash[tx.userId]
+	if !found {
+		trash = make(map[string]models.TrashedEvent)
+		tx.o.cch.Trash[tx.userId] = trash
+	}
+	prev, found := trash[eventId]
+	tx.undo = append(tx.undo, func() {
+		if found {
+			trash[eventId] = prev
+		} else {
+			delete(trash, eventId)
+		}
+	})
+	deletion.Event = stored
+	trash[eventId] = deletion
+
+	result := deletion.Clone()
+	return &result, nil
+}
+
+func (tx *eventsTx) GetTrash() ([]models.TrashedEvent, error) {
+	trash := make([]models.TrashedEvent, 0, len(tx.o.cch.Trash[tx.userId]))
+	for _, v := range tx.o.cch.Trash[tx.userId] {
+		trash = append(trash, v.Clone())
+	}
+	return trash, nil
+}
+
+func (tx *eventsTx) RestoreEvent(eventId string) (*models.Event, error) {
+	trash := tx.o.cch.Trash[tx.userId]
+	trashed, found := trash[eventId]
+	if !found {
+		return nil, trashedEventNotFoundError(tx.userId, eventId)
+	}
+	if _, found = tx.events[eventId]; found {
+		return nil, models.Errorf(models.ErrConflict,
+			"event with id = %s of user id = %s already exists", eventId, tx.userId)
+	}
+
+	tx.undo = append(tx.undo, func() { trash[eventId] = trashed })
+	delete(trash, eventId)
+	event := trashed.Event
+	event.Version++
+	tx.put(event)
+
+	result := event.Clone()
+	return &result, nil
+}
+
+// put stores the event and indexes it.
+func (tx *eventsTx) put(event models.Event) {
+	prev, found := tx.events[event.Id]
+	tx.undo = append(tx.undo, func() {
+		if found {
+			tx.events[event.Id] = prev
+			tx.o.indexEvent(tx.userId, prev)
+		} else {
+			delete(tx.events, event.Id)
+			tx.o.unindexEvent(tx.userId, event.Id)
+		}
+	})
+	tx.events[event.Id] = event
+	tx.o.indexEvent(tx.userId, event)
+}
+
+// remove removes the stored event from the events and the index.
+func (tx *eventsTx) remove(eventId string) {
+	prev := tx.events[eventId]
+	tx.undo = append(tx.undo, func() {
+		tx.events[eventId] = prev
+		tx.o.indexEvent(tx.userId, prev)
+	})
+	delete(tx.events, eventId)
+	tx.o.unindexEvent(tx.userId, eventId)
+}
+
+func (tx *eventsTx) Atomic(fn func() error) error {
+	mark := len(tx.undo)
+	if err := fn(); err != nil {
+		tx.rollback(mark)
+		return err
+	}
+	return nil
+}
+
+// rollback undoes the changes made with the transaction after the first
+// mark of them, from the latest.
+func (tx *eventsTx) rollback(mark int) {
+	for i := len(tx.undo) - 1; i >= mark; i-- {
+		tx.undo[i]()
+	}
+	tx.undo = tx.undo[:mark]
+}
@@ -1,12 +1,9 @@
 package cache
 
-import (
-	"fmt"
-	"net/http"
-
-	"dev11/pkg/models"
-)
+import "dev11/pkg/models"
 
+// UserCacheRepo keeps users in memory. It stores and returns deep copies of
+// users and events, so callers never share memory with the cache.
 type UserCacheRepo struct {
 	cch *Cache
 }
@@ -17,22 +14,141 @@ func NewUserCache(cch *Cache) *UserCacheRepo {
 	return &c
 }
 
-func (o *UserCacheRepo) PutUser(id string, user models.User) {
+// PutUser stores the user replacing an existing one with the same id.
+func (o *UserCacheRepo) PutUser(id string, user models.User) error {
 	o.cch.Mutex.Lock()
 	defer o.cch.Mutex.Unlock()
+
+	user = user.Clone()
+	if user.Events == nil {
+		user.Events = models.NewUser(id).Events
+	}
 	o.cch.Data[id] = user
+	o.indexUser(id, user)
+	return nil
+}
+
+func (o *UserCacheRepo) CreateUser(user models.User) error {
+	o.cch.Mutex.Lock()
+	defer o.cch.Mutex.Unlock()
+
+	if _, found := o.cch.Data[user.Id]; found {
+		return models.Errorf(models.ErrConflict, "user with id = %s already exists", user.Id)
+	}
+	user = user.Clone()
+	if user.Events == nil {
+		user.Events = models.NewUser(user.Id).Events
+	}
+	o.cch.Data[user.Id] = user
+	o.indexUser(user.Id, user)
+	return nil
+}
+
+func (o *UserCacheRepo) UpdateUser(id string, update func(user *models.User) error) (*models.User, error) {
+	o.cch.Mutex.Lock()
+	defer o.cch.Mutex.Unlock()
+
+	user, err := o.getUser(id)
+	if err != nil {
+		return nil, err
+	}
+	updated := user.Clone()
+	if err = update(&updated); err != nil {
+		return nil, err
+	}
+	updated.Id, updated.Events = id, user.Events
+	o.cch.Data[id] = updated
+
+	result := updated.Clone()
+	return &result, nil
+}
+
+func (o *UserCacheRepo) CreateUsersEvent(userId string, event models.Event) error {
+	o.cch.Mutex.Lock()
+	defer o.cch.Mutex.Unlock()
+
+	tx, err := o.begin(userId)
+	if err != nil {
+		return err
+	}
+	return tx.CreateEvent(event)
 }
 
+// PutUsersEvent stores the event replacing an existing one with the same id.
 func (o *UserCacheRepo) PutUsersEvent(userId string, event models.Event) error {
 	o.cch.Mutex.Lock()
 	defer o.cch.Mutex.Unlock()
 
-	user, err := o.GetUser(userId)
+	user, err := o.getUser(userId)
 	if err != nil {
 		return err
 	}
 
+	event = event.Clone()
+	if event.Version == 0 {
+		event.Version = user.Events[event.Id].Version + 1
+	}
 	user.Events[event.Id] = event
+	o.indexEvent(userId, event)
+	return nil
+}
+
+func (o *UserCacheRepo) UpdateUsersEvent(userId string, event models.Event) (*models.Event, error) {
+	o.cch.Mutex.Lock()
+	defer o.cch.Mutex.Unlock()
+
+	tx, err := o.begin(userId)
+	if err != nil {
+		return nil, err
+	}
+	return tx.UpdateEvent(event)
+}
+
+func (o *UserCacheRepo) ModifyUsersEvent(
+	userId, eventId string,
+	modify func(event *models.Event) error,
+) (*models.Event, error) {
+	o.cch.Mutex.Lock()
+	defer o.cch.Mutex.Unlock()
+
+	user, err := o.getUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	stored, found := user.Events[eventId]
+	if !found {
+		return nil, eventNotFoundError(userId, eventId)
+	}
+	event := stored.Clone()
+	if err = modify(&event); err != nil {
+		return nil, err
+	}
+	event.Id, event.Version = eventId, stored.Version+1
+	user.Events[eventId] = event
+	o.indexEvent(userId, event)
+
+	result := event.Clone()
+	return &result, nil
+}
+
+func (o *UserCacheRepo) DeleteUsersEvent(userId, eventId string, version int64) error {
+	o.cch.Mutex.Lock()
+	defer o.cch.Mutex.Unlock()
+
+	user, err := o.getUser(userId)
+	if err != nil {
+		return err
+	}
+	stored, found := user.Events[eventId]
+	if !found {
+		return eventNotFoundError(userId, eventId)
+	}
+	if err = checkVersion(userId, stored, version); err != nil {
+		return err
+	}
+
+	delete(user.Events, eventId)
+	o.unindexEvent(userId, eventId)
 	return nil
 }
 
@@ -40,10 +156,114 @@ func (o *UserCacheRepo) GetUser(id string) (*models.User, error) {
 	o.cch.Mutex.RLock()
 	defer o.cch.Mutex.RUnlock()
 
+	user, err := o.getUser(id)
+	if err != nil {
+		return nil, err
+	}
+	result := user.Clone()
+	return &result, nil
+}
+
+func (o *UserCacheRepo) GetUsers() ([]models.User, error) {
+	o.cch.Mutex.RLock()
+	defer o.cch.Mutex.RUnlock()
+
+	users := make([]models.User, 0, len(o.cch.Data))
+	for _, v := range o.cch.Data {
+		users = append(users, v.Clone())
+	}
+	return users, nil
+}
+
+func (o *UserCacheRepo) CountUsersEvents() (users, events int, err error) {
+	o.cch.Mutex.RLock()
+	defer o.cch.Mutex.RUnlock()
+
+	for _, v := range o.cch.Data {
+		events += len(v.Events)
+	}
+	return len(o.cch.Data), events, nil
+}
+
+// GetUserByKeyHash returns the user whose API key has the given hash.
+func (o *UserCacheRepo) GetUserByKeyHash(keyHash string) (*models.User, error) {
+	o.cch.Mutex.RLock()
+	defer o.cch.Mutex.RUnlock()
+
+	for _, v := range o.cch.Data {
+		if keyHash != "" && v.APIKeyHash == keyHash {
+			result := v.Clone()
+			return &result, nil
+		}
+	}
+	return nil, models.Errorf(models.ErrNotFound, "failed to find user with the API key")
+}
+
+func (o *UserCacheRepo) DeleteUser(id string) error {
+	o.cch.Mutex.Lock()
+	defer o.cch.Mutex.Unlock()
+
+	if _, err := o.getUser(id); err != nil {
+		return err
+	}
+	delete(o.cch.Data, id)
+	delete(o.cch.Indexes, id)
+	delete(o.cch.Trash, id)
+	delete(o.cch.Audit, id)
+	return nil
+}
+
+func (o *UserCacheRepo) GetUsersEvent(userId, eventId string) (*models.Event, error) {
+	o.cch.Mutex.RLock()
+	defer o.cch.Mutex.RUnlock()
+
+	user, err := o.getUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	if event, found := user.Events[eventId]; found {
+		result := event.Clone()
+		return &result, nil
+	}
+	return nil, eventNotFoundError(userId, eventId)
+}
+
+func (o *UserCacheRepo) GetUsersEvents(userId string) ([]models.Event, error) {
+	o.cch.Mutex.RLock()
+	defer o.cch.Mutex.RUnlock()
+
+	user, err := o.getUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]models.Event, 0, len(user.Events))
+	for _, v := range user.Events {
+		events = append(events, v.Clone())
+	}
+	return events, nil
+}
+
+// getUser returns the stored user, which shares its events with the cache.
+// It must be called with o.cch.Mutex held.
+func (o *UserCacheRepo) getUser(id string) (*models.User, error) {
 	if userData, found := o.cch.Data[id]; found {
 		return &userData, nil
 	}
-	return nil, NewErrorHandler(
-		fmt.Errorf("failed to find user with id = %s", id),
-		http.StatusBadRequest)
+	return nil, models.Errorf(models.ErrNotFound, "failed to find user with id = %s", id)
+}
+
+// checkVersion returns models.ErrPreconditionFailed unless version is 0 or
+// the version of the stored event.
+func checkVersion(userId string, stored models.Event, version int64) error {
+	if version != 0 && version != stored.Version {
+		return models.Errorf(models.ErrPreconditionFailed,
+			"event with id = %s of user id = %s has version %d, not %d",
+			stored.Id, userId, stored.Version, version)
+	}
+	return nil
+}
+
+func eventNotFoundError(userId, eventId string) error {
+	return models.Errorf(models.ErrNotFound,
+		"failed to find event with id = %s of user id = %s", eventId, userId)
 }
@@ -0,0 +1,391 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"dev11/pkg/models"
+	"dev11/pkg/repository"
+)
+
+// TestUserCacheRepo_Concurrent hammers the repository from many goroutines;
+// run it with -race.
+func TestUserCacheRepo_Concurrent(t *testing.T) {
+	const (
+		users   = 4
+		workers = 8
+		ops     = 200
+	)
+	repo := NewUserCache(NewCache())
+	date := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	for u := 0; u < users; u++ {
+		userId := fmt.Sprint("u", u)
+		if err := repo.CreateUser(models.NewUser(userId)); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.CreateUsersEvent(userId, models.Event{Id: "shared", Date: date}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var (
+		wg      sync.WaitGroup
+		created sync.Map
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < ops; i++ {
+				userId := fmt.Sprint("u", i%users)
+				end := date.Add(time.Hour)
+				event := models.Event{
+					Id:        fmt.Sprintf("%d-%d", w, i),
+					Date:      date,
+					End:       &end,
+					Reminders: []models.Duration{models.Duration(time.Minute)},
+				}
+				if err := repo.CreateUsersEvent(userId, event); err != nil {
+					t.Error(err)
+					return
+				}
+				// the stored event must not share memory with the argument
+				end = end.Add(time.Hour)
+				event.Reminders[0] = 0
+
+				_, err := repo.ModifyUsersEvent(userId, "shared", func(e *models.Event) error {
+					e.ExDates = append(e.ExDates, date.AddDate(0, 0, w*ops+i))
+					return nil
+				})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				if i%2 == 0 {
+					if err = repo.DeleteUsersEvent(userId, event.Id, 0); err != nil {
+						t.Error(err)
+						return
+					}
+				} else {
+					created.Store(userId+"/"+event.Id, struct{}{})
+				}
+
+				events, err := repo.GetUsersEvents(userId)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				for j := range events {
+					events[j].Name = "changed"
+					events[j].ExDates = append(events[j].ExDates, date)
+				}
+				_, _, _ = repo.CountUsersEvents()
+				all, _ := repo.GetUsers()
+				for _, u := range all {
+					for id, e := range u.Events {
+						e.Reminders = nil
+						u.Events[id] = e
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	want := 0
+	created.Range(func(key, _ any) bool {
+		want++
+		return true
+	})
+	got := 0
+	exDates := 0
+	for u := 0; u < users; u++ {
+		events, err := repo.GetUsersEvents(fmt.Sprint("u", u))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range events {
+			if e.Id == "shared" {
+				exDates += len(e.ExDates)
+				continue
+			}
+			got++
+			if e.Name != "" || !e.End.Equal(date.Add(time.Hour)) || len(e.Reminders) != 1 || e.Reminders[0] == 0 {
+				t.Errorf("event %s was changed through a copy: %+v", e.Id, e)
+			}
+		}
+	}
+	if got != want {
+		t.Errorf("got %d events, want %d", got, want)
+	}
+	if exDates != workers*ops {
+		t.Errorf("shared events have %d exdates, want %d: modifications were lost", exDates, workers*ops)
+	}
+	// the test user is created with the repository
+	if u, e, err := repo.CountUsersEvents(); err != nil || u != users+1 || e != want+users {
+		t.Errorf("CountUsersEvents() = %d, %d, %v, want %d, %d", u, e, err, users+1, want+users)
+	}
+}
+
+func TestUserCacheRepo_CreateConflicts(t *testing.T) {
+	repo := NewUserCache(NewCache())
+	const workers = 16
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- repo.CreateUser(models.NewUser("new"))
+			errs <- repo.CreateUsersEvent("1", models.Event{Id: "same"})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, models.ErrConflict):
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+	if succeeded != 2 {
+		t.Errorf("%d creations succeeded, want one user and one event", succeeded)
+	}
+}
+
+func TestUserCacheRepo_SearchUsersEvents(t *testing.T) {
+	repo := NewUserCache(NewCache())
+	user := models.NewUser("alice")
+	user.Events["imported"] = models.Event{Id: "imported", Name: "Отпуск"}
+	if err := repo.CreateUser(user); err != nil {
+		t.Fatal(err)
+	}
+	ids := func(text string, tags ...string) []string {
+		t.Helper()
+		events, err := repo.SearchUsersEvents("alice", text, tags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, e := range events {
+			ids = append(ids, e.Id)
+		}
+		return ids
+	}
+
+	if err := repo.CreateUsersEvent("alice", models.Event{Id: "a", Name: "Планёрка", Tags: []string{"work"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateUsersEvent("alice", models.Event{Id: "b", Name: "Review", Description: "план релиза"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids("ПЛАН"); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("search after create = %q, want [a b]", got)
+	}
+	if got := ids("отп"); !slices.Equal(got, []string{"imported"}) {
+		t.Errorf("search of an event of a created user = %q, want [imported]", got)
+	}
+
+	if _, err := repo.UpdateUsersEvent("alice", models.Event{Id: "b", Name: "Review"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.ModifyUsersEvent("alice", "imported", func(e *models.Event) error {
+		e.Tags = []string{"Work"}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids("план"); !slices.Equal(got, []string{"a"}) {
+		t.Errorf("search after update = %q, want [a]", got)
+	}
+	if got := ids("", "WORK"); !slices.Equal(got, []string{"a", "imported"}) {
+		t.Errorf("search by tag after modify = %q, want [a imported]", got)
+	}
+
+	if err := repo.DeleteUsersEvent("alice", "a", 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids("", "work"); !slices.Equal(got, []string{"imported"}) {
+		t.Errorf("search after delete = %q, want [imported]", got)
+	}
+	if err := repo.DeleteUser("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.SearchUsersEvents("alice", "review", nil); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("search of a deleted user error = %v, want %v", err, models.ErrNotFound)
+	}
+}
+
+func TestUserCacheRepo_Trash(t *testing.T) {
+	repo := NewUserCache(NewCache())
+	deletedAt := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	if err := repo.CreateUsersEvent("1", models.Event{Id: "a", Name: "review"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.TrashUsersEvent("1", "a", 2, models.TrashedEvent{}); !errors.Is(err, models.ErrPreconditionFailed) {
+		t.Errorf("TrashUsersEvent() of a stale version error = %v, want %v", err, models.ErrPreconditionFailed)
+	}
+	trashed, err := repo.TrashUsersEvent("1", "a", 1, models.TrashedEvent{DeletedAt: deletedAt, PurgeAt: deletedAt.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trashed.Event.Name != "review" {
+		t.Errorf("TrashUsersEvent() = %+v", trashed)
+	}
+	if _, err = repo.GetUsersEvent("1", "a"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("GetUsersEvent() of a trashed event error = %v, want %v", err, models.ErrNotFound)
+	}
+	if events, _ := repo.SearchUsersEvents("1", "review", nil); len(events) != 0 {
+		t.Errorf("SearchUsersEvents() found a trashed event: %+v", events)
+	}
+
+	if err = repo.CreateUsersEvent("1", models.Event{Id: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = repo.RestoreUsersEvent("1", "a"); !errors.Is(err, models.ErrConflict) {
+		t.Errorf("RestoreUsersEvent() over an existing event error = %v, want %v", err, models.ErrConflict)
+	}
+	if err = repo.DeleteUsersEvent("1", "a", 0); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := repo.RestoreUsersEvent("1", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Name != "review" || restored.Version != 2 {
+		t.Errorf("RestoreUsersEvent() = %+v, want version 2", restored)
+	}
+	if trash, _ := repo.GetUsersTrash("1"); len(trash) != 0 {
+		t.Errorf("GetUsersTrash() after restoring = %+v", trash)
+	}
+
+	if _, err = repo.TrashUsersEvent("1", "a", 0, models.TrashedEvent{DeletedAt: deletedAt, PurgeAt: deletedAt.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if purged, _ := repo.PurgeTrash(deletedAt); len(purged) != 0 {
+		t.Errorf("PurgeTrash() before PurgeAt = %+v", purged)
+	}
+	if purged, _ := repo.PurgeTrash(deletedAt.Add(time.Hour)); len(purged["1"]) != 1 {
+		t.Errorf("PurgeTrash() at PurgeAt = %+v, want the event", purged)
+	}
+	if _, err = repo.RestoreUsersEvent("1", "a"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("RestoreUsersEvent() of a purged event error = %v, want %v", err, models.ErrNotFound)
+	}
+}
+
+func TestUserCacheRepo_AuditEntries(t *testing.T) {
+	repo := NewUserCache(NewCache())
+	for i := 0; i <= maxAuditEntries; i++ {
+		entry := models.AuditEntry{Type: models.ChangeCreated, After: &models.Event{Id: "a", Version: int64(i + 1)}}
+		if i > 0 {
+			entry.Type, entry.Before = models.ChangeUpdated, &models.Event{Id: "a", Version: int64(i)}
+		}
+		if err := repo.AddAuditEntry("1", "a", entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deleted := models.AuditEntry{Type: models.ChangeDeleted, Before: &models.Event{Id: "a", Version: maxAuditEntries + 1}}
+	if err := repo.AddAuditEntry("1", "a", deleted); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := repo.GetAuditEntries("1", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != maxAuditEntries {
+		t.Fatalf("history has %d entries, want %d", len(history), maxAuditEntries)
+	}
+	if first := history[0]; first.Before == nil || first.Before.Version != 2 || first.After.Version != 3 {
+		t.Errorf("oldest kept entry = %+v, want the change from version 2 to 3", first)
+	}
+	if last := history[len(history)-1]; last.Before == nil || last.Before.Version != maxAuditEntries+1 || last.After != nil {
+		t.Errorf("deletion entry = %+v, want the last version before it", last)
+	}
+	if history, _ = repo.GetAuditEntries("1", "unknown"); len(history) != 0 {
+		t.Errorf("history of an unknown event = %+v", history)
+	}
+}
+
+func TestUserCacheRepo_UpdateUsersEvents(t *testing.T) {
+	repo := NewUserCache(NewCache())
+	for _, id := range []string{"a", "b"} {
+		if err := repo.CreateUsersEvent("1", models.Event{Id: id, Name: "review " + id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snapshot := func() []string {
+		events, _ := repo.GetUsersEvents("1")
+		var state []string
+		for _, e := range events {
+			state = append(state, fmt.Sprintf("%s %s v%d", e.Id, e.Name, e.Version))
+		}
+		slices.Sort(state)
+		return state
+	}
+	before := snapshot()
+
+	failed := errors.New("failed")
+	err := repo.UpdateUsersEvents("1", func(tx repository.EventsTx) error {
+		if err := tx.CreateEvent(models.Event{Id: "c", Name: "retro"}); err != nil {
+			return err
+		}
+		if _, err := tx.UpdateEvent(models.Event{Id: "a", Name: "planning", Version: 1}); err != nil {
+			return err
+		}
+		if _, err := tx.TrashEvent("b", 0, models.TrashedEvent{}); err != nil {
+			return err
+		}
+		if events, _ := tx.GetEvents(); len(events) != 2 {
+			t.Errorf("GetEvents() within the transaction = %+v", events)
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("UpdateUsersEvents() error = %v, want %v", err, failed)
+	}
+	if after := snapshot(); !slices.Equal(after, before) {
+		t.Errorf("events after rollback = %q, want %q", after, before)
+	}
+	if trash, _ := repo.GetUsersTrash("1"); len(trash) != 0 {
+		t.Errorf("GetUsersTrash() after rollback = %+v", trash)
+	}
+	for text, want := range map[string]int{"review": 2, "planning": 0, "retro": 0} {
+		if events, _ := repo.SearchUsersEvents("1", text, nil); len(events) != want {
+			t.Errorf("SearchUsersEvents(%q) after rollback = %d events, want %d", text, len(events), want)
+		}
+	}
+
+	err = repo.UpdateUsersEvents("1", func(tx repository.EventsTx) error {
+		if _, err := tx.UpdateEvent(models.Event{Id: "a", Version: 2}); !errors.Is(err, models.ErrPreconditionFailed) {
+			t.Errorf("UpdateEvent() of a stale version error = %v, want %v", err, models.ErrPreconditionFailed)
+		}
+		if err := tx.CreateEvent(models.Event{Id: "c", Name: "retro"}); err != nil {
+			return err
+		}
+		_, err := tx.TrashEvent("b", 1, models.TrashedEvent{})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after := snapshot(); !slices.Equal(after, []string{"a review a v1", "c retro v1"}) {
+		t.Errorf("events after commit = %q", after)
+	}
+	if trash, _ := repo.GetUsersTrash("1"); len(trash) != 1 || trash[0].Event.Id != "b" {
+		t.Errorf("GetUsersTrash() after commit = %+v", trash)
+	}
+	if err = repo.UpdateUsersEvents("unknown", func(repository.EventsTx) error { return nil }); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("UpdateUsersEvents() of an unknown user error = %v, want %v", err, models.ErrNotFound)
+	}
+}
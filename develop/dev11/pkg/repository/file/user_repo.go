@@ -0,0 +1,651 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"dev11/pkg/models"
+	"dev11/pkg/repository"
+	"dev11/pkg/repository/cache"
+)
+
+type operation string
+
+const (
+	opPutUser      operation = "put_user"
+	opCreateUser   operation = "create_user"
+	opUpdateUser   operation = "update_user"
+	opDeleteUser   operation = "delete_user"
+	opPutEvent     operation = "put_event"
+	opCreateEvent  operation = "create_event"
+	opUpdateEvent  operation = "update_event"
+	opDeleteEvent  operation = "delete_event"
+	opCreateGroup  operation = "create_group"
+	opUpdateGroup  operation = "update_group"
+	opDeleteGroup  operation = "delete_group"
+	opTrashEvent   operation = "trash_event"
+	opRestoreEvent operation = "restore_event"
+	opPurgeTrash   operation = "purge_trash"
+	opTrimTrash    operation = "trim_trash"
+	opAddAudit     operation = "add_audit"
+	opBatch        operation = "batch"
+)
+
+// record is a single line of the append-only log.
+type record struct {
+	Op      operation     `json:"op"`
+	UserId  string        `json:"userId"`
+	User    *models.User  `json:"user,omitempty"`
+	Event   *models.Event `json:"event,omitempty"`
+	EventId string        `json:"eventId,omitempty"`
+	Version int64         `json:"version,omitempty"`
+	GroupId string        `json:"groupId,omitempty"`
+	Group   *models.Group `json:"group,omitempty"`
+	// Trashed is the deletion of a trashed event without the event.
+	Trashed *models.TrashedEvent `json:"trashed,omitempty"`
+	Audit   *models.AuditEntry   `json:"audit,omitempty"`
+	// Time is the time the trash was purged at.
+	Time *time.Time `json:"time,omitempty"`
+	// Keep is the number of the latest deleted events kept in the trash
+	// when it is trimmed.
+	Keep int `json:"keep,omitempty"`
+	// Batch are the changes of events of the user made in one transaction.
+	Batch []record `json:"batch,omitempty"`
+}
+
+// logFile is the file of the log, an *os.File outside of tests.
+type logFile interface {
+	io.ReadWriteSeeker
+	Truncate(size int64) error
+	Sync() error
+	Stat() (os.FileInfo, error)
+	Close() error
+}
+
+// UserFileRepo keeps users in memory and persists every mutation to an
+// append-only log of JSON records, which is replayed on start.
+type UserFileRepo struct {
+	mu   sync.Mutex
+	cch  *cache.Cache
+	mem  *cache.UserCacheRepo
+	file logFile
+	// offset is the end of the last record written in full.
+	offset int64
+	// writeErr is the error of a write to the log after which neither the
+	// log nor the in-memory state could be restored. Every later change
+	// fails with it.
+	writeErr error
+}
+
+func NewUserFile(path string) (*UserFileRepo, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	o := newUserFileRepo(f)
+	if err = o.replay(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to replay %s: %w", path, err)
+	}
+	return o, nil
+}
+
+func newUserFileRepo(f logFile) *UserFileRepo {
+	cch := cache.NewCache()
+	return &UserFileRepo{cch: cch, mem: cache.NewUserCache(cch), file: f}
+}
+
+func (o *UserFileRepo) PutUser(id string, user models.User) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.apply(record{Op: opPutUser, UserId: id, User: &user})
+}
+
+func (o *UserFileRepo) CreateUser(user models.User) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.apply(record{Op: opCreateUser, UserId: user.Id, User: &user})
+}
+
+// UpdateUser logs only the attributes of the user, its events stay as they
+// are.
+func (o *UserFileRepo) UpdateUser(id string, update func(user *models.User) error) (*models.User, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	user, err := o.mem.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+	if err = update(user); err != nil {
+		return nil, err
+	}
+	user.Id, user.Events = id, nil
+	if err = o.apply(record{Op: opUpdateUser, UserId: id, User: user}); err != nil {
+		return nil, err
+	}
+	return o.mem.GetUser(id)
+}
+
+func (o *UserFileRepo) DeleteUser(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.apply(record{Op: opDeleteUser, UserId: id})
+}
+
+// PutUsersEvent stores the event replacing an existing one with the same id.
+func (o *UserFileRepo) PutUsersEvent(userId string, event models.Event) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.apply(record{Op: opPutEvent, UserId: userId, Event: &event})
+}
+
+func (o *UserFileRepo) CreateUsersEvent(userId string, event models.Event) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.apply(record{Op: opCreateEvent, UserId: userId, Event: &event})
+}
+
+func (o *UserFileRepo) UpdateUsersEvent(userId string, event models.Event) (*models.Event, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := o.apply(record{Op: opUpdateEvent, UserId: userId, Event: &event}); err != nil {
+		return nil, err
+	}
+	return o.mem.GetUsersEvent(userId, event.Id)
+}
+
+// ModifyUsersEvent is atomic as every change of the repository is made
+// with o.mu held.
+func (o *UserFileRepo) ModifyUsersEvent(
+	userId, eventId string,
+	modify func(event *models.Event) error,
+) (*models.Event, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	event, err := o.mem.GetUsersEvent(userId, eventId)
+	if err != nil {
+		return nil, err
+	}
+	if err = modify(event); err != nil {
+		return nil, err
+	}
+	// nothing can change the event in between, so the update is unconditional
+	event.Id, event.Version = eventId, 0
+	if err = o.apply(record{Op: opUpdateEvent, UserId: userId, Event: event}); err != nil {
+		return nil, err
+	}
+	return o.mem.GetUsersEvent(userId, eventId)
+}
+
+func (o *UserFileRepo) DeleteUsersEvent(userId, eventId string, version int64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.apply(record{Op: opDeleteEvent, UserId: userId, EventId: eventId, Version: version})
+}
+
+// UpdateUsersEvents logs the changes made with the transaction as a single
+// record, so replaying the log never applies a part of them.
+func (o *UserFileRepo) UpdateUsersEvents(userId string, fn func(tx repository.EventsTx) error) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.writeErr != nil {
+		return o.writeErr
+	}
+
+	var batch []record
+	err := o.mem.UpdateUsersEvents(userId, func(tx repository.EventsTx) error {
+		return fn(&loggedTx{EventsTx: tx, userId: userId, batch: &batch})
+	})
+	if err != nil || len(batch) == 0 {
+		return err
+	}
+	return o.write(record{Op: opBatch, UserId: userId, Batch: batch})
+}
+
+func (o *UserFileRepo) TrashUsersEvent(
+	userId, eventId string,
+	version int64,
+	deletion models.TrashedEvent,
+) (*models.TrashedEvent, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	deletion.Event = models.Event{}
+	rec := record{Op: opTrashEvent, UserId: userId, EventId: eventId, Version: version, Trashed: &deletion}
+	if err := o.apply(rec); err != nil {
+		return nil, err
+	}
+	return o.mem.GetUsersTrashedEvent(userId, eventId)
+}
+
+func (o *UserFileRepo) RestoreUsersEvent(userId, eventId string) (*models.Event, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := o.apply(record{Op: opRestoreEvent, UserId: userId, EventId: eventId}); err != nil {
+		return nil, err
+	}
+	return o.mem.GetUsersEvent(userId, eventId)
+}
+
+// PurgeTrash logs the time of the purge, which removes the same events when
+// the log is replayed.
+func (o *UserFileRepo) PurgeTrash(now time.Time) (map[string][]models.TrashedEvent, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.writeErr != nil {
+		return nil, o.writeErr
+	}
+	purged, err := o.mem.PurgeTrash(now)
+	if err != nil || len(purged) == 0 {
+		return purged, err
+	}
+	if err = o.write(record{Op: opPurgeTrash, Time: &now}); err != nil {
+		return nil, err
+	}
+	return purged, nil
+}
+
+func (o *UserFileRepo) TrimUsersTrash(userId string, n int) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.writeErr != nil {
+		return 0, o.writeErr
+	}
+	trimmed, err := o.mem.TrimUsersTrash(userId, n)
+	if err != nil || trimmed == 0 {
+		return trimmed, err
+	}
+	if err = o.write(record{Op: opTrimTrash, UserId: userId, Keep: n}); err != nil {
+		return 0, err
+	}
+	return trimmed, nil
+}
+
+func (o *UserFileRepo) AddAuditEntry(userId, eventId string, entry models.AuditEntry) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.apply(record{Op: opAddAudit, UserId: userId, EventId: eventId, Audit: &entry})
+}
+
+func (o *UserFileRepo) CreateGroup(group models.Group) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.apply(record{Op: opCreateGroup, GroupId: group.Id, Group: &group})
+}
+
+// UpdateGroup logs the whole updated group.
+func (o *UserFileRepo) UpdateGroup(id string, update func(group *models.Group) error) (*models.Group, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	group, err := o.mem.GetGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	if err = update(group); err != nil {
+		return nil, err
+	}
+	group.Id = id
+	if err = o.apply(record{Op: opUpdateGroup, GroupId: id, Group: group}); err != nil {
+		return nil, err
+	}
+	return o.mem.GetGroup(id)
+}
+
+func (o *UserFileRepo) DeleteGroup(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.apply(record{Op: opDeleteGroup, GroupId: id})
+}
+
+func (o *UserFileRepo) GetGroup(id string) (*models.Group, error) {
+	return o.mem.GetGroup(id)
+}
+
+func (o *UserFileRepo) GetUsersTrashedEvent(userId, eventId string) (*models.TrashedEvent, error) {
+	return o.mem.GetUsersTrashedEvent(userId, eventId)
+}
+
+func (o *UserFileRepo) GetUsersTrash(userId string) ([]models.TrashedEvent, error) {
+	return o.mem.GetUsersTrash(userId)
+}
+
+func (o *UserFileRepo) GetAuditEntries(userId, eventId string) ([]models.AuditEntry, error) {
+	return o.mem.GetAuditEntries(userId, eventId)
+}
+
+func (o *UserFileRepo) GetGroups() ([]models.Group, error) {
+	return o.mem.GetGroups()
+}
+
+func (o *UserFileRepo) GetUser(id string) (*models.User, error) {
+	return o.mem.GetUser(id)
+}
+
+func (o *UserFileRepo) GetUsers() ([]models.User, error) {
+	return o.mem.GetUsers()
+}
+
+func (o *UserFileRepo) CountUsersEvents() (users, events int, err error) {
+	return o.mem.CountUsersEvents()
+}
+
+func (o *UserFileRepo) GetUserByKeyHash(keyHash string) (*models.User, error) {
+	return o.mem.GetUserByKeyHash(keyHash)
+}
+
+func (o *UserFileRepo) GetUsersEvent(userId, eventId string) (*models.Event, error) {
+	return o.mem.GetUsersEvent(userId, eventId)
+}
+
+func (o *UserFileRepo) GetUsersEvents(userId string) ([]models.Event, error) {
+	return o.mem.GetUsersEvents(userId)
+}
+
+func (o *UserFileRepo) SearchUsersEvents(userId, text string, tags []string) ([]models.Event, error) {
+	return o.mem.SearchUsersEvents(userId, text, tags)
+}
+
+// Ping fails if the log is closed or could not be restored after a failed
+// write.
+func (o *UserFileRepo) Ping() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.writeErr != nil {
+		return o.writeErr
+	}
+	_, err := o.file.Stat()
+	return err
+}
+
+// Close flushes the log to disk and closes it.
+func (o *UserFileRepo) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := o.file.Sync(); err != nil {
+		o.file.Close()
+		return err
+	}
+	return o.file.Close()
+}
+
+// apply executes rec against the in-memory state and, if it succeeds,
+// appends it to the log. o.mu must be held.
+func (o *UserFileRepo) apply(rec record) error {
+	if o.writeErr != nil {
+		return o.writeErr
+	}
+	if err := o.exec(rec); err != nil {
+		return err
+	}
+	return o.write(rec)
+}
+
+// write appends rec, which is already executed against the in-memory
+// state, to the log. If that fails, the log and the in-memory state are
+// restored to the last record written in full. o.mu must be held.
+func (o *UserFileRepo) write(rec record) error {
+	line, err := json.Marshal(rec)
+	if err == nil {
+		line = append(line, '\n')
+		if _, err = o.file.Write(line); err == nil {
+			err = o.file.Sync()
+		}
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to write log: %w", err)
+		o.restore(err)
+		return err
+	}
+	o.offset += int64(len(line))
+	return nil
+}
+
+// restore cuts off the log after the last record written in full and
+// rebuilds the in-memory state from it, dropping the changes which were not
+// logged. If that fails, writeErr is set. o.mu must be held.
+func (o *UserFileRepo) restore(writeErr error) {
+	err := o.file.Truncate(o.offset)
+	if err == nil {
+		_, err = o.file.Seek(0, io.SeekStart)
+	}
+	if err == nil {
+		replayed := newUserFileRepo(o.file)
+		if err = replayed.replay(); err == nil {
+			o.cch.Restore(replayed.cch)
+		}
+	}
+	if err != nil {
+		o.writeErr = fmt.Errorf("%w; failed to restore the log: %v", writeErr, err)
+	}
+}
+
+func (o *UserFileRepo) exec(rec record) error {
+	switch rec.Op {
+	case opPutUser:
+		if rec.User == nil {
+			return errors.New("put_user record without user")
+		}
+		if rec.User.Events == nil {
+			rec.User.Events = models.NewUser(rec.UserId).Events
+		}
+		return o.mem.PutUser(rec.UserId, *rec.User)
+	case opCreateUser:
+		if rec.User == nil {
+			return errors.New("create_user record without user")
+		}
+		return o.mem.CreateUser(*rec.User)
+	case opUpdateUser:
+		if rec.User == nil {
+			return errors.New("update_user record without user")
+		}
+		_, err := o.mem.UpdateUser(rec.UserId, func(user *models.User) error {
+			*user = *rec.User
+			return nil
+		})
+		return err
+	case opDeleteUser:
+		return o.mem.DeleteUser(rec.UserId)
+	case opPutEvent:
+		if rec.Event == nil {
+			return errors.New("put_event record without event")
+		}
+		return o.mem.PutUsersEvent(rec.UserId, *rec.Event)
+	case opCreateEvent:
+		if rec.Event == nil {
+			return errors.New("create_event record without event")
+		}
+		return o.mem.CreateUsersEvent(rec.UserId, *rec.Event)
+	case opUpdateEvent:
+		if rec.Event == nil {
+			return errors.New("update_event record without event")
+		}
+		_, err := o.mem.UpdateUsersEvent(rec.UserId, *rec.Event)
+		return err
+	case opDeleteEvent:
+		return o.mem.DeleteUsersEvent(rec.UserId, rec.EventId, rec.Version)
+	case opCreateGroup:
+		if rec.Group == nil {
+			return errors.New("create_group record without group")
+		}
+		return o.mem.CreateGroup(*rec.Group)
+	case opUpdateGroup:
+		if rec.Group == nil {
+			return errors.New("update_group record without group")
+		}
+		_, err := o.mem.UpdateGroup(rec.GroupId, func(group *models.Group) error {
+			*group = *rec.Group
+			return nil
+		})
+		return err
+	case opDeleteGroup:
+		return o.mem.DeleteGroup(rec.GroupId)
+	case opTrashEvent:
+		if rec.Trashed == nil {
+			return errors.New("trash_event record without deletion")
+		}
+		_, err := o.mem.TrashUsersEvent(rec.UserId, rec.EventId, rec.Version, *rec.Trashed)
+		return err
+	case opRestoreEvent:
+		_, err := o.mem.RestoreUsersEvent(rec.UserId, rec.EventId)
+		return err
+	case opPurgeTrash:
+		if rec.Time == nil {
+			return errors.New("purge_trash record without time")
+		}
+		_, err := o.mem.PurgeTrash(*rec.Time)
+		return err
+	case opTrimTrash:
+		_, err := o.mem.TrimUsersTrash(rec.UserId, rec.Keep)
+		return err
+	case opAddAudit:
+		if rec.Audit == nil {
+			return errors.New("add_audit record without entry")
+		}
+		return o.mem.AddAuditEntry(rec.UserId, rec.EventId, *rec.Audit)
+	case opBatch:
+		return o.mem.UpdateUsersEvents(rec.UserId, func(tx repository.EventsTx) error {
+			for i, r := range rec.Batch {
+				if err := execTx(tx, r); err != nil {
+					return fmt.Errorf("batch record %d: %w", i+1, err)
+				}
+			}
+			return nil
+		})
+	default:
+		return fmt.Errorf("unknown operation %q", rec.Op)
+	}
+}
+
+// execTx executes a record of a batch within the transaction.
+func execTx(tx repository.EventsTx, rec record) error {
+	switch rec.Op {
+	case opCreateEvent:
+		if rec.Event == nil {
+			return errors.New("create_event record without event")
+		}
+		return tx.CreateEvent(*rec.Event)
+	case opUpdateEvent:
+		if rec.Event == nil {
+			return errors.New("update_event record without event")
+		}
+		_, err := tx.UpdateEvent(*rec.Event)
+		return err
+	case opTrashEvent:
+		if rec.Trashed == nil {
+			return errors.New("trash_event record without deletion")
+		}
+		_, err := tx.TrashEvent(rec.EventId, rec.Version, *rec.Trashed)
+		return err
+	case opRestoreEvent:
+		_, err := tx.RestoreEvent(rec.EventId)
+		return err
+	default:
+		return fmt.Errorf("operation %q is not allowed in a batch", rec.Op)
+	}
+}
+
+// loggedTx records the successful changes made with the transaction.
+type loggedTx struct {
+	repository.EventsTx
+	userId string
+	batch  *[]record
+}
+
+func (tx *loggedTx) CreateEvent(event models.Event) error {
+	if err := tx.EventsTx.CreateEvent(event); err != nil {
+		return err
+	}
+	*tx.batch = append(*tx.batch, record{Op: opCreateEvent, UserId: tx.userId, Event: &event})
+	return nil
+}
+
+func (tx *loggedTx) UpdateEvent(event models.Event) (*models.Event, error) {
+	updated, err := tx.EventsTx.UpdateEvent(event)
+	if err != nil {
+		return nil, err
+	}
+	*tx.batch = append(*tx.batch, record{Op: opUpdateEvent, UserId: tx.userId, Event: &event})
+	return updated, nil
+}
+
+func (tx *loggedTx) TrashEvent(
+	eventId string,
+	version int64,
+	deletion models.TrashedEvent,
+) (*models.TrashedEvent, error) {
+	trashed, err := tx.EventsTx.TrashEvent(eventId, version, deletion)
+	if err != nil {
+		return nil, err
+	}
+	deletion.Event = models.Event{}
+	*tx.batch = append(*tx.batch, record{
+		Op: opTrashEvent, UserId: tx.userId, EventId: eventId, Version: version, Trashed: &deletion,
+	})
+	return trashed, nil
+}
+
+func (tx *loggedTx) RestoreEvent(eventId string) (*models.Event, error) {
+	restored, err := tx.EventsTx.RestoreEvent(eventId)
+	if err != nil {
+		return nil, err
+	}
+	*tx.batch = append(*tx.batch, record{Op: opRestoreEvent, UserId: tx.userId, EventId: eventId})
+	return restored, nil
+}
+
+func (tx *loggedTx) Atomic(fn func() error) error {
+	mark := len(*tx.batch)
+	return tx.EventsTx.Atomic(func() error {
+		if err := fn(); err != nil {
+			*tx.batch = (*tx.batch)[:mark]
+			return err
+		}
+		return nil
+	})
+}
+
+// replay rebuilds the in-memory state from the log. A trailing record
+// without a newline is the result of an interrupted write and is cut off.
+func (o *UserFileRepo) replay() error {
+	r := bufio.NewReader(o.file)
+	var offset int64
+	for lineNum := 1; ; lineNum++ {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				if err = o.file.Truncate(offset); err != nil {
+					return err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+		offset += int64(len(line))
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var rec record
+		if err = json.Unmarshal(line, &rec); err != nil {
+			return fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		if err = o.exec(rec); err != nil {
+			return fmt.Errorf("line %d: %w", lineNum, err)
+		}
+	}
+	o.offset = offset
+	_, err := o.file.Seek(offset, io.SeekStart)
+	return err
+}
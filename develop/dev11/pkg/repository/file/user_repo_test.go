@@ -0,0 +1,353 @@
+package file
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dev11/pkg/models"
+	"dev11/pkg/repository"
+)
+
+func TestUserFileRepo_Replay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar.log")
+	date := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	repo, err := NewUserFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = repo.PutUser("2", models.NewUser("2")); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []models.Event{
+		{Id: "1", Name: "standup", Date: date},
+		{Id: "2", Name: "review", Date: date},
+	} {
+		if err = repo.PutUsersEvent("2", e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = repo.UpdateUsersEvent("2", models.Event{Id: "1", Name: "daily", Date: date}); err != nil {
+		t.Fatal(err)
+	}
+	if err = repo.DeleteUsersEvent("2", "2", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err = repo.DeleteUsersEvent("2", "3", 0); err == nil {
+		t.Fatal("expected error on deleting unknown event")
+	}
+	if err = repo.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// simulate a write interrupted by a crash
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.WriteString(`{"op":"put_event","userId":"2","ev`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	repo, err = NewUserFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := repo.GetUsersEvents("2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Name != "daily" || !events[0].Date.Equal(date) {
+		t.Errorf("GetUsersEvents() = %v, want single event \"daily\"", events)
+	}
+
+	if err = repo.PutUsersEvent("2", models.Event{Id: "3", Name: "retro"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = repo.Close(); err != nil {
+		t.Fatal(err)
+	}
+	repo, err = NewUserFile(path)
+	if err != nil {
+		t.Fatalf("log is corrupted after truncated record: %v", err)
+	}
+	defer repo.Close()
+	if events, _ = repo.GetUsersEvents("2"); len(events) != 2 {
+		t.Errorf("got %d events after reopening, want 2", len(events))
+	}
+}
+
+func TestUserFileRepo_Ping(t *testing.T) {
+	repo, err := NewUserFile(filepath.Join(t.TempDir(), "calendar.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = repo.Ping(); err != nil {
+		t.Fatalf("Ping() = %v on an open log", err)
+	}
+	if err = repo.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = repo.Ping(); err == nil {
+		t.Error("Ping() succeeded on a closed log")
+	}
+}
+
+// failingFile writes only the first half of what is written while fail is
+// set, as a full disk does.
+type failingFile struct {
+	logFile
+	fail bool
+}
+
+func (f *failingFile) Write(p []byte) (int, error) {
+	if !f.fail {
+		return f.logFile.Write(p)
+	}
+	n, _ := f.logFile.Write(p[:len(p)/2])
+	return n, errors.New("no space left on device")
+}
+
+func TestUserFileRepo_FailedWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar.log")
+	repo, err := NewUserFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = repo.CreateUsersEvent("1", models.Event{Id: "a", Name: "review"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = repo.TrashUsersEvent("1", "a", 0, models.TrashedEvent{}); err != nil {
+		t.Fatal(err)
+	}
+	file := &failingFile{logFile: repo.file, fail: true}
+	repo.file = file
+
+	if err = repo.CreateUsersEvent("1", models.Event{Id: "b", Name: "retro"}); err == nil {
+		t.Error("CreateUsersEvent() succeeded although the log is not written")
+	}
+	err = repo.UpdateUsersEvents("1", func(tx repository.EventsTx) error {
+		return tx.CreateEvent(models.Event{Id: "c", Name: "planning"})
+	})
+	if err == nil {
+		t.Error("UpdateUsersEvents() succeeded although the log is not written")
+	}
+	if _, err = repo.PurgeTrash(time.Now()); err == nil {
+		t.Error("PurgeTrash() succeeded although the log is not written")
+	}
+	if events, _ := repo.GetUsersEvents("1"); len(events) != 0 {
+		t.Errorf("GetUsersEvents() after failed writes = %+v, want none", events)
+	}
+	if trash, _ := repo.GetUsersTrash("1"); len(trash) != 1 {
+		t.Errorf("GetUsersTrash() after a failed purge = %+v, want the trashed event", trash)
+	}
+	if err = repo.Ping(); err != nil {
+		t.Errorf("Ping() = %v after the log is restored", err)
+	}
+
+	file.fail = false
+	if err = repo.CreateUsersEvent("1", models.Event{Id: "d", Name: "standup"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = repo.Close(); err != nil {
+		t.Fatal(err)
+	}
+	repo, err = NewUserFile(path)
+	if err != nil {
+		t.Fatalf("log is corrupted after failed writes: %v", err)
+	}
+	defer repo.Close()
+	if events, _ := repo.GetUsersEvents("1"); len(events) != 1 || events[0].Id != "d" {
+		t.Errorf("GetUsersEvents() after reopening = %+v, want only the event written in full", events)
+	}
+	if trash, _ := repo.GetUsersTrash("1"); len(trash) != 1 {
+		t.Errorf("GetUsersTrash() after reopening = %+v, want the trashed event", trash)
+	}
+}
+
+func TestUserFileRepo_ReplayGroups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar.log")
+	repo, err := NewUserFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range []models.Group{{Id: "team", Members: []string{"1"}}, {Id: "old"}} {
+		if err = repo.CreateGroup(g); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = repo.CreateGroup(models.Group{Id: "team"}); err == nil {
+		t.Fatal("expected error on creating existing group")
+	}
+	if _, err = repo.UpdateGroup("team", func(g *models.Group) error {
+		g.Name, g.Members = "Team", append(g.Members, "2")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = repo.DeleteGroup("old"); err != nil {
+		t.Fatal(err)
+	}
+	if err = repo.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err = NewUserFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	groups, err := repo.GetGroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].Name != "Team" || len(groups[0].Members) != 2 {
+		t.Errorf("GetGroups() after reopening = %+v, want team with 2 members", groups)
+	}
+}
+
+func TestUserFileRepo_ReplayTrash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar.log")
+	repo, err := NewUserFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deletedAt := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	for _, id := range []string{"kept", "restored", "purged", "trimmed"} {
+		event := models.Event{Id: id, Name: id}
+		if err = repo.CreateUsersEvent("1", event); err != nil {
+			t.Fatal(err)
+		}
+		if err = repo.AddAuditEntry("1", id, models.AuditEntry{Type: models.ChangeCreated, After: &event}); err != nil {
+			t.Fatal(err)
+		}
+		deletion := models.TrashedEvent{DeletedAt: deletedAt, PurgeAt: deletedAt.AddDate(0, 0, 30)}
+		switch id {
+		case "purged":
+			deletion.PurgeAt = deletedAt.AddDate(0, 0, 1)
+		case "trimmed":
+			deletion.DeletedAt = deletedAt.Add(-time.Hour)
+		}
+		if _, err = repo.TrashUsersEvent("1", id, 0, deletion); err != nil {
+			t.Fatal(err)
+		}
+		if err = repo.AddAuditEntry("1", id, models.AuditEntry{Type: models.ChangeDeleted, Before: &event}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = repo.RestoreUsersEvent("1", "restored"); err != nil {
+		t.Fatal(err)
+	}
+	purged, err := repo.PurgeTrash(deletedAt.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(purged["1"]) != 1 || purged["1"][0].Event.Id != "purged" {
+		t.Fatalf("PurgeTrash() = %+v, want the purged event", purged)
+	}
+	if n, err := repo.TrimUsersTrash("1", 1); err != nil || n != 1 {
+		t.Fatalf("TrimUsersTrash() = %d, %v, want the earlier deleted event removed", n, err)
+	}
+	if err = repo.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err = NewUserFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	trash, err := repo.GetUsersTrash("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash) != 1 || trash[0].Event.Id != "kept" || trash[0].Event.Name != "kept" || !trash[0].DeletedAt.Equal(deletedAt) {
+		t.Errorf("GetUsersTrash() after reopening = %+v, want the kept event", trash)
+	}
+	if event, err := repo.GetUsersEvent("1", "restored"); err != nil || event.Version != 2 {
+		t.Errorf("GetUsersEvent() of the restored event = %+v, %v", event, err)
+	}
+	for _, id := range []string{"purged", "trimmed"} {
+		if history, _ := repo.GetAuditEntries("1", id); len(history) != 0 {
+			t.Errorf("GetAuditEntries() of the %s event after reopening = %+v, want none", id, history)
+		}
+	}
+	history, err := repo.GetAuditEntries("1", "kept")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[1].Before == nil || history[1].Before.Name != "kept" {
+		t.Errorf("GetAuditEntries() of the kept event after reopening = %+v", history)
+	}
+}
+
+func TestUserFileRepo_ReplayBatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar.log")
+	repo, err := NewUserFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = repo.CreateUsersEvent("1", models.Event{Id: "a", Name: "review"}); err != nil {
+		t.Fatal(err)
+	}
+	err = repo.UpdateUsersEvents("1", func(tx repository.EventsTx) error {
+		if err := tx.CreateEvent(models.Event{Id: "b", Name: "retro"}); err != nil {
+			return err
+		}
+		// failed calls are not logged
+		if err := tx.CreateEvent(models.Event{Id: "a"}); err == nil {
+			t.Error("CreateEvent() of an existing event succeeded")
+		}
+		if _, err := tx.UpdateEvent(models.Event{Id: "b", Name: "retrospective", Version: 1}); err != nil {
+			return err
+		}
+		// neither are the calls undone by Atomic
+		err := tx.Atomic(func() error {
+			if err := tx.CreateEvent(models.Event{Id: "d"}); err != nil {
+				return err
+			}
+			return os.ErrInvalid
+		})
+		if err == nil {
+			t.Error("Atomic() of a failed function succeeded")
+		}
+		_, err = tx.TrashEvent("a", 1, models.TrashedEvent{DeletedBy: "1"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.UpdateUsersEvents("1", func(tx repository.EventsTx) error {
+		if err := tx.CreateEvent(models.Event{Id: "c"}); err != nil {
+			return err
+		}
+		return os.ErrInvalid
+	})
+	if err == nil {
+		t.Fatal("UpdateUsersEvents() of a failed transaction succeeded")
+	}
+	if err = repo.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err = NewUserFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	events, err := repo.GetUsersEvents("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Name != "retrospective" || events[0].Version != 2 {
+		t.Errorf("GetUsersEvents() after reopening = %+v", events)
+	}
+	if trash, _ := repo.GetUsersTrash("1"); len(trash) != 1 || trash[0].DeletedBy != "1" || trash[0].Event.Name != "review" {
+		t.Errorf("GetUsersTrash() after reopening = %+v", trash)
+	}
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"time"
+
+	"dev11/pkg/models"
+)
+
+// User is a storage of users, their events and groups of users.
+// Implementations are safe for concurrent use: every method is atomic and
+// values passed in and returned are deep copies which share no memory with
+// the stored ones.
+type User interface {
+	// CreateUser stores a new user and fails with models.ErrConflict if a
+	// user with the same id exists.
+	CreateUser(user models.User) error
+	// UpdateUser changes attributes of the user with update. Changes of the
+	// events made by update are ignored.
+	UpdateUser(id string, update func(user *models.User) error) (*models.User, error)
+	GetUser(id string) (*models.User, error)
+	GetUsers() ([]models.User, error)
+	// CountUsersEvents returns the number of users and the number of their
+	// events without copying them.
+	CountUsersEvents() (users, events int, err error)
+	GetUserByKeyHash(keyHash string) (*models.User, error)
+	DeleteUser(id string) error
+
+	// CreateUsersEvent stores a new event with version 1 and fails with
+	// models.ErrConflict if the user has an event with the same id.
+	CreateUsersEvent(userId string, event models.Event) error
+	// UpdateUsersEvent replaces an existing event and returns it with the
+	// incremented version. Unless event.Version is 0 it must be equal to the
+	// stored version, otherwise models.ErrPreconditionFailed is returned.
+	UpdateUsersEvent(userId string, event models.Event) (*models.Event, error)
+	// ModifyUsersEvent changes an existing event with modify and returns the
+	// result. No other change of the event can happen in between.
+	ModifyUsersEvent(userId, eventId string, modify func(event *models.Event) error) (*models.Event, error)
+	// DeleteUsersEvent deletes the event. Unless version is 0 it must be
+	// equal to the stored version as in UpdateUsersEvent.
+	DeleteUsersEvent(userId, eventId string, version int64) error
+	GetUsersEvent(userId, eventId string) (*models.Event, error)
+	GetUsersEvents(userId string) ([]models.Event, error)
+	// UpdateUsersEvents runs fn in a transaction over the events of the
+	// user. Changes made with tx are kept if fn returns nil and discarded
+	// otherwise. Other changes of the repository wait until fn returns, so
+	// fn must not call the repository.
+	UpdateUsersEvents(userId string, fn func(tx EventsTx) error) error
+	// SearchUsersEvents returns the events of the user with words of the
+	// name or the description starting with every word of text and with all
+	// of tags. Words and tags are compared case-insensitively. The index
+	// behind it is kept up to date by every change of the events.
+	SearchUsersEvents(userId, text string, tags []string) ([]models.Event, error)
+
+	// TrashUsersEvent moves the event to the trash of the user and returns
+	// deletion with the event set. Unless version is 0 it must be equal to
+	// the stored version as in UpdateUsersEvent. Methods of events do not
+	// see trashed events.
+	TrashUsersEvent(userId, eventId string, version int64, deletion models.TrashedEvent) (*models.TrashedEvent, error)
+	// RestoreUsersEvent moves the event from the trash back to the events
+	// of the user and returns it with the incremented version. It fails
+	// with models.ErrConflict if the user has an event with the same id.
+	RestoreUsersEvent(userId, eventId string) (*models.Event, error)
+	GetUsersTrashedEvent(userId, eventId string) (*models.TrashedEvent, error)
+	GetUsersTrash(userId string) ([]models.TrashedEvent, error)
+	// PurgeTrash removes the events with PurgeAt not after now from the
+	// trash of every user together with their histories and returns them by
+	// user id.
+	PurgeTrash(now time.Time) (map[string][]models.TrashedEvent, error)
+	// TrimUsersTrash removes the events from the trash of the user together
+	// with their histories except the n latest deleted ones, and returns the
+	// number of removed events. Events deleted at the same time are ordered
+	// by id.
+	TrimUsersTrash(userId string, n int) (int, error)
+
+	// AddAuditEntry appends the entry to the history of the event. Only the
+	// latest entries of a history are kept.
+	AddAuditEntry(userId, eventId string, entry models.AuditEntry) error
+	// GetAuditEntries returns the history of the event from the oldest
+	// entry; it is empty for unknown events.
+	GetAuditEntries(userId, eventId string) ([]models.AuditEntry, error)
+
+	// CreateGroup stores a new group and fails with models.ErrConflict if a
+	// group with the same id exists.
+	CreateGroup(group models.Group) error
+	// UpdateGroup changes the group with update and returns the result.
+	UpdateGroup(id string, update func(group *models.Group) error) (*models.Group, error)
+	GetGroup(id string) (*models.Group, error)
+	GetGroups() ([]models.Group, error)
+	DeleteGroup(id string) error
+}
+
+// EventsTx changes the events of a user within a transaction started by
+// User.UpdateUsersEvents. Its methods behave as the methods of User with
+// the same names, and a failed call changes nothing.
+type EventsTx interface {
+	GetEvents() ([]models.Event, error)
+	CreateEvent(event models.Event) error
+	UpdateEvent(event models.Event) (*models.Event, error)
+	TrashEvent(eventId string, version int64, deletion models.TrashedEvent) (*models.TrashedEvent, error)
+	GetTrash() ([]models.TrashedEvent, error)
+	RestoreEvent(eventId string) (*models.Event, error)
+	// Atomic calls fn and, if it fails, undoes the changes made with the
+	// transaction by fn, keeping the earlier ones.
+	Atomic(fn func() error) error
+}
+
+// Pinger is implemented by storages which can become unavailable, like
+// storages backed by files or remote databases.
+type Pinger interface {
+	// Ping returns an error if the storage can not serve requests.
+	Ping() error
+}
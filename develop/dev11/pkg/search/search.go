@@ -0,0 +1,169 @@
+// Package search implements an inverted index of documents with words
+// matched by prefix and tags matched exactly, both case-insensitively.
+package search
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Tokenize splits text into normalized words: maximal runs of letters and
+// digits.
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = Normalize(w)
+	}
+	return words
+}
+
+// Normalize folds the case of s with the Unicode rules, so it works for
+// Cyrillic as well as for Latin text. Ё is folded to Е since they are used
+// interchangeably in Russian.
+func Normalize(s string) string {
+	return strings.Map(func(r rune) rune {
+		r = unicode.ToLower(r)
+		if r == 'ё' {
+			return 'е'
+		}
+		return r
+	}, s)
+}
+
+type set map[string]struct{}
+
+type document struct {
+	words []string
+	tags  []string
+}
+
+// Index is an inverted index of documents by their words and tags. It is not
+// safe for concurrent use: searches may run in parallel, but not with
+// changes.
+type Index struct {
+	words map[string]set
+	// terms are the sorted keys of words, which makes prefixes of words
+	// ranges of terms.
+	terms []string
+	tags  map[string]set
+	docs  map[string]document
+}
+
+func NewIndex() *Index {
+	return &Index{words: make(map[string]set), tags: make(map[string]set), docs: make(map[string]document)}
+}
+
+// Add indexes the document with the words of text and with tags replacing
+// the document with the same id.
+func (x *Index) Add(id, text string, tags []string) {
+	x.Remove(id)
+	var doc document
+	for _, w := range Tokenize(text) {
+		if !slices.Contains(doc.words, w) {
+			doc.words = append(doc.words, w)
+			x.addWord(w, id)
+		}
+	}
+	for _, t := range tags {
+		t = Normalize(t)
+		if !slices.Contains(doc.tags, t) {
+			doc.tags = append(doc.tags, t)
+			addTo(x.tags, t, id)
+		}
+	}
+	x.docs[id] = doc
+}
+
+// Remove removes the document from the index.
+func (x *Index) Remove(id string) {
+	doc, found := x.docs[id]
+	if !found {
+		return
+	}
+	for _, w := range doc.words {
+		if removeFrom(x.words, w, id) {
+			i, _ := slices.BinarySearch(x.terms, w)
+			x.terms = slices.Delete(x.terms, i, i+1)
+		}
+	}
+	for _, t := range doc.tags {
+		removeFrom(x.tags, t, id)
+	}
+	delete(x.docs, id)
+}
+
+// Search returns sorted ids of the documents which have a word starting
+// with every word of text and all of tags. It returns nothing if text has
+// no words and tags are empty.
+func (x *Index) Search(text string, tags []string) []string {
+	var found set
+	for _, w := range Tokenize(text) {
+		matched := make(set)
+		for i := sort.SearchStrings(x.terms, w); i < len(x.terms) && strings.HasPrefix(x.terms[i], w); i++ {
+			for id := range x.words[x.terms[i]] {
+				matched[id] = struct{}{}
+			}
+		}
+		found = intersect(found, matched)
+	}
+	for _, t := range tags {
+		found = intersect(found, x.tags[Normalize(t)])
+	}
+	ids := make([]string, 0, len(found))
+	for id := range found {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func (x *Index) addWord(w, id string) {
+	if _, found := x.words[w]; !found {
+		i, _ := slices.BinarySearch(x.terms, w)
+		x.terms = slices.Insert(x.terms, i, w)
+	}
+	addTo(x.words, w, id)
+}
+
+func addTo(m map[string]set, key, id string) {
+	ids, found := m[key]
+	if !found {
+		ids = make(set)
+		m[key] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+// removeFrom removes id from the set of key and reports whether the set
+// became empty and was dropped.
+func removeFrom(m map[string]set, key, id string) bool {
+	ids := m[key]
+	delete(ids, id)
+	if len(ids) > 0 {
+		return false
+	}
+	delete(m, key)
+	return true
+}
+
+// intersect returns the elements of b which are in a, or b itself if a is
+// nil meaning no constraint yet.
+func intersect(a, b set) set {
+	if a == nil {
+		if b == nil {
+			return set{}
+		}
+		return b
+	}
+	result := make(set)
+	for id := range b {
+		if _, found := a[id]; found {
+			result[id] = struct{}{}
+		}
+	}
+	return result
+}
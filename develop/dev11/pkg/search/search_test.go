@@ -0,0 +1,75 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"Daily stand-up", []string{"daily", "stand", "up"}},
+		{"Встреча с КОМАНДОЙ, 10:00", []string{"встреча", "с", "командой", "10", "00"}},
+		{"Ёлка и ёжик", []string{"елка", "и", "ежик"}},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestIndex_Search(t *testing.T) {
+	x := NewIndex()
+	x.Add("1", "Планёрка отдела", []string{"Work"})
+	x.Add("2", "Plan the trip", []string{"personal", "travel"})
+	x.Add("3", "Планирование спринта", []string{"work"})
+
+	tests := []struct {
+		name string
+		text string
+		tags []string
+		want []string
+	}{
+		{"empty", "", nil, []string{}},
+		{"prefix", "план", nil, []string{"1", "3"}},
+		{"case", "ПЛАНЁРКА", nil, []string{"1"}},
+		{"latin", "pla", nil, []string{"2"}},
+		{"all words", "план спр", nil, []string{"3"}},
+		{"no match", "отпуск", nil, []string{}},
+		{"tag", "", []string{"WORK"}, []string{"1", "3"}},
+		{"tags", "", []string{"travel", "personal"}, []string{"2"}},
+		{"text and tag", "план", []string{"work"}, []string{"1", "3"}},
+		{"tag prefix", "", []string{"wor"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := x.Search(tt.text, tt.tags); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%q, %q) = %q, want %q", tt.text, tt.tags, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndex_AddRemove(t *testing.T) {
+	x := NewIndex()
+	x.Add("1", "review", []string{"work"})
+	x.Add("1", "retro", nil)
+	if got := x.Search("review", nil); len(got) != 0 {
+		t.Errorf("Search() of a replaced word = %q", got)
+	}
+	if got := x.Search("", []string{"work"}); len(got) != 0 {
+		t.Errorf("Search() of a replaced tag = %q", got)
+	}
+	if got := x.Search("re", nil); !slices.Equal(got, []string{"1"}) {
+		t.Errorf("Search() = %q, want [1]", got)
+	}
+	x.Remove("1")
+	x.Remove("1")
+	if len(x.terms) != 0 || len(x.words) != 0 || len(x.docs) != 0 {
+		t.Errorf("index is not empty after Remove(): %+v", x)
+	}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+
+	"dev11/pkg/models"
+)
+
+const (
+	apiKeyBytes    = 32
+	feedTokenBytes = 32
+	userIdBytes    = 8
+)
+
+// CreateUser stores a new user and returns it together with its API key.
+// The key is returned only once, only its hash is stored. An id is
+// generated if the user has none.
+func (s *Service) CreateUser(user models.User) (*models.User, string, error) {
+	if user.Id == "" {
+		id, err := randomHex(userIdBytes)
+		if err != nil {
+			return nil, "", err
+		}
+		user.Id = id
+	}
+	if strings.ContainsAny(user.Id, "/?#") {
+		return nil, "", models.Errorf(models.ErrValidation, "user id %q contains reserved characters", user.Id)
+	}
+	switch user.Role {
+	case "":
+		user.Role = models.RoleUser
+	case models.RoleUser, models.RoleAdmin:
+	default:
+		return nil, "", models.Errorf(models.ErrValidation, "unknown role %q", user.Role)
+	}
+	if user.TimeZone != "" {
+		if _, err := models.LoadLocation(user.TimeZone); err != nil {
+			return nil, "", models.NewError(models.ErrValidation, err)
+		}
+	}
+	key, err := randomHex(apiKeyBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	user.APIKeyHash = HashAPIKey(key)
+	user.Events = models.NewUser(user.Id).Events
+	if err = s.repo.CreateUser(user); err != nil {
+		return nil, "", err
+	}
+	return &user, key, nil
+}
+
+func (s *Service) GetUser(id string) (*models.User, error) {
+	return s.repo.GetUser(id)
+}
+
+// GetUsers returns all users ordered by id.
+func (s *Service) GetUsers() ([]models.User, error) {
+	users, err := s.repo.GetUsers()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Id < users[j].Id
+	})
+	return users, nil
+}
+
+// DeleteUser deletes the user with all its events.
+func (s *Service) DeleteUser(id string) error {
+	if err := s.repo.DeleteUser(id); err != nil {
+		return err
+	}
+	s.creating.Delete(id)
+	s.invitations.removeUser(id)
+	s.notify(id)
+	return nil
+}
+
+// Authenticate returns the user the API key belongs to.
+func (s *Service) Authenticate(apiKey string) (*models.User, error) {
+	if apiKey == "" {
+		return nil, models.Errorf(models.ErrUnauthorized, "API key is required")
+	}
+	user, err := s.repo.GetUserByKeyHash(HashAPIKey(apiKey))
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.Errorf(models.ErrUnauthorized, "invalid API key")
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// ResetFeedToken replaces the feed token of the user and returns it. Like the
+// API key, the token is returned only once, only its hash is stored.
+func (s *Service) ResetFeedToken(userId string) (string, error) {
+	token, err := randomHex(feedTokenBytes)
+	if err != nil {
+		return "", err
+	}
+	_, err = s.repo.UpdateUser(userId, func(user *models.User) error {
+		user.FeedTokenHash = HashAPIKey(token)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// AuthenticateFeed checks that the token is the feed token of the user. A
+// missing user is reported as an invalid token.
+func (s *Service) AuthenticateFeed(userId, token string) error {
+	if token == "" {
+		return models.Errorf(models.ErrUnauthorized, "feed token is required")
+	}
+	user, err := s.repo.GetUser(userId)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		return err
+	}
+	if user == nil || user.FeedTokenHash == "" ||
+		subtle.ConstantTimeCompare([]byte(HashAPIKey(token)), []byte(user.FeedTokenHash)) != 1 {
+		return models.Errorf(models.ErrUnauthorized, "invalid feed token")
+	}
+	return nil
+}
+
+// HashAPIKey returns the hex encoded SHA-256 of the key.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
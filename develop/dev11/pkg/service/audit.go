@@ -0,0 +1,37 @@
+package service
+
+import (
+	"log/slog"
+
+	"dev11/pkg/models"
+)
+
+// GetEventHistory returns the changes of the event from the oldest one. The
+// history outlives the event until it is removed from the trash, so it is
+// available for deleted events as well.
+func (s *Service) GetEventHistory(userId, eventId string) ([]models.AuditEntry, error) {
+	entries, err := s.repo.GetAuditEntries(userId, eventId)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		// events created before histories were kept
+		if _, err = s.repo.GetUsersEvent(userId, eventId); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// audit records the change made by the actor of the service in the history
+// of the event. Failures are only logged since the change itself is made.
+func (s *Service) audit(userId, eventId string, entry models.AuditEntry) {
+	entry.Actor = s.actor
+	if err := s.repo.AddAuditEntry(userId, eventId, entry); err != nil {
+		slog.ErrorContext(s.ctx, "failed to record change in the history of the event",
+			slog.String("user_id", userId),
+			slog.String("event_id", eventId),
+			slog.String("error", err.Error()),
+		)
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"dev11/pkg/logging"
+	"dev11/pkg/models"
+	"dev11/pkg/repository"
+	"dev11/pkg/repository/cache"
+)
+
+// failingAuditRepo fails to record changes in histories of events.
+type failingAuditRepo struct {
+	repository.User
+}
+
+func (failingAuditRepo) AddAuditEntry(string, string, models.AuditEntry) error {
+	return errors.New("disk is full")
+}
+
+func TestService_AuditFailureLog(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(logging.NewHandler(slog.NewJSONHandler(&buf, nil))))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	s := NewService(failingAuditRepo{cache.NewUserCache(cache.NewCache())})
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+	created, err := s.WithContext(ctx).As("1").CreateEvent("1", models.Event{Name: "a", Date: date(2024, 1, 1)})
+	if err != nil {
+		t.Fatalf("CreateEvent() error = %v, want the event created although its history is not", err)
+	}
+
+	var rec map[string]any
+	if err = json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	if rec["request_id"] != "req-1" || rec["event_id"] != created.Id || rec["error"] != "disk is full" {
+		t.Errorf("record %v, want the failure with the request id", rec)
+	}
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+
+	"dev11/pkg/models"
+	"dev11/pkg/repository"
+)
+
+// BatchError is returned by ApplyEvents when an operation of an atomic
+// batch fails.
+type BatchError struct {
+	// Index is the index of the failed operation.
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// ApplyEvents applies the operations to the events of the user in order
+// within one transaction of the repository, so no other change of the
+// events happens in between and every operation sees the previous ones,
+// e.g. when conflicts are checked. If atomic, either all the operations are
+// applied or none of them and BatchError of the first failed one is
+// returned. Otherwise the failed operations are skipped and their errors
+// are returned in the results. Deletions move events to the trash like
+// DeleteEvent.
+func (s *Service) ApplyEvents(userId string, ops []models.EventOperation, atomic bool) ([]models.OperationResult, error) {
+	user, err := s.repo.GetUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]models.OperationResult, len(ops))
+	prepared := make([]models.EventOperation, len(ops))
+	for i, op := range ops {
+		if prepared[i], err = s.prepareOperation(*user, op); err != nil {
+			if atomic {
+				return nil, &BatchError{Index: i, Err: err}
+			}
+			results[i].Err = err
+		}
+	}
+	if s.maxEvents > 0 {
+		defer s.lockCreating(userId)()
+	}
+
+	deletion := s.newDeletion()
+	var changes []eventChange
+	err = s.repo.UpdateUsersEvents(userId, func(tx repository.EventsTx) error {
+		events, err := tx.GetEvents()
+		if err != nil {
+			return err
+		}
+		for i, op := range prepared {
+			if results[i].Err != nil {
+				continue
+			}
+			var opChanges []eventChange
+			// a failed operation changes nothing even if it failed half way,
+			// e.g. when deleting the occurrences of a series
+			err := tx.Atomic(func() error {
+				var err error
+				results[i].Event, opChanges, err = s.applyOperation(tx, userId, events, op, deletion)
+				return err
+			})
+			if err != nil {
+				if atomic {
+					return &BatchError{Index: i, Err: err}
+				}
+				results[i].Err = err
+				continue
+			}
+			events = applyChanges(events, opChanges)
+			changes = append(changes, opChanges...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) > 0 {
+		s.notify(userId, changes...)
+		s.trimTrash(userId)
+	}
+	return results, nil
+}
+
+// prepareOperation validates the operation and prepares its event as
+// CreateEvent and UpdateEvent do, which needs the repository and so can not
+// be done within the transaction.
+func (s *Service) prepareOperation(user models.User, op models.EventOperation) (models.EventOperation, error) {
+	switch op.Type {
+	case models.OperationCreate, models.OperationUpdate:
+		if op.Type == models.OperationUpdate && op.Event.Id == "" {
+			return op, models.Errorf(models.ErrValidation, "id of the updated event is required")
+		}
+		event, err := validateEvent(op.Event)
+		if err != nil {
+			return op, err
+		}
+		if op.Type == models.OperationCreate {
+			event.Id, event.Version = "", 0
+			if event.TimeZone == "" && user.TimeZone != "" {
+				event.TimeZone = user.TimeZone
+			}
+		}
+		if err = s.prepareAttendees(user.Id, &event); err != nil {
+			return op, err
+		}
+		op.Event = event
+		return op, nil
+	case models.OperationDelete:
+		if op.EventId == "" {
+			return op, models.Errorf(models.ErrValidation, "id of the deleted event is required")
+		}
+		return op, nil
+	default:
+		return op, models.Errorf(models.ErrValidation, "unknown operation %q", op.Type)
+	}
+}
+
+// applyOperation applies the prepared operation within the transaction to
+// the events of the user, which are the events of tx, and returns the
+// resulting event and the changes to publish.
+func (s *Service) applyOperation(
+	tx repository.EventsTx,
+	userId string,
+	events []models.Event,
+	op models.EventOperation,
+	deletion models.TrashedEvent,
+) (*models.Event, []eventChange, error) {
+	var err error
+	switch op.Type {
+	case models.OperationCreate:
+		if s.maxEvents > 0 && len(events) >= s.maxEvents {
+			return nil, nil, s.quotaExceededError(userId)
+		}
+		if err = findConflicts(events, op.Event); err != nil {
+			return nil, nil, err
+		}
+		event, err := storeNewEvent(userId, op.Event, tx.CreateEvent)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &event, []eventChange{newChange(models.ChangeCreated, userId, event)}, nil
+	case models.OperationUpdate:
+		if err = findConflicts(events, op.Event); err != nil {
+			return nil, nil, err
+		}
+		updated, err := tx.UpdateEvent(op.Event)
+		if err != nil {
+			return nil, nil, err
+		}
+		// the event is found, since it is updated
+		i := slices.IndexFunc(events, func(v models.Event) bool { return v.Id == op.Event.Id })
+		return updated, []eventChange{updateChange(userId, events[i], *updated)}, nil
+	default:
+		trashed, err := tx.TrashEvent(op.EventId, op.Version, deletion)
+		if err != nil {
+			return nil, nil, err
+		}
+		changes := []eventChange{newChange(models.ChangeDeleted, userId, trashed.Event)}
+		if !trashed.Event.IsRecurring() {
+			return nil, changes, nil
+		}
+		for _, v := range events {
+			if v.SeriesId == op.EventId {
+				if _, err = tx.TrashEvent(v.Id, 0, deletion); err != nil {
+					return nil, nil, err
+				}
+				changes = append(changes, newChange(models.ChangeDeleted, userId, v))
+			}
+		}
+		return nil, changes, nil
+	}
+}
+
+// applyChanges returns the events with the changes applied, so that they stay
+// the events of the transaction the changes are made in without loading them
+// again.
+func applyChanges(events []models.Event, changes []eventChange) []models.Event {
+	for _, c := range changes {
+		i := slices.IndexFunc(events, func(v models.Event) bool { return v.Id == c.EventId })
+		switch {
+		case c.Type == models.ChangeDeleted:
+			if i >= 0 {
+				events = slices.Delete(events, i, i+1)
+			}
+		case i >= 0:
+			events[i] = *c.Event
+		default:
+			events = append(events, *c.Event)
+		}
+	}
+	return events
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"dev11/pkg/models"
+	"dev11/pkg/repository"
+	"dev11/pkg/repository/cache"
+)
+
+func TestService_ApplyEvents(t *testing.T) {
+	s := newTestService(t)
+	at := func(h int) time.Time { return time.Date(2024, 3, 4, h, 0, 0, 0, time.UTC) }
+	standup, err := s.CreateEvent("1", timed("standup", at(9), time.Hour, "FREQ=DAILY;COUNT=3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	review, err := s.CreateEvent("1", timed("review", at(14), time.Hour, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.UpdateOccurrence("1", standup.Id, at(9).AddDate(0, 0, 1), timed("late standup", at(11).AddDate(0, 0, 1), time.Hour, "")); err != nil {
+		t.Fatal(err)
+	}
+	names := func() []string {
+		events, _ := s.GetEvents("1")
+		names := eventNames(events)
+		slices.Sort(names)
+		return names
+	}
+
+	moved := review
+	moved.Date, moved.End = at(16), nil
+	ops := []models.EventOperation{
+		{Type: models.OperationDelete, EventId: standup.Id},
+		// the slot of the deleted series is free within the batch
+		{Type: models.OperationCreate, Event: timed("planning", at(9), time.Hour, "")},
+		{Type: models.OperationCreate, Event: timed("lunch", at(13), 2*time.Hour, "")},
+		{Type: models.OperationUpdate, Event: moved},
+	}
+	_, err = s.ApplyEvents("1", ops, true)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || batchErr.Index != 2 || !errors.Is(err, models.ErrConflict) {
+		t.Fatalf("ApplyEvents() of an atomic batch error = %v, want a conflict of operation 2", err)
+	}
+	if got, want := names(), []string{"late standup", "review", "standup"}; !slices.Equal(got, want) {
+		t.Errorf("events after a failed atomic batch = %q, want %q", got, want)
+	}
+
+	results, err := s.ApplyEvents("1", ops, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 || results[0].Err != nil || results[0].Event != nil ||
+		results[1].Err != nil || results[1].Event.Id == "" || results[1].Event.Version != 1 ||
+		!errors.Is(results[2].Err, models.ErrConflict) ||
+		results[3].Err != nil || results[3].Event.Version != review.Version+1 {
+		t.Fatalf("ApplyEvents() = %+v", results)
+	}
+	if got, want := names(), []string{"planning", "review"}; !slices.Equal(got, want) {
+		t.Errorf("events after a batch = %q, want %q", got, want)
+	}
+	if trash, _ := s.GetTrash("1"); len(trash) != 2 {
+		t.Errorf("GetTrash() after deleting the series in a batch = %+v, want the series and its occurrence", trash)
+	}
+	if history, _ := s.GetEventHistory("1", review.Id); len(history) != 2 || history[1].Type != models.ChangeUpdated ||
+		history[1].Before == nil || !history[1].Before.Date.Equal(review.Date) {
+		t.Errorf("GetEventHistory() of the updated event = %+v", history)
+	}
+
+	results, err = s.ApplyEvents("1", []models.EventOperation{
+		{Type: models.OperationUpdate, Event: models.Event{Id: review.Id, Name: "stale", Date: at(18), Version: review.Version}},
+		{Type: models.OperationDelete},
+		{Type: "move", EventId: review.Id},
+		{Type: models.OperationCreate, Event: models.Event{Name: "invalid", Date: at(9), RRule: "FREQ=NEVER"}},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantErrs := []error{models.ErrPreconditionFailed, models.ErrValidation, models.ErrValidation, models.ErrValidation}
+	for i, want := range wantErrs {
+		if !errors.Is(results[i].Err, want) {
+			t.Errorf("results[%d].Err = %v, want %v", i, results[i].Err, want)
+		}
+	}
+	if _, err = s.ApplyEvents("unknown", nil, true); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("ApplyEvents() of an unknown user error = %v, want %v", err, models.ErrNotFound)
+	}
+}
+
+func TestService_ApplyEventsMaxEvents(t *testing.T) {
+	s := newTestService(t, date(2024, 1, 1))
+	s.SetMaxEvents(3)
+	ops := make([]models.EventOperation, 3)
+	for i := range ops {
+		ops[i] = models.EventOperation{Type: models.OperationCreate, Event: models.Event{Name: "a", Date: date(2024, 2, i+1)}}
+	}
+
+	if _, err := s.ApplyEvents("1", ops, true); !errors.Is(err, models.ErrQuotaExceeded) {
+		t.Errorf("ApplyEvents() over the limit error = %v, want %v", err, models.ErrQuotaExceeded)
+	}
+	results, err := s.ApplyEvents("1", ops, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Err != nil || results[1].Err != nil || !errors.Is(results[2].Err, models.ErrQuotaExceeded) {
+		t.Errorf("ApplyEvents() = %+v, want the last operation over the limit", results)
+	}
+}
+
+// failingTrashRepo fails to trash and to restore the event with the id
+// within transactions.
+type failingTrashRepo struct {
+	repository.User
+	eventId string
+}
+
+func (r *failingTrashRepo) UpdateUsersEvents(userId string, fn func(tx repository.EventsTx) error) error {
+	return r.User.UpdateUsersEvents(userId, func(tx repository.EventsTx) error {
+		return fn(failingTrashTx{EventsTx: tx, eventId: r.eventId})
+	})
+}
+
+type failingTrashTx struct {
+	repository.EventsTx
+	eventId string
+}
+
+func (tx failingTrashTx) TrashEvent(eventId string, version int64, deletion models.TrashedEvent) (*models.TrashedEvent, error) {
+	if eventId == tx.eventId {
+		return nil, errors.New("disk is full")
+	}
+	return tx.EventsTx.TrashEvent(eventId, version, deletion)
+}
+
+func (tx failingTrashTx) RestoreEvent(eventId string) (*models.Event, error) {
+	if eventId == tx.eventId {
+		return nil, errors.New("disk is full")
+	}
+	return tx.EventsTx.RestoreEvent(eventId)
+}
+
+func TestService_ApplyEventsFailedOperation(t *testing.T) {
+	repo := &failingTrashRepo{User: cache.NewUserCache(cache.NewCache())}
+	s := NewService(repo)
+	at := func(h int) time.Time { return time.Date(2024, 3, 4, h, 0, 0, 0, time.UTC) }
+	standup, err := s.CreateEvent("1", timed("standup", at(9), time.Hour, "FREQ=DAILY;COUNT=3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	late, err := s.UpdateOccurrence("1", standup.Id, at(9).AddDate(0, 0, 1), timed("late standup", at(11).AddDate(0, 0, 1), time.Hour, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.eventId = late.Id
+
+	results, err := s.ApplyEvents("1", []models.EventOperation{
+		{Type: models.OperationDelete, EventId: standup.Id},
+		{Type: models.OperationCreate, Event: timed("planning", at(9), time.Hour, "")},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Err == nil || results[1].Err == nil {
+		t.Fatalf("ApplyEvents() = %+v, want the deletion of the series and the conflicting creation failed", results)
+	}
+	events, _ := s.GetEvents("1")
+	names := eventNames(events)
+	slices.Sort(names)
+	if want := []string{"late standup", "standup"}; !slices.Equal(names, want) {
+		t.Errorf("events after a failed deletion of a series = %q, want %q", names, want)
+	}
+	if trash, _ := s.GetTrash("1"); len(trash) != 0 {
+		t.Errorf("GetTrash() after a failed deletion of a series = %+v, want empty", trash)
+	}
+}
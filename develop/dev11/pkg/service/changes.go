@@ -0,0 +1,181 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"dev11/pkg/models"
+)
+
+const (
+	// defaultChangeLogSize is the number of the latest changes kept to be
+	// replayed to resuming subscribers.
+	defaultChangeLogSize = 1024
+	// subscriptionBuffer is the number of changes a subscriber may lag
+	// behind before it is dropped.
+	subscriptionBuffer = 64
+)
+
+// Subscription delivers changes of events of a user. Replay holds changes
+// published before the subscription which the subscriber missed. Reset is
+// set if some of them are no longer in the log and the subscriber has to
+// reload the events. C is closed when the subscription is closed or the
+// subscriber lags too far behind; it may resume from the last received
+// change then.
+type Subscription struct {
+	Replay []models.Change
+	Reset  bool
+	C      <-chan models.Change
+
+	c      chan models.Change
+	userId string
+	log    *changeLog
+}
+
+// Close stops the delivery of changes.
+func (s *Subscription) Close() {
+	s.log.unsubscribe(s)
+}
+
+// changeLog is a bounded log of changes which fans new changes out to
+// subscribers.
+type changeLog struct {
+	mu      sync.Mutex
+	lastId  uint64
+	changes []models.Change // ring buffer, changes[lastId%len] is the last one
+	size    int
+	subs    map[*Subscription]struct{}
+	closed  bool
+}
+
+func newChangeLog(size int) *changeLog {
+	return &changeLog{
+		changes: make([]models.Change, size),
+		size:    size,
+		subs:    make(map[*Subscription]struct{}),
+	}
+}
+
+func (l *changeLog) publish(c models.Change) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastId++
+	c.Id = l.lastId
+	l.changes[c.Id%uint64(l.size)] = c
+	for s := range l.subs {
+		if s.userId != c.UserId {
+			continue
+		}
+		select {
+		case s.c <- c:
+		default:
+			delete(l.subs, s)
+			close(s.c)
+		}
+	}
+}
+
+// subscribe returns a subscription to changes of the user. If lastId is not
+// nil, changes published after it are replayed.
+func (l *changeLog) subscribe(userId string, lastId *uint64) *Subscription {
+	c := make(chan models.Change, subscriptionBuffer)
+	s := &Subscription{C: c, c: c, userId: userId, log: l}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		close(c)
+		return s
+	}
+	l.subs[s] = struct{}{}
+	if lastId == nil {
+		return s
+	}
+	first := uint64(1)
+	if l.lastId > uint64(l.size) {
+		first = l.lastId - uint64(l.size) + 1
+	}
+	// the id is unknown after a restart
+	if *lastId+1 < first || *lastId > l.lastId {
+		s.Reset = true
+		return s
+	}
+	for id := *lastId + 1; id <= l.lastId; id++ {
+		if c := l.changes[id%uint64(l.size)]; c.UserId == userId {
+			s.Replay = append(s.Replay, c)
+		}
+	}
+	return s
+}
+
+func (l *changeLog) unsubscribe(s *Subscription) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.subs[s]; ok {
+		delete(l.subs, s)
+		close(s.c)
+	}
+}
+
+// close closes all subscriptions and refuses new ones.
+func (l *changeLog) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	for s := range l.subs {
+		delete(l.subs, s)
+		close(s.c)
+	}
+}
+
+// SubscribeChanges subscribes to changes of events of the user. If lastId is
+// not nil, changes published after the change with this id are replayed.
+func (s *Service) SubscribeChanges(userId string, lastId *uint64) (*Subscription, error) {
+	if _, err := s.repo.GetUser(userId); err != nil {
+		return nil, err
+	}
+	return s.changes.subscribe(userId, lastId), nil
+}
+
+// CloseSubscriptions closes all subscriptions to changes, so that streams of
+// changes end before the server shuts down.
+func (s *Service) CloseSubscriptions() {
+	s.changes.close()
+}
+
+// eventChange is a change with the event before it, which is recorded in
+// the history of the event but not published. It is taken within the same
+// modification of the repository as the change, so that concurrent changes
+// can not come in between.
+type eventChange struct {
+	models.Change
+	before *models.Event
+}
+
+// newChange returns the change of the event, which is the deleted event for
+// deletions.
+func newChange(t models.ChangeType, userId string, event models.Event) eventChange {
+	c := eventChange{Change: models.Change{Type: t, UserId: userId, EventId: event.Id, Time: time.Now().UTC()}}
+	if t == models.ChangeDeleted {
+		c.before = &event
+	} else {
+		c.Event = &event
+	}
+	return c
+}
+
+// updateChange returns the update of the event from before.
+func updateChange(userId string, before, event models.Event) eventChange {
+	c := newChange(models.ChangeUpdated, userId, event)
+	c.before = &before
+	return c
+}
+
+// keepBefore returns modify which first keeps a copy of the modified event
+// in before.
+func keepBefore(before *models.Event, modify func(event *models.Event) error) func(event *models.Event) error {
+	return func(event *models.Event) error {
+		*before = event.Clone()
+		return modify(event)
+	}
+}
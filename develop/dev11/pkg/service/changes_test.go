@@ -0,0 +1,128 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"dev11/pkg/models"
+)
+
+func changeTypes(changes []models.Change) []models.ChangeType {
+	types := make([]models.ChangeType, 0, len(changes))
+	for _, c := range changes {
+		types = append(types, c.Type)
+	}
+	return types
+}
+
+func TestService_SubscribeChanges(t *testing.T) {
+	s := newTestService(t)
+	sub, err := s.SubscribeChanges("1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	event, err := s.CreateEvent("1", models.Event{Name: "standup", Date: date(2024, 3, 4), RRule: "FREQ=DAILY"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	override, err := s.UpdateOccurrence("1", event.Id, date(2024, 3, 5), models.Event{Name: "moved", Date: date(2024, 3, 6)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.DeleteEvent("1", event.Id, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []models.Change
+	for len(got) < 5 {
+		got = append(got, <-sub.C)
+	}
+	want := []models.ChangeType{
+		models.ChangeCreated, models.ChangeCreated, models.ChangeUpdated, models.ChangeDeleted, models.ChangeDeleted,
+	}
+	if !reflect.DeepEqual(changeTypes(got), want) {
+		t.Fatalf("changes = %v, want %v", changeTypes(got), want)
+	}
+	if got[1].EventId != override.Id || got[4].EventId != override.Id || got[4].Event != nil {
+		t.Errorf("unexpected changes of the occurrence %+v, %+v", got[1], got[4])
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].Id <= got[i-1].Id {
+			t.Errorf("change ids %d, %d are not increasing", got[i-1].Id, got[i].Id)
+		}
+	}
+
+	// resuming replays the missed changes of the user only
+	resumed, err := s.SubscribeChanges("1", &got[2].Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resumed.Close()
+	if resumed.Reset || !reflect.DeepEqual(resumed.Replay, got[3:]) {
+		t.Errorf("replay = %+v, reset = %v, want %+v", resumed.Replay, resumed.Reset, got[3:])
+	}
+
+	if _, err = s.SubscribeChanges("2", nil); err == nil {
+		t.Error("subscribing to changes of an unknown user succeeded")
+	}
+}
+
+func TestChangeLog(t *testing.T) {
+	l := newChangeLog(3)
+	for i := 0; i < 5; i++ {
+		l.publish(models.Change{UserId: "1"})
+	}
+
+	tests := []struct {
+		name      string
+		lastId    uint64
+		wantIds   []uint64
+		wantReset bool
+	}{
+		{"up to date", 5, nil, false},
+		{"kept changes", 2, []uint64{3, 4, 5}, false},
+		{"truncated", 1, nil, true},
+		{"unknown id", 6, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := l.subscribe("1", &tt.lastId)
+			defer sub.Close()
+			var ids []uint64
+			for _, c := range sub.Replay {
+				ids = append(ids, c.Id)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIds) || sub.Reset != tt.wantReset {
+				t.Errorf("replayed %v, reset = %v, want %v, %v", ids, sub.Reset, tt.wantIds, tt.wantReset)
+			}
+		})
+	}
+
+	t.Run("slow subscriber is dropped", func(t *testing.T) {
+		sub := l.subscribe("1", nil)
+		for i := 0; i < subscriptionBuffer+1; i++ {
+			l.publish(models.Change{UserId: "1"})
+		}
+		n := 0
+		for range sub.C {
+			n++
+		}
+		if n != subscriptionBuffer {
+			t.Errorf("received %d changes, want %d", n, subscriptionBuffer)
+		}
+		sub.Close()
+	})
+
+	t.Run("close", func(t *testing.T) {
+		sub := l.subscribe("1", nil)
+		l.close()
+		if _, ok := <-sub.C; ok {
+			t.Error("subscription is not closed")
+		}
+		if _, ok := <-l.subscribe("1", nil).C; ok {
+			t.Error("subscribed to a closed log")
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"dev11/pkg/models"
+)
+
+// conflictHorizon limits how far occurrences of a recurring event are
+// checked for conflicts.
+const conflictHorizon = 365 * 24 * time.Hour
+
+// ConflictError is returned when an event overlaps other events of the user.
+type ConflictError struct {
+	Conflicts []models.Event
+}
+
+func (e *ConflictError) Error() string {
+	names := make([]string, 0, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		names = append(names, fmt.Sprintf("%q at %s", c.Name, c.Date.Format(time.RFC3339)))
+	}
+	return "event overlaps " + strings.Join(names, ", ")
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == models.ErrConflict
+}
+
+// GetFreeBusy returns merged busy intervals of the user and the free
+// intervals between them within [from, to).
+func (s *Service) GetFreeBusy(userId string, from, to time.Time) ([]models.Interval, []models.Interval, error) {
+	occurrences, err := s.getOverlappingOccurrences(userId, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	models.SortEvents(occurrences)
+
+	busy := make([]models.Interval, 0)
+	for _, o := range occurrences {
+		start, end := o.Date, *o.End
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if n := len(busy); n > 0 && !start.After(busy[n-1].End) {
+			if end.After(busy[n-1].End) {
+				busy[n-1].End = end
+			}
+			continue
+		}
+		busy = append(busy, models.Interval{Start: start, End: end})
+	}
+
+	free := make([]models.Interval, 0)
+	cur := from
+	for _, b := range busy {
+		if b.Start.After(cur) {
+			free = append(free, models.Interval{Start: cur, End: b.Start})
+		}
+		cur = b.End
+	}
+	if cur.Before(to) {
+		free = append(free, models.Interval{Start: cur, End: to})
+	}
+	return busy, free, nil
+}
+
+// getOverlappingOccurrences returns occurrences of the events of the user
+// which have an end and overlap [from, to).
+func (s *Service) getOverlappingOccurrences(userId string, from, to time.Time) ([]models.Event, error) {
+	userEvents, err := s.repo.GetUsersEvents(userId)
+	if err != nil {
+		return nil, err
+	}
+	return overlappingOccurrences(userEvents, from, to)
+}
+
+// overlappingOccurrences returns occurrences of the events which have an end
+// and overlap [from, to).
+func overlappingOccurrences(userEvents []models.Event, from, to time.Time) ([]models.Event, error) {
+	window := models.Event{Date: from, End: &to}
+	events := make([]models.Event, 0)
+	for _, v := range userEvents {
+		if v.End == nil {
+			continue
+		}
+		occurrences, err := v.Occurrences(from.Add(-v.Duration()), to)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range occurrences {
+			if o.Overlaps(window) {
+				events = append(events, o)
+			}
+		}
+	}
+	return events, nil
+}
+
+// findConflicts returns ConflictError if the event overlaps the other
+// events of the user. Occurrences of a series are not checked against each
+// other and against separately edited occurrences of the series.
+func findConflicts(userEvents []models.Event, event models.Event) error {
+	if event.End == nil {
+		return nil
+	}
+	occurrences, err := event.Occurrences(event.Date, event.Date.Add(conflictHorizon))
+	if err != nil || len(occurrences) == 0 {
+		return err
+	}
+	last := occurrences[len(occurrences)-1]
+	existing, err := overlappingOccurrences(userEvents, event.Date, *last.End)
+	if err != nil {
+		return err
+	}
+
+	conflicts := make([]models.Event, 0)
+	for _, e := range existing {
+		if event.Id != "" && (e.Id == event.Id || e.SeriesId == event.Id) {
+			continue
+		}
+		if event.SeriesId != "" && e.Id == event.SeriesId && e.RecurrenceId != nil &&
+			event.RecurrenceId != nil && e.RecurrenceId.Equal(*event.RecurrenceId) {
+			continue
+		}
+		for _, o := range occurrences {
+			if o.Overlaps(e) {
+				conflicts = append(conflicts, e)
+				break
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		models.SortEvents(conflicts)
+		return &ConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
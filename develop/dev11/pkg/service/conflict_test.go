@@ -0,0 +1,127 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"dev11/pkg/models"
+	"dev11/pkg/repository/cache"
+)
+
+func timed(name string, start time.Time, d time.Duration, rrule string) models.Event {
+	end := start.Add(d)
+	return models.Event{Name: name, Date: start, End: &end, RRule: rrule}
+}
+
+func TestService_CreateEventConflicts(t *testing.T) {
+	s := NewService(cache.NewUserCache(cache.NewCache()))
+	at := func(d, h int) time.Time { return time.Date(2024, 3, d, h, 0, 0, 0, time.UTC) }
+	for _, e := range []models.Event{
+		timed("standup", at(4, 10), time.Hour, "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR"),
+		timed("lunch", at(6, 13), time.Hour, ""),
+	} {
+		if _, err := s.CreateEvent("1", e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		event models.Event
+		want  []string
+	}{
+		{"free slot", timed("a", at(6, 11), 2*time.Hour, ""), nil},
+		{"adjacent", timed("b", at(6, 9), time.Hour, ""), nil},
+		{"overlaps lunch", timed("c", at(6, 13), 90*time.Minute, ""), []string{"lunch"}},
+		{"weekend", timed("d", at(9, 10), time.Hour, ""), nil},
+		{"weekly over standup", timed("e", at(11, 9), 2*time.Hour, "FREQ=WEEKLY;COUNT=3"), []string{"standup", "standup", "standup"}},
+		{"without end", models.Event{Name: "f", Date: at(6, 10)}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.CreateEvent("1", tt.event)
+			var conflict *ConflictError
+			if !errors.As(err, &conflict) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tt.want != nil {
+					t.Fatalf("expected conflict with %v", tt.want)
+				}
+				return
+			}
+			if names := eventNames(conflict.Conflicts); !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("conflicts = %v, want %v", names, tt.want)
+			}
+		})
+	}
+
+	if _, err := s.CreateEvent("1", timed("g", at(7, 10), 0, "")); err == nil {
+		t.Error("expected error for an event ending at its start")
+	}
+}
+
+func TestService_CreateEventConflictsConcurrently(t *testing.T) {
+	s := NewService(cache.NewUserCache(cache.NewCache()))
+	start := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.CreateEvent("1", timed("a", start.Add(time.Duration(i)*time.Minute), time.Hour, ""))
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		var conflict *ConflictError
+		switch {
+		case err == nil:
+			created++
+		case !errors.As(err, &conflict):
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+	if created != 1 {
+		t.Errorf("created %d overlapping events, want 1", created)
+	}
+}
+
+func TestService_GetFreeBusy(t *testing.T) {
+	s := NewService(cache.NewUserCache(cache.NewCache()))
+	at := func(h, m int) time.Time { return time.Date(2024, 3, 4, h, m, 0, 0, time.UTC) }
+	for _, e := range []models.Event{
+		timed("a", at(8, 0), 2*time.Hour, ""),
+		timed("b", at(10, 0), time.Hour, "FREQ=DAILY"),
+		timed("c", at(14, 0), 30*time.Minute, ""),
+	} {
+		if _, err := s.CreateEvent("1", e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	busy, free, err := s.GetFreeBusy("1", at(9, 0), at(18, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBusy := []models.Interval{{Start: at(9, 0), End: at(11, 0)}, {Start: at(14, 0), End: at(14, 30)}}
+	wantFree := []models.Interval{
+		{Start: at(11, 0), End: at(14, 0)},
+		{Start: at(14, 30), End: at(18, 0)},
+	}
+	if !reflect.DeepEqual(busy, wantBusy) {
+		t.Errorf("busy = %v, want %v", busy, wantBusy)
+	}
+	if !reflect.DeepEqual(free, wantFree) {
+		t.Errorf("free = %v, want %v", free, wantFree)
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+
+	"dev11/pkg/models"
+)
+
+// CreateGroup stores a new group of users. An id is generated if the group
+// has none.
+func (s *Service) CreateGroup(group models.Group) (*models.Group, error) {
+	if group.Id == "" {
+		id, err := randomHex(userIdBytes)
+		if err != nil {
+			return nil, err
+		}
+		group.Id = id
+	}
+	if strings.ContainsAny(group.Id, "/?#") {
+		return nil, models.Errorf(models.ErrValidation, "group id %q contains reserved characters", group.Id)
+	}
+	members, err := s.checkMembers(group.Members)
+	if err != nil {
+		return nil, err
+	}
+	group.Members = members
+	if err = s.repo.CreateGroup(group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// UpdateGroup replaces the name and the members of the group.
+func (s *Service) UpdateGroup(group models.Group) (*models.Group, error) {
+	members, err := s.checkMembers(group.Members)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateGroup(group.Id, func(stored *models.Group) error {
+		stored.Name, stored.Members = group.Name, members
+		return nil
+	})
+}
+
+func (s *Service) GetGroup(id string) (*models.Group, error) {
+	return s.repo.GetGroup(id)
+}
+
+// GetGroups returns all groups ordered by id.
+func (s *Service) GetGroups() ([]models.Group, error) {
+	groups, err := s.repo.GetGroups()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Id < groups[j].Id
+	})
+	return groups, nil
+}
+
+// DeleteGroup deletes the group. Calendars shared with it are no longer
+// readable by its members.
+func (s *Service) DeleteGroup(id string) error {
+	return s.repo.DeleteGroup(id)
+}
+
+// ShareCalendar makes the calendar of the user readable by the members of
+// the groups replacing the groups it was shared with before.
+func (s *Service) ShareCalendar(userId string, groupIds []string) (*models.User, error) {
+	shared := make([]string, 0, len(groupIds))
+	for _, id := range groupIds {
+		if slices.Contains(shared, id) {
+			continue
+		}
+		if _, err := s.repo.GetGroup(id); err != nil {
+			if errors.Is(err, models.ErrNotFound) {
+				return nil, models.NewError(models.ErrValidation, err)
+			}
+			return nil, err
+		}
+		shared = append(shared, id)
+	}
+	if len(shared) == 0 {
+		shared = nil
+	}
+	return s.repo.UpdateUser(userId, func(user *models.User) error {
+		user.SharedWith = shared
+		return nil
+	})
+}
+
+// CanRead reports whether the reader can read the calendar of the owner:
+// either it is the owner or a member of a group the calendar is shared
+// with.
+func (s *Service) CanRead(readerId, ownerId string) (bool, error) {
+	if readerId == ownerId {
+		return true, nil
+	}
+	owner, err := s.repo.GetUser(ownerId)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range owner.SharedWith {
+		group, err := s.repo.GetGroup(id)
+		if errors.Is(err, models.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if group.HasMember(readerId) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// checkMembers returns the members without duplicates and fails if any of
+// them does not exist.
+func (s *Service) checkMembers(members []string) ([]string, error) {
+	result := make([]string, 0, len(members))
+	for _, id := range members {
+		if slices.Contains(result, id) {
+			continue
+		}
+		if _, err := s.repo.GetUser(id); err != nil {
+			if errors.Is(err, models.ErrNotFound) {
+				return nil, models.Errorf(models.ErrValidation, "member with id = %s does not exist", id)
+			}
+			return nil, err
+		}
+		result = append(result, id)
+	}
+	return result, nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"dev11/pkg/models"
+)
+
+// ImportEvents stores events of an imported calendar and returns an error
+// for every event which failed to be stored, nil for the stored ones.
+// Events are matched with already stored ones by UID, so importing the same
+// calendar twice updates events instead of duplicating them. An event with
+// RecurrenceId replaces an occurrence of the series with the same UID.
+func (s *Service) ImportEvents(userId string, events []models.Event) ([]error, error) {
+	stored, err := s.repo.GetUsersEvents(userId)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]string, len(stored))
+	for _, e := range stored {
+		if e.UID != "" && e.SeriesId == "" {
+			ids[e.UID] = e.Id
+		}
+	}
+
+	errs := make([]error, len(events))
+	for i, e := range events {
+		if e.RecurrenceId != nil {
+			continue
+		}
+		if id, found := ids[e.UID]; found && e.UID != "" {
+			e.Id = id
+			_, errs[i] = s.UpdateEvent(userId, e)
+			continue
+		}
+		created, err := s.CreateEvent(userId, e)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		if e.UID != "" {
+			ids[e.UID] = created.Id
+		}
+	}
+
+	for i, e := range events {
+		if e.RecurrenceId == nil {
+			continue
+		}
+		seriesId, found := ids[e.UID]
+		if !found {
+			errs[i] = models.Errorf(models.ErrValidation, "failed to find series with uid = %s", e.UID)
+			continue
+		}
+		errs[i] = s.importOccurrence(userId, seriesId, e)
+	}
+	return errs, nil
+}
+
+func (s *Service) importOccurrence(userId, seriesId string, e models.Event) error {
+	stored, err := s.repo.GetUsersEvents(userId)
+	if err != nil {
+		return err
+	}
+	for _, v := range stored {
+		if v.SeriesId == seriesId && v.RecurrenceId != nil && v.RecurrenceId.Equal(*e.RecurrenceId) {
+			e.Id, e.UID, e.SeriesId, e.RecurrenceId = v.Id, "", v.SeriesId, v.RecurrenceId
+			_, err = s.UpdateEvent(userId, e)
+			return err
+		}
+	}
+
+	occurrence := *e.RecurrenceId
+	e.UID, e.RecurrenceId = "", nil
+	_, err = s.UpdateOccurrence(userId, seriesId, occurrence, e)
+	return err
+}
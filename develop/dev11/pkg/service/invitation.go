@@ -0,0 +1,240 @@
+package service
+
+import (
+	"errors"
+	"sync"
+
+	"dev11/pkg/models"
+	"dev11/pkg/repository"
+)
+
+// eventRef identifies an event by the id of its organizer and its id.
+type eventRef struct {
+	userId  string
+	eventId string
+}
+
+// invitationIndex maps attendees to the events of other users they are
+// invited to. It is loaded from the repository on first use and then kept
+// up to date from the published changes.
+type invitationIndex struct {
+	mu     sync.Mutex
+	loaded bool
+	// events are the events the attendee with the id is invited to.
+	events map[string]map[eventRef]struct{}
+	// attendees are the ids of the attendees of the event.
+	attendees map[eventRef][]string
+}
+
+func newInvitationIndex() *invitationIndex {
+	return &invitationIndex{
+		events:    make(map[string]map[eventRef]struct{}),
+		attendees: make(map[eventRef][]string),
+	}
+}
+
+// lookup returns the events the user is invited to.
+func (x *invitationIndex) lookup(repo repository.User, userId string) ([]eventRef, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if !x.loaded {
+		users, err := repo.GetUsers()
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			for _, e := range u.Events {
+				x.set(eventRef{u.Id, e.Id}, e.Attendees)
+			}
+		}
+		x.loaded = true
+	}
+
+	refs := make([]eventRef, 0, len(x.events[userId]))
+	for ref := range x.events[userId] {
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// update applies the change of an event to the index. Changes published
+// before the index is loaded are already in the repository it is loaded
+// from.
+func (x *invitationIndex) update(c models.Change) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if !x.loaded {
+		return
+	}
+	ref := eventRef{c.UserId, c.EventId}
+	if c.Type == models.ChangeDeleted || c.Event == nil {
+		x.set(ref, nil)
+		return
+	}
+	x.set(ref, c.Event.Attendees)
+}
+
+// removeUser removes the events organized by the user and its invitations.
+func (x *invitationIndex) removeUser(userId string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for ref := range x.attendees {
+		if ref.userId == userId {
+			x.set(ref, nil)
+		}
+	}
+	delete(x.events, userId)
+}
+
+// set replaces the attendees of the event. x.mu must be held.
+func (x *invitationIndex) set(ref eventRef, attendees []models.Attendee) {
+	for _, id := range x.attendees[ref] {
+		delete(x.events[id], ref)
+		if len(x.events[id]) == 0 {
+			delete(x.events, id)
+		}
+	}
+	delete(x.attendees, ref)
+	for _, a := range attendees {
+		if x.events[a.UserId] == nil {
+			x.events[a.UserId] = make(map[eventRef]struct{})
+		}
+		x.events[a.UserId][ref] = struct{}{}
+		x.attendees[ref] = append(x.attendees[ref], a.UserId)
+	}
+}
+
+// GetInvitations returns the events of other users the user is invited to
+// with any response, sorted by date.
+func (s *Service) GetInvitations(userId string) ([]models.Event, error) {
+	if _, err := s.repo.GetUser(userId); err != nil {
+		return nil, err
+	}
+	events, err := s.invitedEvents(userId)
+	if err != nil {
+		return nil, err
+	}
+	models.SortEvents(events)
+	return events, nil
+}
+
+// RespondToInvitation sets the status of the user in the attendees of the
+// event organized by another user and returns the event.
+func (s *Service) RespondToInvitation(
+	userId, organizerId, eventId string,
+	status models.AttendeeStatus,
+) (models.Event, error) {
+	if !status.IsValid() {
+		return models.Event{}, models.Errorf(models.ErrValidation, "unknown attendee status %q", status)
+	}
+	var before models.Event
+	event, err := s.repo.ModifyUsersEvent(organizerId, eventId, keepBefore(&before, func(event *models.Event) error {
+		attendee := event.Attendee(userId)
+		if attendee == nil {
+			return models.Errorf(models.ErrNotFound,
+				"user with id = %s is not invited to event with id = %s", userId, eventId)
+		}
+		attendee.Status = status
+		return nil
+	}))
+	if err != nil {
+		return models.Event{}, err
+	}
+	s.notify(organizerId, updateChange(organizerId, before, *event))
+	return *event, nil
+}
+
+// invitedEvents returns the events the user is invited to. Events deleted
+// concurrently are skipped.
+func (s *Service) invitedEvents(userId string) ([]models.Event, error) {
+	refs, err := s.invitations.lookup(s.repo, userId)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]models.Event, 0, len(refs))
+	for _, ref := range refs {
+		event, err := s.repo.GetUsersEvent(ref.userId, ref.eventId)
+		if errors.Is(err, models.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if event.Attendee(userId) != nil {
+			events = append(events, *event)
+		}
+	}
+	return events, nil
+}
+
+// prepareAttendees makes the user the organizer of the event and checks its
+// attendees. Statuses of the attendees are kept from the stored event, new
+// attendees have not responded yet: only attendees can change their status.
+// New attendees must be users the organizer can invite, see canInvite.
+func (s *Service) prepareAttendees(userId string, event *models.Event) error {
+	event.Organizer = userId
+	if len(event.Attendees) == 0 {
+		event.Attendees = nil
+		return nil
+	}
+
+	var stored *models.Event
+	if event.Id != "" {
+		var err error
+		if stored, err = s.repo.GetUsersEvent(userId, event.Id); err != nil && !errors.Is(err, models.ErrNotFound) {
+			return err
+		}
+	}
+	attendees := make([]models.Attendee, 0, len(event.Attendees))
+	seen := make(map[string]bool, len(event.Attendees))
+	for _, a := range event.Attendees {
+		if a.UserId == userId || seen[a.UserId] {
+			continue
+		}
+		seen[a.UserId] = true
+		var prev *models.Attendee
+		if stored != nil {
+			prev = stored.Attendee(a.UserId)
+		}
+		status := models.StatusNeedsAction
+		if prev != nil {
+			status = prev.Status
+		} else if ok, err := s.canInvite(userId, a.UserId); err != nil {
+			return err
+		} else if !ok {
+			// unknown users are not told apart from others
+			return models.Errorf(models.ErrValidation, "user with id = %s can not be invited", a.UserId)
+		}
+		attendees = append(attendees, models.Attendee{UserId: a.UserId, Status: status})
+	}
+	if len(attendees) == 0 {
+		attendees = nil
+	}
+	event.Attendees = attendees
+	return nil
+}
+
+// canInvite reports whether the organizer can invite the user to events:
+// they are members of the same group or one of them can read the calendar
+// of the other. Unknown users can not be invited.
+func (s *Service) canInvite(organizerId, userId string) (bool, error) {
+	for _, ids := range [][2]string{{organizerId, userId}, {userId, organizerId}} {
+		ok, err := s.CanRead(ids[0], ids[1])
+		if errors.Is(err, models.ErrNotFound) {
+			return false, nil
+		}
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	groups, err := s.repo.GetGroups()
+	if err != nil {
+		return false, err
+	}
+	for _, g := range groups {
+		if g.HasMember(organizerId) && g.HasMember(userId) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
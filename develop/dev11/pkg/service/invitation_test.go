@@ -0,0 +1,207 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"dev11/pkg/models"
+)
+
+func TestService_Invitations(t *testing.T) {
+	s := newTestService(t)
+	for _, id := range []string{"alice", "bob", "carol", "dave"} {
+		if _, _, err := s.CreateUser(models.User{Id: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.CreateGroup(models.Group{Id: "team", Members: []string{"alice", "bob", "carol"}}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	// created before the index is loaded
+	review, err := s.CreateEvent("alice", models.Event{
+		Name: "review", Date: start, End: &end,
+		Attendees: []models.Attendee{{UserId: "bob", Status: models.StatusAccepted}, {UserId: "alice"}, {UserId: "bob"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if review.Organizer != "alice" || !reflect.DeepEqual(review.Attendees, []models.Attendee{{UserId: "bob", Status: models.StatusNeedsAction}}) {
+		t.Fatalf("CreateEvent() = %+v", review)
+	}
+	// unknown users can not be told apart from users without a group or a
+	// calendar shared with the organizer
+	var messages []string
+	for _, id := range []string{"nobody", "dave"} {
+		_, err = s.CreateEvent("alice", models.Event{Name: "x", Date: start, Attendees: []models.Attendee{{UserId: id}}})
+		if !errors.Is(err, models.ErrValidation) {
+			t.Fatalf("CreateEvent() inviting %s error = %v, want %v", id, err, models.ErrValidation)
+		}
+		messages = append(messages, strings.Replace(err.Error(), id, "{id}", 1))
+	}
+	if messages[0] != messages[1] {
+		t.Errorf("errors of inviting an unknown user and a stranger differ: %q", messages)
+	}
+
+	dayOf := func(userId string) []string {
+		t.Helper()
+		events, err := s.GetEventsForDay(userId, start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return eventNames(events)
+	}
+	if got := dayOf("bob"); !reflect.DeepEqual(got, []string{"review"}) {
+		t.Errorf("day of bob = %v, want [review]", got)
+	}
+
+	if _, err = s.RespondToInvitation("carol", "alice", review.Id, models.StatusAccepted); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("RespondToInvitation() of not invited user error = %v, want %v", err, models.ErrNotFound)
+	}
+	if _, err = s.RespondToInvitation("bob", "alice", review.Id, "maybe"); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("RespondToInvitation() with unknown status error = %v, want %v", err, models.ErrValidation)
+	}
+	if _, err = s.RespondToInvitation("bob", "alice", review.Id, models.StatusDeclined); err != nil {
+		t.Fatal(err)
+	}
+	if got := dayOf("bob"); len(got) != 0 {
+		t.Errorf("day of bob after declining = %v, want []", got)
+	}
+
+	// the organizer invites carol too, the response of bob is kept
+	stored, err := s.GetEvent("alice", review.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored.Attendees = append(stored.Attendees, models.Attendee{UserId: "carol", Status: models.StatusAccepted})
+	updated, err := s.UpdateEvent("alice", *stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.Attendee{{UserId: "bob", Status: models.StatusDeclined}, {UserId: "carol", Status: models.StatusNeedsAction}}
+	if !reflect.DeepEqual(updated.Attendees, want) {
+		t.Errorf("attendees = %+v, want %+v", updated.Attendees, want)
+	}
+	if got := dayOf("carol"); !reflect.DeepEqual(got, []string{"review"}) {
+		t.Errorf("day of carol = %v, want [review]", got)
+	}
+	invitations, err := s.GetInvitations("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := eventNames(invitations); !reflect.DeepEqual(got, []string{"review"}) {
+		t.Errorf("invitations of bob = %v, want [review]", got)
+	}
+
+	if err = s.DeleteEvent("alice", review.Id, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := dayOf("carol"); len(got) != 0 {
+		t.Errorf("day of carol after deletion = %v, want []", got)
+	}
+	if _, err = s.GetInvitations("nobody"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("GetInvitations() of unknown user error = %v, want %v", err, models.ErrNotFound)
+	}
+}
+
+func TestService_ShareCalendar(t *testing.T) {
+	s := newTestService(t)
+	for _, id := range []string{"alice", "bob", "carol"} {
+		if _, _, err := s.CreateUser(models.User{Id: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.CreateGroup(models.Group{Id: "team", Members: []string{"bob", "nobody"}}); !errors.Is(err, models.ErrValidation) {
+		t.Fatalf("CreateGroup() with unknown member error = %v, want %v", err, models.ErrValidation)
+	}
+	group, err := s.CreateGroup(models.Group{Id: "team", Members: []string{"bob", "bob"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(group.Members, []string{"bob"}) {
+		t.Errorf("members = %v, want [bob]", group.Members)
+	}
+	if _, err = s.ShareCalendar("alice", []string{"unknown"}); !errors.Is(err, models.ErrValidation) {
+		t.Fatalf("ShareCalendar() with unknown group error = %v, want %v", err, models.ErrValidation)
+	}
+	if _, err = s.ShareCalendar("alice", []string{"team"}); err != nil {
+		t.Fatal(err)
+	}
+
+	canRead := func(reader string) bool {
+		t.Helper()
+		ok, err := s.CanRead(reader, "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+	if !canRead("alice") || !canRead("bob") || canRead("carol") {
+		t.Errorf("CanRead() of alice, bob, carol = %v, %v, %v, want true, true, false",
+			canRead("alice"), canRead("bob"), canRead("carol"))
+	}
+	if _, err = s.UpdateGroup(models.Group{Id: "team", Members: []string{"carol"}}); err != nil {
+		t.Fatal(err)
+	}
+	if canRead("bob") || !canRead("carol") {
+		t.Error("membership change is not applied")
+	}
+	if err = s.DeleteGroup("team"); err != nil {
+		t.Fatal(err)
+	}
+	if canRead("carol") {
+		t.Error("calendar is readable after the group is deleted")
+	}
+}
+
+func TestService_InvitationsOfSharedCalendar(t *testing.T) {
+	s := newTestService(t)
+	for _, id := range []string{"alice", "bob", "carol"} {
+		if _, _, err := s.CreateUser(models.User{Id: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, g := range []models.Group{{Id: "team", Members: []string{"alice", "bob"}}, {Id: "readers", Members: []string{"carol"}}} {
+		if _, err := s.CreateGroup(g); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.ShareCalendar("bob", []string{"readers"}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	if _, err := s.CreateEvent("alice", models.Event{Name: "review", Date: start, Attendees: []models.Attendee{{UserId: "bob"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateEvent("bob", models.Event{Name: "lunch", Date: start.Add(3 * time.Hour), Attendees: []models.Attendee{{UserId: "carol"}}}); err != nil {
+		t.Errorf("CreateEvent() inviting a reader of the calendar error = %v", err)
+	}
+	if _, err := s.CreateEvent("alice", models.Event{Name: "x", Date: start, Attendees: []models.Attendee{{UserId: "carol"}}}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("CreateEvent() inviting a stranger error = %v, want %v", err, models.ErrValidation)
+	}
+
+	tests := []struct {
+		name    string
+		service User
+		want    []string
+	}{
+		{"owner", s.As("bob"), []string{"review", "lunch"}},
+		{"admin", s, []string{"review", "lunch"}},
+		{"reader", s.As("carol"), []string{"lunch"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := tt.service.GetEventsForDay("bob", start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := eventNames(events); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("day of bob = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"time"
+
+	"dev11/pkg/models"
+)
+
+// GetOccurrence returns the occurrence of the series starting at occurrence.
+func (s *Service) GetOccurrence(userId, eventId string, occurrence time.Time) (*models.Event, error) {
+	series, err := s.getSeriesOccurrence(userId, eventId, occurrence)
+	if err != nil {
+		return nil, err
+	}
+	events, err := series.Occurrences(occurrence, occurrence.Add(time.Nanosecond))
+	if err != nil {
+		return nil, err
+	}
+	return &events[0], nil
+}
+
+// UpdateOccurrence detaches a single occurrence from the series: it is
+// excluded from the series and stored as a separate event, which is
+// returned.
+func (s *Service) UpdateOccurrence(
+	userId, eventId string,
+	occurrence time.Time,
+	event models.Event,
+) (models.Event, error) {
+	series, err := s.getSeriesOccurrence(userId, eventId, occurrence)
+	if err != nil {
+		return models.Event{}, err
+	}
+	if event.IsRecurring() {
+		return models.Event{}, models.Errorf(models.ErrValidation, "an occurrence of a series can not be recurring")
+	}
+
+	event.SeriesId = series.Id
+	event.RecurrenceId = &occurrence
+	created, err := s.CreateEvent(userId, event)
+	if err != nil {
+		return models.Event{}, err
+	}
+	var before models.Event
+	series, err = s.repo.ModifyUsersEvent(userId, series.Id, keepBefore(&before, excludeOccurrence(occurrence)))
+	if err != nil {
+		// the occurrence was excluded concurrently
+		if s.repo.DeleteUsersEvent(userId, created.Id, 0) == nil {
+			s.notify(userId, newChange(models.ChangeDeleted, userId, created))
+		}
+		return models.Event{}, err
+	}
+	s.notify(userId, updateChange(userId, before, *series))
+	return created, nil
+}
+
+// DeleteOccurrence excludes a single occurrence from the series.
+func (s *Service) DeleteOccurrence(userId, eventId string, occurrence time.Time) error {
+	var before models.Event
+	series, err := s.repo.ModifyUsersEvent(userId, eventId, keepBefore(&before, excludeOccurrence(occurrence)))
+	if err != nil {
+		return err
+	}
+	s.notify(userId, updateChange(userId, before, *series))
+	return nil
+}
+
+// excludeOccurrence returns a modification of a series which excludes the
+// occurrence from it.
+func excludeOccurrence(occurrence time.Time) func(series *models.Event) error {
+	return func(series *models.Event) error {
+		if err := checkOccurrence(series, occurrence); err != nil {
+			return err
+		}
+		series.ExDates = append(series.ExDates, occurrence)
+		return nil
+	}
+}
+
+func (s *Service) getSeriesOccurrence(
+	userId, eventId string,
+	occurrence time.Time,
+) (*models.Event, error) {
+	series, err := s.repo.GetUsersEvent(userId, eventId)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkOccurrence(series, occurrence); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+func checkOccurrence(series *models.Event, occurrence time.Time) error {
+	found, err := series.HasOccurrence(occurrence)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return models.Errorf(models.ErrNotFound,
+			"failed to find occurrence at %s of event with id = %s",
+			occurrence.Format(time.RFC3339), series.Id)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"dev11/pkg/models"
+)
+
+// newSeriesTestService returns a service with a daily standup of alice
+// from 9 to 10 on 4 to 6 March 2024 and the series.
+func newSeriesTestService(t *testing.T) (*Service, models.Event) {
+	t.Helper()
+	s := newTestService(t)
+	if _, _, err := s.CreateUser(models.User{Id: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	series, err := s.CreateEvent("alice", models.Event{Name: "standup", Date: start, End: &end, RRule: "FREQ=DAILY;COUNT=3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, series
+}
+
+func TestService_UpdateOccurrence(t *testing.T) {
+	s, series := newSeriesTestService(t)
+	second := series.Date.AddDate(0, 0, 1)
+	movedEnd := second.Add(3 * time.Hour)
+	moved, err := s.UpdateOccurrence("alice", series.Id, second, models.Event{Name: "moved", Date: second.Add(2 * time.Hour), End: &movedEnd})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.SeriesId != series.Id || moved.RecurrenceId == nil || !moved.RecurrenceId.Equal(second) {
+		t.Errorf("UpdateOccurrence() = %+v, want an occurrence of %s at %s", moved, series.Id, second)
+	}
+	stored, err := s.GetEvent("alice", series.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.ExDates) != 1 || !stored.ExDates[0].Equal(second) {
+		t.Errorf("ExDates of the series = %v, want [%s]", stored.ExDates, second)
+	}
+	events, err := s.GetEventsInRange("alice", series.Date, series.Date.AddDate(0, 0, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := eventNames(events), []string{"standup", "moved", "standup"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetEventsInRange() = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name       string
+		eventId    string
+		occurrence time.Time
+		event      models.Event
+		want       error
+	}{
+		{"detached occurrence", series.Id, second, models.Event{Name: "again", Date: second}, models.ErrNotFound},
+		{"not an occurrence", series.Id, second.Add(time.Minute), models.Event{Name: "x", Date: second}, models.ErrNotFound},
+		{"after the series", series.Id, series.Date.AddDate(0, 0, 3), models.Event{Name: "x", Date: second}, models.ErrNotFound},
+		{"unknown series", "unknown", second, models.Event{Name: "x", Date: second}, models.ErrNotFound},
+		{"recurring occurrence", series.Id, series.Date, models.Event{Name: "x", Date: series.Date, RRule: "FREQ=DAILY"}, models.ErrValidation},
+		{"conflict", series.Id, series.Date, models.Event{Name: "x", Date: second.Add(2 * time.Hour), End: &movedEnd}, models.ErrConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.UpdateOccurrence("alice", tt.eventId, tt.occurrence, tt.event); !errors.Is(err, tt.want) {
+				t.Errorf("UpdateOccurrence() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if events, _ := s.GetEvents("alice"); len(events) != 2 {
+		t.Errorf("GetEvents() after failed updates = %+v, want the series and the moved occurrence", events)
+	}
+}
+
+func TestService_DeleteOccurrence(t *testing.T) {
+	s, series := newSeriesTestService(t)
+	first := series.Date
+	if err := s.DeleteOccurrence("alice", series.Id, first); err != nil {
+		t.Fatal(err)
+	}
+	events, err := s.GetEventsInRange("alice", series.Date, series.Date.AddDate(0, 0, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || !events[0].Date.Equal(first.AddDate(0, 0, 1)) {
+		t.Errorf("GetEventsInRange() = %+v, want the second and the third occurrence", events)
+	}
+
+	tests := []struct {
+		name       string
+		eventId    string
+		occurrence time.Time
+	}{
+		{"deleted occurrence", series.Id, first},
+		{"not an occurrence", series.Id, first.Add(time.Hour)},
+		{"unknown series", "unknown", first},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.DeleteOccurrence("alice", tt.eventId, tt.occurrence); !errors.Is(err, models.ErrNotFound) {
+				t.Errorf("DeleteOccurrence() error = %v, want %v", err, models.ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestService_DeleteSeries(t *testing.T) {
+	s, series := newSeriesTestService(t)
+	second := series.Date.AddDate(0, 0, 1)
+	if _, err := s.UpdateOccurrence("alice", series.Id, second, models.Event{Name: "moved", Date: second.Add(2 * time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateEvent("alice", models.Event{Name: "lunch", Date: second.Add(4 * time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.DeleteEvent("alice", series.Id, 0); err != nil {
+		t.Fatal(err)
+	}
+	events, err := s.GetEvents("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := eventNames(events); !reflect.DeepEqual(got, []string{"lunch"}) {
+		t.Errorf("GetEvents() after deleting the series = %v, want [lunch]", got)
+	}
+	trash, err := s.GetTrash("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash) != 2 {
+		t.Errorf("GetTrash() = %+v, want the series and its moved occurrence", trash)
+	}
+}
+
+func TestService_OccurrencesBeyondLimit(t *testing.T) {
+	s, series := newSeriesTestService(t)
+	if _, err := s.CreateEvent("alice", models.Event{Name: "daily", Date: series.Date, RRule: "FREQ=DAILY"}); err != nil {
+		t.Fatal(err)
+	}
+
+	from := series.Date.AddDate(300, 0, 0)
+	_, err := s.GetEventsInRange("alice", from, from.AddDate(0, 0, 1))
+	if !errors.Is(err, models.ErrTooManyPeriods) || !errors.Is(err, models.ErrValidation) {
+		t.Errorf("GetEventsInRange() error = %v, want %v", err, models.ErrTooManyPeriods)
+	}
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"dev11/pkg/models"
+	"dev11/pkg/search"
+)
+
+// SearchEvents returns the events of the user matching the query sorted by
+// date. With a time range recurring events are expanded into their
+// occurrences within it, otherwise series are returned as a whole.
+func (s *Service) SearchEvents(userId string, query models.SearchQuery) ([]models.Event, error) {
+	if len(search.Tokenize(query.Text)) == 0 && len(query.Tags) == 0 {
+		return nil, models.Errorf(models.ErrValidation, "search query must have words or tags")
+	}
+	if query.HasRange() {
+		if query.From.IsZero() || query.To.IsZero() {
+			return nil, models.Errorf(models.ErrValidation, "both ends of the search range must be set")
+		}
+		if !query.To.After(query.From) {
+			return nil, models.Errorf(models.ErrValidation, "end of the search range must be after its start")
+		}
+	}
+	matched, err := s.repo.SearchUsersEvents(userId, query.Text, query.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if !query.HasRange() {
+		models.SortEvents(matched)
+		return matched, nil
+	}
+	events := make([]models.Event, 0)
+	for _, v := range matched {
+		occurrences, err := v.Occurrences(query.From, query.To)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, occurrences...)
+	}
+	models.SortEvents(events)
+	return events, nil
+}
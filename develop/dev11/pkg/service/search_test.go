@@ -0,0 +1,69 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"dev11/pkg/models"
+)
+
+func TestService_SearchEvents(t *testing.T) {
+	s := newTestService(t)
+	for _, e := range []models.Event{
+		{Name: "Планёрка", Date: date(2024, 3, 4), RRule: "FREQ=WEEKLY;COUNT=4", Tags: []string{"Work", " work "}},
+		{Name: "План отпуска", Date: date(2024, 3, 1), Tags: []string{"personal"}},
+		{Name: "Retro", Description: "планирование", Date: date(2024, 3, 20), Tags: []string{"work"}},
+	} {
+		if _, err := s.CreateEvent("1", e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query models.SearchQuery
+		want  []string
+	}{
+		{"prefix", models.SearchQuery{Text: "ПЛАН"}, []string{"План отпуска", "Планёрка", "Retro"}},
+		{"words", models.SearchQuery{Text: "план отп"}, []string{"План отпуска"}},
+		{"tag", models.SearchQuery{Tags: []string{"WORK"}}, []string{"Планёрка", "Retro"}},
+		{"text and tag", models.SearchQuery{Text: "планерка", Tags: []string{"work"}}, []string{"Планёрка"}},
+		{
+			"range",
+			models.SearchQuery{Tags: []string{"work"}, From: date(2024, 3, 10), To: date(2024, 3, 21)},
+			[]string{"Планёрка", "Планёрка", "Retro"},
+		},
+		{"nothing", models.SearchQuery{Text: "review"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := s.SearchEvents("1", tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := eventNames(events); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SearchEvents() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	events, _ := s.SearchEvents("1", models.SearchQuery{Text: "планёрка"})
+	if len(events) != 1 || !reflect.DeepEqual(events[0].Tags, []string{"Work"}) {
+		t.Errorf("tags of the created event = %+v, want duplicates dropped", events)
+	}
+
+	for _, q := range []models.SearchQuery{
+		{},
+		{Text: "  !"},
+		{Text: "план", From: date(2024, 3, 1)},
+		{Text: "план", From: date(2024, 3, 2), To: date(2024, 3, 1)},
+	} {
+		if _, err := s.SearchEvents("1", q); !errors.Is(err, models.ErrValidation) {
+			t.Errorf("SearchEvents(%+v) error = %v, want %v", q, err, models.ErrValidation)
+		}
+	}
+	if _, err := s.CreateEvent("1", models.Event{Name: "x", Date: date(2024, 3, 1), Tags: []string{" "}}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("CreateEvent() with an empty tag error = %v, want %v", err, models.ErrValidation)
+	}
+}
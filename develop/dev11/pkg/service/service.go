@@ -1,25 +1,160 @@
 package service
 
 import (
+	"context"
+	"sync"
 	"time"
 
 	"dev11/pkg/models"
-	"dev11/pkg/repository/cache"
+	"dev11/pkg/repository"
 )
 
 type User interface {
 	GetEventsForDay(id string, date time.Time) ([]models.Event, error)
 	GetEventsForWeek(id string, startWeekDate time.Time) ([]models.Event, error)
 	GetEventsForMonth(id string, startMonthDate time.Time) ([]models.Event, error)
-	CreateEvent(userId string, event models.Event) error
-	UpdateEvent(userId string, event models.Event) error
-	DeleteEvent(userId, eventId string) error
+	GetEventsInRange(userId string, from, to time.Time) ([]models.Event, error)
+	GetEvents(userId string) ([]models.Event, error)
+	GetEvent(userId, eventId string) (*models.Event, error)
+	SearchEvents(userId string, query models.SearchQuery) ([]models.Event, error)
+	CreateEvent(userId string, event models.Event) (models.Event, error)
+	UpdateEvent(userId string, event models.Event) (models.Event, error)
+	PatchEvent(userId, eventId string, version int64, patch func(event *models.Event) error) (models.Event, error)
+	DeleteEvent(userId, eventId string, version int64) error
+	GetOccurrence(userId, eventId string, occurrence time.Time) (*models.Event, error)
+	UpdateOccurrence(userId, eventId string, occurrence time.Time, event models.Event) (models.Event, error)
+	DeleteOccurrence(userId, eventId string, occurrence time.Time) error
+	ImportEvents(userId string, events []models.Event) ([]error, error)
+	ApplyEvents(userId string, ops []models.EventOperation, atomic bool) ([]models.OperationResult, error)
+	GetFreeBusy(userId string, from, to time.Time) ([]models.Interval, []models.Interval, error)
+	Location(userId, tz string) (*time.Location, error)
+	SetUserTimeZone(userId, tz string) (*models.User, error)
+	CreateUser(user models.User) (*models.User, string, error)
+	GetUser(id string) (*models.User, error)
+	GetUsers() ([]models.User, error)
+	DeleteUser(id string) error
+	Authenticate(apiKey string) (*models.User, error)
+	ResetFeedToken(userId string) (string, error)
+	AuthenticateFeed(userId, token string) error
+	SubscribeChanges(userId string, lastId *uint64) (*Subscription, error)
+	GetInvitations(userId string) ([]models.Event, error)
+	RespondToInvitation(userId, organizerId, eventId string, status models.AttendeeStatus) (models.Event, error)
+	CreateGroup(group models.Group) (*models.Group, error)
+	UpdateGroup(group models.Group) (*models.Group, error)
+	GetGroup(id string) (*models.Group, error)
+	GetGroups() ([]models.Group, error)
+	DeleteGroup(id string) error
+	ShareCalendar(userId string, groupIds []string) (*models.User, error)
+	CanRead(readerId, ownerId string) (bool, error)
+	GetTrash(userId string) ([]models.TrashedEvent, error)
+	RestoreEvent(userId, eventId string) (models.Event, error)
+	GetEventHistory(userId, eventId string) ([]models.AuditEntry, error)
+	As(actorId string) User
+	WithContext(ctx context.Context) User
+	Ping() error
+	Stats() (users, events int, err error)
 }
 
+// ChangeListener is called after events of the user are changed.
+type ChangeListener func(userId string)
+
 type Service struct {
-	cache cache.UserCacheRepo
+	repo        repository.User
+	changes     *changeLog
+	invitations *invitationIndex
+	// maxEvents limits the number of events of a user, 0 means no limit.
+	maxEvents int
+	// trashRetention is how long deleted events are kept in the trash.
+	trashRetention time.Duration
+	// maxTrashedEvents limits the number of events in the trash of a user,
+	// 0 means no limit.
+	maxTrashedEvents int
+	// creating serializes creation of events of a user to keep the number
+	// of events within maxEvents.
+	creating  *sync.Map
+	listeners *listenerList
+	// actor is the id of the user on whose behalf the service acts, which
+	// is recorded in the histories of events and limits what others see of
+	// calendars.
+	actor string
+	// ctx is the context of the request the service serves, which failures
+	// are logged with.
+	ctx context.Context
+}
+
+type listenerList struct {
+	mu        sync.RWMutex
+	listeners []ChangeListener
+}
+
+func NewService(repo repository.User) *Service {
+	return &Service{
+		repo:           repo,
+		changes:        newChangeLog(defaultChangeLogSize),
+		invitations:    newInvitationIndex(),
+		trashRetention: defaultTrashRetention,
+		creating:       new(sync.Map),
+		listeners:      new(listenerList),
+		ctx:            context.Background(),
+	}
+}
+
+// As returns the service acting on behalf of the user with the id, an
+// empty id stands for the admin. The returned service shares everything
+// but the actor with s, so As must be called after s is configured.
+func (s *Service) As(actorId string) User {
+	acting := *s
+	acting.actor = actorId
+	return &acting
+}
+
+// WithContext returns the service logging failures with ctx, so that they
+// carry the id of the request ctx belongs to. As As, it shares everything
+// else with s.
+func (s *Service) WithContext(ctx context.Context) User {
+	serving := *s
+	serving.ctx = ctx
+	return &serving
+}
+
+// SetMaxEvents limits the number of stored events of every user including
+// separately edited occurrences of series; 0 means no limit. It must be
+// called before the service is used.
+func (s *Service) SetMaxEvents(n int) {
+	s.maxEvents = n
+}
+
+// OnChange registers l to be called after every change of events.
+func (s *Service) OnChange(l ChangeListener) {
+	s.listeners.mu.Lock()
+	defer s.listeners.mu.Unlock()
+	s.listeners.listeners = append(s.listeners.listeners, l)
+}
+
+// Ping checks that the storage is available.
+func (s *Service) Ping() error {
+	if p, ok := s.repo.(repository.Pinger); ok {
+		return p.Ping()
+	}
+	return nil
+}
+
+// Stats returns the number of users and the number of their events.
+func (s *Service) Stats() (users, events int, err error) {
+	return s.repo.CountUsersEvents()
 }
 
-func NewService() *Service {
-	return &Service{cache: *cache.NewUserCache(cache.NewCache())}
+// notify publishes changes of events of the user, records them in the
+// histories of the events and calls the listeners.
+func (s *Service) notify(userId string, changes ...eventChange) {
+	for _, c := range changes {
+		s.changes.publish(c.Change)
+		s.invitations.update(c.Change)
+		s.audit(c.UserId, c.EventId, models.AuditEntry{Type: c.Type, Time: c.Time, Before: c.before, After: c.Event})
+	}
+	s.listeners.mu.RLock()
+	defer s.listeners.mu.RUnlock()
+	for _, l := range s.listeners.listeners {
+		l(userId)
+	}
 }
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"time"
+
+	"dev11/pkg/models"
+)
+
+// Location returns the time zone tz or, if tz is empty, the default time
+// zone of the user.
+func (s *Service) Location(userId, tz string) (*time.Location, error) {
+	if tz == "" {
+		user, err := s.repo.GetUser(userId)
+		if err != nil {
+			return nil, err
+		}
+		if tz = user.TimeZone; tz == "" {
+			return time.UTC, nil
+		}
+	}
+	loc, err := models.LoadLocation(tz)
+	if err != nil {
+		return nil, models.NewError(models.ErrValidation, err)
+	}
+	return loc, nil
+}
+
+// SetUserTimeZone sets the default time zone of the user.
+func (s *Service) SetUserTimeZone(userId, tz string) (*models.User, error) {
+	if _, err := models.LoadLocation(tz); err != nil {
+		return nil, models.NewError(models.ErrValidation, err)
+	}
+	return s.repo.UpdateUser(userId, func(user *models.User) error {
+		user.TimeZone = tz
+		return nil
+	})
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sort"
+	"time"
+
+	"dev11/pkg/models"
+	"dev11/pkg/repository"
+)
+
+// defaultTrashRetention is how long deleted events are kept in the trash
+// unless set with SetTrashRetention.
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// SetTrashRetention sets how long deleted events are kept in the trash
+// before they are purged. It must be called before the service is used.
+func (s *Service) SetTrashRetention(d time.Duration) {
+	s.trashRetention = d
+}
+
+// SetMaxTrashedEvents limits the number of events in the trash of every
+// user, the earliest deleted events are removed beyond it along with their
+// histories; 0 means no limit. It must be called before the service is used.
+func (s *Service) SetMaxTrashedEvents(n int) {
+	s.maxTrashedEvents = n
+}
+
+// GetTrash returns the deleted events of the user, the latest deleted
+// first.
+func (s *Service) GetTrash(userId string) ([]models.TrashedEvent, error) {
+	trash, err := s.repo.GetUsersTrash(userId)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(trash, func(i, j int) bool {
+		if !trash[i].DeletedAt.Equal(trash[j].DeletedAt) {
+			return trash[i].DeletedAt.After(trash[j].DeletedAt)
+		}
+		return trash[i].Event.Id < trash[j].Event.Id
+	})
+	return trash, nil
+}
+
+// RestoreEvent returns the event from the trash and returns it with the new
+// version. A series is restored with the separately edited occurrences
+// deleted with it, while such an occurrence alone can be restored only as
+// long as its series exists. The restored events must fit into the
+// calendar as new events do. All of them are restored within one
+// transaction of the repository, so either all or none are restored.
+func (s *Service) RestoreEvent(userId, eventId string) (models.Event, error) {
+	var (
+		restored *models.Event
+		changes  []eventChange
+	)
+	err := s.repo.UpdateUsersEvents(userId, func(tx repository.EventsTx) error {
+		events, err := tx.GetEvents()
+		if err != nil {
+			return err
+		}
+		trash, err := tx.GetTrash()
+		if err != nil {
+			return err
+		}
+		if restored, err = tx.RestoreEvent(eventId); err != nil {
+			return err
+		}
+		if restored.SeriesId != "" && !slices.ContainsFunc(events, func(v models.Event) bool { return v.Id == restored.SeriesId }) {
+			return models.Errorf(models.ErrConflict,
+				"series with id = %s of the event is deleted, it has to be restored first", restored.SeriesId)
+		}
+		if err = findConflicts(events, *restored); err != nil {
+			return err
+		}
+		changes = []eventChange{newChange(models.ChangeRestored, userId, *restored)}
+		if restored.IsRecurring() {
+			// the event is found, since it is restored
+			deletedAt := trash[slices.IndexFunc(trash, func(v models.TrashedEvent) bool { return v.Event.Id == eventId })].DeletedAt
+			for _, v := range trash {
+				if v.Event.SeriesId == eventId && v.DeletedAt.Equal(deletedAt) {
+					occurrence, err := tx.RestoreEvent(v.Event.Id)
+					if err != nil {
+						return err
+					}
+					changes = append(changes, newChange(models.ChangeRestored, userId, *occurrence))
+				}
+			}
+		}
+		if s.maxEvents > 0 && len(events)+len(changes) > s.maxEvents {
+			return s.quotaExceededError(userId)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Event{}, err
+	}
+	s.notify(userId, changes...)
+	return *restored, nil
+}
+
+// PurgeTrash removes the events kept in the trash longer than the retention
+// as of now together with their histories and returns their number.
+func (s *Service) PurgeTrash(now time.Time) (int, error) {
+	purged, err := s.repo.PurgeTrash(now)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, trash := range purged {
+		n += len(trash)
+	}
+	return n, nil
+}
+
+// newDeletion returns the deletion of events by the actor of the service
+// now.
+func (s *Service) newDeletion() models.TrashedEvent {
+	now := time.Now().UTC()
+	return models.TrashedEvent{DeletedAt: now, DeletedBy: s.actor, PurgeAt: now.Add(s.trashRetention)}
+}
+
+// trimTrash removes the earliest deleted events beyond maxTrashedEvents
+// from the trash of the user. Failures are only logged since the deletions
+// filling the trash are made.
+func (s *Service) trimTrash(userId string) {
+	if s.maxTrashedEvents == 0 {
+		return
+	}
+	if _, err := s.repo.TrimUsersTrash(userId, s.maxTrashedEvents); err != nil {
+		slog.ErrorContext(s.ctx, "failed to trim the trash",
+			slog.String("user_id", userId),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// RunPurger purges the trash every interval until ctx is done.
+func (s *Service) RunPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.PurgeTrash(time.Now()); err != nil {
+			slog.ErrorContext(ctx, "failed to purge the trash", slog.String("error", err.Error()))
+		} else if n > 0 {
+			slog.InfoContext(ctx, "purged the trash", slog.Int("events", n))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
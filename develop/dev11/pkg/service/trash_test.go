@@ -0,0 +1,198 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"dev11/pkg/models"
+	"dev11/pkg/repository/cache"
+)
+
+func TestService_TrashAndRestore(t *testing.T) {
+	s := newTestService(t)
+	if _, _, err := s.CreateUser(models.User{Id: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	alice := s.As("alice")
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	series, err := alice.CreateEvent("alice", models.Event{Name: "standup", Date: start, End: &end, RRule: "FREQ=DAILY;COUNT=3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved, err := alice.UpdateOccurrence("alice", series.Id, start.AddDate(0, 0, 1), models.Event{Name: "moved", Date: start.AddDate(0, 0, 1).Add(2 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.DeleteEvent("alice", series.Id, 0); err != nil {
+		t.Fatal(err)
+	}
+	if events, _ := s.GetEvents("alice"); len(events) != 0 {
+		t.Errorf("GetEvents() after deleting the series = %+v", events)
+	}
+	trash, err := s.GetTrash("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash) != 2 || trash[0].DeletedBy != "" || !trash[0].PurgeAt.Equal(trash[0].DeletedAt.Add(defaultTrashRetention)) {
+		t.Fatalf("GetTrash() = %+v, want the series and its occurrence", trash)
+	}
+	if _, err = s.RestoreEvent("alice", moved.Id); !errors.Is(err, models.ErrConflict) {
+		t.Errorf("RestoreEvent() of an occurrence of a deleted series error = %v, want %v", err, models.ErrConflict)
+	}
+
+	// the slot of the series is taken in the meantime
+	if _, err = alice.CreateEvent("alice", models.Event{Name: "other", Date: start, End: &end}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = alice.RestoreEvent("alice", series.Id); !errors.Is(err, models.ErrConflict) {
+		t.Errorf("RestoreEvent() of an overlapping series error = %v, want %v", err, models.ErrConflict)
+	}
+	events, _ := s.GetEvents("alice")
+	if err = alice.DeleteEvent("alice", events[0].Id, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := alice.RestoreEvent("alice", series.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Version <= series.Version {
+		t.Errorf("RestoreEvent().Version = %d, want above %d", restored.Version, series.Version)
+	}
+	events, _ = s.GetEvents("alice")
+	if names := eventNames(events); len(names) != 2 {
+		t.Errorf("GetEvents() after restoring the series = %v, want the series and its occurrence", names)
+	}
+	if trash, _ = s.GetTrash("alice"); len(trash) != 1 || trash[0].DeletedBy != "alice" {
+		t.Errorf("GetTrash() after restoring = %+v, want the event deleted by alice", trash)
+	}
+
+	history, err := s.GetEventHistory("alice", series.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []models.ChangeType
+	for _, e := range history {
+		types = append(types, e.Type)
+	}
+	want := []models.ChangeType{models.ChangeCreated, models.ChangeUpdated, models.ChangeDeleted, models.ChangeRestored}
+	if len(types) != len(want) {
+		t.Fatalf("history = %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("history = %v, want %v", types, want)
+		}
+	}
+	if history[0].Actor != "alice" || history[2].Actor != "" || history[3].Actor != "alice" {
+		t.Errorf("actors = %q, %q, %q, want alice, the admin and alice", history[0].Actor, history[2].Actor, history[3].Actor)
+	}
+	if b, a := history[1].Before, history[1].After; b == nil || a == nil || len(b.ExDates) != 0 || len(a.ExDates) != 1 {
+		t.Errorf("update entry = %+v, want the exclusion of the occurrence", history[1])
+	}
+	if history[2].Before == nil || history[2].After != nil {
+		t.Errorf("deletion entry = %+v, want the deleted event before it", history[2])
+	}
+	if _, err = s.GetEventHistory("alice", "unknown"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("GetEventHistory() of an unknown event error = %v, want %v", err, models.ErrNotFound)
+	}
+}
+
+func TestService_TrashAndRestoreFailedOccurrence(t *testing.T) {
+	repo := &failingTrashRepo{User: cache.NewUserCache(cache.NewCache())}
+	s := NewService(repo)
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	series, err := s.CreateEvent("1", timed("standup", start, time.Hour, "FREQ=DAILY;COUNT=3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved, err := s.UpdateOccurrence("1", series.Id, start.AddDate(0, 0, 1), timed("moved", start.AddDate(0, 0, 1).Add(2*time.Hour), time.Hour, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := func() (events, trash int) {
+		e, _ := s.GetEvents("1")
+		tr, _ := s.GetTrash("1")
+		return len(e), len(tr)
+	}
+
+	repo.eventId = moved.Id
+	if err = s.DeleteEvent("1", series.Id, 0); err == nil {
+		t.Fatal("DeleteEvent() succeeded although the occurrence is not deleted")
+	}
+	if events, trash := state(); events != 2 || trash != 0 {
+		t.Errorf("%d events and %d in the trash after a failed deletion, want 2 and 0", events, trash)
+	}
+
+	repo.eventId = ""
+	if err = s.DeleteEvent("1", series.Id, 0); err != nil {
+		t.Fatal(err)
+	}
+	repo.eventId = moved.Id
+	if _, err = s.RestoreEvent("1", series.Id); err == nil {
+		t.Fatal("RestoreEvent() succeeded although the occurrence is not restored")
+	}
+	if events, trash := state(); events != 0 || trash != 2 {
+		t.Errorf("%d events and %d in the trash after a failed restoration, want 0 and 2", events, trash)
+	}
+}
+
+func TestService_PurgeTrash(t *testing.T) {
+	s := newTestService(t, date(2024, 3, 1), date(2024, 3, 2))
+	s.SetTrashRetention(time.Hour)
+	events, _ := s.GetEvents("1")
+	for _, e := range events {
+		if err := s.DeleteEvent("1", e.Id, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n, err := s.PurgeTrash(time.Now()); err != nil || n != 0 {
+		t.Errorf("PurgeTrash() within the retention = %d, %v", n, err)
+	}
+	if n, err := s.PurgeTrash(time.Now().Add(time.Hour)); err != nil || n != 2 {
+		t.Errorf("PurgeTrash() after the retention = %d, %v, want 2", n, err)
+	}
+	if trash, _ := s.GetTrash("1"); len(trash) != 0 {
+		t.Errorf("GetTrash() after purging = %+v", trash)
+	}
+	_, err := s.GetEventHistory("1", events[0].Id)
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("GetEventHistory() of a purged event error = %v, want %v", err, models.ErrNotFound)
+	}
+	if _, err = s.RestoreEvent("1", events[0].Id); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("RestoreEvent() of a purged event error = %v, want %v", err, models.ErrNotFound)
+	}
+}
+
+func TestService_TrimTrash(t *testing.T) {
+	s := newTestService(t, date(2024, 3, 1), date(2024, 3, 2), date(2024, 3, 3))
+	s.SetMaxTrashedEvents(2)
+	events, _ := s.GetEvents("1")
+	models.SortEvents(events)
+	for _, e := range events[:2] {
+		if err := s.DeleteEvent("1", e.Id, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if trash, _ := s.GetTrash("1"); len(trash) != 2 {
+		t.Fatalf("GetTrash() within the limit = %+v", trash)
+	}
+	if _, err := s.ApplyEvents("1", []models.EventOperation{{Type: models.OperationDelete, EventId: events[2].Id}}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	trash, _ := s.GetTrash("1")
+	if len(trash) != 2 || trash[0].Event.Id != events[2].Id || trash[1].Event.Id != events[1].Id {
+		t.Errorf("GetTrash() beyond the limit = %+v, want the latest deleted events", trash)
+	}
+	if _, err := s.GetEventHistory("1", events[0].Id); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("GetEventHistory() of a removed event error = %v, want %v", err, models.ErrNotFound)
+	}
+	if history, err := s.GetEventHistory("1", events[1].Id); err != nil || len(history) != 2 {
+		t.Errorf("GetEventHistory() of a kept event = %+v, %v", history, err)
+	}
+}
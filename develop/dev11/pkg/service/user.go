@@ -1,107 +1,312 @@
 package service
 
 import (
-	"fmt"
-	"net/http"
-	"strconv"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"dev11/pkg/models"
-	"dev11/pkg/repository/cache"
+	"dev11/pkg/repository"
+	"dev11/pkg/search"
 )
 
 const (
-	hoursDay         = 24
-	daysWeek         = 7
-	averageDaysMonth = 30
+	eventIdBytes = 8
+	// maxTagLength limits the length of a tag in characters.
+	maxTagLength = 64
+	// maxEventIdAttempts limits retries of creating an event when the
+	// random id is already taken.
+	maxEventIdAttempts = 3
+	// maxPatchAttempts limits retries of patching an event changed
+	// concurrently.
+	maxPatchAttempts = 3
 )
 
+// GetEventsForDay returns events of the calendar day containing date.
 func (s *Service) GetEventsForDay(userId string, date time.Time) ([]models.Event, error) {
-	user, err := s.cache.GetUser(userId)
+	from := startOfDay(date)
+	return s.GetEventsInRange(userId, from, from.AddDate(0, 0, 1))
+}
+
+// GetEventsForWeek returns events of the calendar week (Monday to Sunday)
+// containing startWeekDate.
+func (s *Service) GetEventsForWeek(userId string, startWeekDate time.Time) ([]models.Event, error) {
+	from := startOfDay(startWeekDate)
+	from = from.AddDate(0, 0, -(int(from.Weekday())+6)%7)
+	return s.GetEventsInRange(userId, from, from.AddDate(0, 0, 7))
+}
+
+// GetEventsForMonth returns events of the calendar month containing
+// startMonthDate.
+func (s *Service) GetEventsForMonth(
+	userId string,
+	startMonthDate time.Time,
+) ([]models.Event, error) {
+	y, m, _ := startMonthDate.Date()
+	from := time.Date(y, m, 1, 0, 0, 0, 0, startMonthDate.Location())
+	return s.GetEventsInRange(userId, from, from.AddDate(0, 1, 0))
+}
+
+// GetEventsInRange returns events with from <= date < to sorted by date.
+// Recurring events are expanded into their occurrences. Events of other
+// users the user is invited to are included unless the user declined them,
+// but only if the service acts on behalf of the user or an admin: readers
+// of a shared calendar do not see events of third parties.
+func (s *Service) GetEventsInRange(userId string, from, to time.Time) ([]models.Event, error) {
+	userEvents, err := s.repo.GetUsersEvents(userId)
 	if err != nil {
 		return nil, err
 	}
+	if s.actor == "" || s.actor == userId {
+		invited, err := s.invitedEvents(userId)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range invited {
+			if e.Attendee(userId).Status != models.StatusDeclined {
+				userEvents = append(userEvents, e)
+			}
+		}
+	}
 	events := make([]models.Event, 0)
-	for _, v := range user.Events {
-		if date.Truncate(hoursDay * time.Hour).Equal(v.Date.Truncate(hoursDay * time.Hour)) {
-			events = append(events, v)
+	for _, v := range userEvents {
+		occurrences, err := v.Occurrences(from, to)
+		if err != nil {
+			return nil, err
 		}
+		events = append(events, occurrences...)
 	}
+	models.SortEvents(events)
 	return events, nil
 }
 
-func (s *Service) GetEventsForWeek(userId string, startWeekDate time.Time) ([]models.Event, error) {
-	user, err := s.cache.GetUser(userId)
+func (s *Service) GetEvents(userId string) ([]models.Event, error) {
+	events, err := s.repo.GetUsersEvents(userId)
 	if err != nil {
 		return nil, err
 	}
-	events := make([]models.Event, 0)
-	var difTime time.Duration
+	models.SortEvents(events)
+	return events, nil
+}
 
-	for _, v := range user.Events {
-		difTime = v.Date.Sub(startWeekDate)
-		if difTime > 0 && difTime < time.Hour*hoursDay*daysWeek {
-			events = append(events, v)
-		}
+func (s *Service) GetEvent(userId, eventId string) (*models.Event, error) {
+	return s.repo.GetUsersEvent(userId, eventId)
+}
+
+func (s *Service) CreateEvent(userId string, event models.Event) (models.Event, error) {
+	event, err := validateEvent(event)
+	if err != nil {
+		return models.Event{}, err
 	}
-	return events, nil
+	user, err := s.repo.GetUser(userId)
+	if err != nil {
+		return models.Event{}, err
+	}
+	if event.TimeZone == "" && user.TimeZone != "" {
+		event.TimeZone = user.TimeZone
+	}
+	if err = s.prepareAttendees(userId, &event); err != nil {
+		return models.Event{}, err
+	}
+	if s.maxEvents > 0 {
+		defer s.lockCreating(userId)()
+	}
+	created, err := s.applyEvent(userId, models.EventOperation{Type: models.OperationCreate, Event: event})
+	if err != nil {
+		return models.Event{}, err
+	}
+	return *created, nil
 }
 
-func (s *Service) GetEventsForMonth(
-	userId string,
-	startMonthDate time.Time,
-) ([]models.Event, error) {
-	user, err := s.cache.GetUser(userId)
+// applyEvent applies the prepared operation within a transaction of the
+// repository, so conflicts and the limit of events are checked against the
+// events it is stored with, and publishes the changes. It returns the
+// resulting event, which is nil for deletions.
+func (s *Service) applyEvent(userId string, op models.EventOperation) (*models.Event, error) {
+	var (
+		event   *models.Event
+		changes []eventChange
+	)
+	err := s.repo.UpdateUsersEvents(userId, func(tx repository.EventsTx) error {
+		events, err := tx.GetEvents()
+		if err != nil {
+			return err
+		}
+		event, changes, err = s.applyOperation(tx, userId, events, op, s.newDeletion())
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	events := make([]models.Event, 0)
-	var difTime time.Duration
+	s.notify(userId, changes...)
+	return event, nil
+}
 
-	for _, v := range user.Events {
-		difTime = v.Date.Sub(startMonthDate)
-		if difTime > 0 && difTime < time.Hour*hoursDay*averageDaysMonth {
-			events = append(events, v)
+// storeNewEvent stores the event with create under a new random id, retrying
+// if the id is taken. The UID of the event is derived from the id unless it
+// is set.
+func storeNewEvent(userId string, event models.Event, create func(event models.Event) error) (models.Event, error) {
+	generateUID := event.UID == ""
+	for attempt := 1; ; attempt++ {
+		id, err := randomHex(eventIdBytes)
+		if err != nil {
+			return models.Event{}, err
+		}
+		event.Id = id
+		if generateUID {
+			event.UID = id + "-" + userId + "@dev11"
+		}
+		err = create(event)
+		if err == nil {
+			// stored events start at version 1
+			event.Version = 1
+			return event, nil
+		}
+		if !errors.Is(err, models.ErrConflict) || attempt == maxEventIdAttempts {
+			return models.Event{}, err
 		}
 	}
-	return events, nil
 }
 
-func (s *Service) CreateEvent(userId string, event models.Event) error {
-	user, err := s.cache.GetUser(userId)
+// quotaExceededError is the error of adding events to the user beyond
+// maxEvents.
+func (s *Service) quotaExceededError(userId string) error {
+	return models.Errorf(models.ErrQuotaExceeded,
+		"user with id = %s can not have more than %d events", userId, s.maxEvents)
+}
+
+// lockCreating keeps events of the user from being added until unlock is
+// called.
+func (s *Service) lockCreating(userId string) (unlock func()) {
+	mu, _ := s.creating.LoadOrStore(userId, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// UpdateEvent replaces the event and returns it with the new version.
+// Unless event.Version is 0 it must be equal to the current version of the
+// event, otherwise models.ErrPreconditionFailed is returned.
+func (s *Service) UpdateEvent(userId string, event models.Event) (models.Event, error) {
+	event, err := validateEvent(event)
 	if err != nil {
-		return err
+		return models.Event{}, err
+	}
+	if err = s.prepareAttendees(userId, &event); err != nil {
+		return models.Event{}, err
+	}
+	updated, err := s.applyEvent(userId, models.EventOperation{Type: models.OperationUpdate, Event: event})
+	if err != nil {
+		return models.Event{}, err
+	}
+	return *updated, nil
+}
+
+// PatchEvent changes the stored event with patch and updates it as
+// UpdateEvent does, unless the event is changed in between. Unless version
+// is 0 it must be equal to the current version of the event, otherwise
+// models.ErrPreconditionFailed is returned. With version 0 the patch is
+// applied again to an event changed concurrently.
+func (s *Service) PatchEvent(
+	userId, eventId string,
+	version int64,
+	patch func(event *models.Event) error,
+) (models.Event, error) {
+	for attempt := 1; ; attempt++ {
+		event, err := s.repo.GetUsersEvent(userId, eventId)
+		if err != nil {
+			return models.Event{}, err
+		}
+		if version != 0 && version != event.Version {
+			return models.Event{}, models.Errorf(models.ErrPreconditionFailed,
+				"event with id = %s of user id = %s has version %d, not %d",
+				eventId, userId, event.Version, version)
+		}
+		read := event.Version
+		if err = patch(event); err != nil {
+			return models.Event{}, err
+		}
+		event.Id, event.Version = eventId, read
+		updated, err := s.UpdateEvent(userId, *event)
+		if version == 0 && errors.Is(err, models.ErrPreconditionFailed) && attempt < maxPatchAttempts {
+			continue
+		}
+		return updated, err
 	}
-	eventId := strconv.Itoa(len(user.Events) + 1)
-	event.Id = eventId
-	user.Events[eventId] = event
-	return nil
 }
 
-func (s *Service) UpdateEvent(userId string, event models.Event) error {
-	user, err := s.cache.GetUser(userId)
+// DeleteEvent moves the event to the trash; deleting a series also deletes
+// its separately edited occurrences within the same transaction of the
+// repository. Unless version is 0 it must be equal to the current version
+// of the event.
+func (s *Service) DeleteEvent(userId, eventId string, version int64) error {
+	_, err := s.applyEvent(userId, models.EventOperation{Type: models.OperationDelete, EventId: eventId, Version: version})
 	if err != nil {
 		return err
 	}
-	if _, found := user.Events[event.Id]; !found {
-		return cache.NewErrorHandler(
-			fmt.Errorf("failed to find event with id = %s of user id = %s", event.Id, userId),
-			http.StatusBadRequest)
-	}
-	user.Events[event.Id] = event
+	s.trimTrash(userId)
 	return nil
 }
 
-func (s *Service) DeleteEvent(userId, eventId string) error {
-	user, err := s.cache.GetUser(userId)
+// validateEvent checks the time zone, the end, the reminders, the tags and
+// the recurrence rule of the event and returns the event with times
+// converted to its time zone and without duplicate tags.
+func validateEvent(event models.Event) (models.Event, error) {
+	if event.TimeZone != "" {
+		loc, err := models.LoadLocation(event.TimeZone)
+		if err != nil {
+			return event, models.NewError(models.ErrValidation, err)
+		}
+		event.Date = event.Date.In(loc)
+		if event.End != nil {
+			end := event.End.In(loc)
+			event.End = &end
+		}
+	}
+	if event.End != nil && !event.End.After(event.Date) {
+		return event, models.Errorf(models.ErrValidation, "end of the event must be after its start")
+	}
+	for _, r := range event.Reminders {
+		if r < 0 {
+			return event, models.Errorf(models.ErrValidation, "reminder offset %s is negative", r)
+		}
+	}
+	tags, err := validateTags(event.Tags)
 	if err != nil {
-		return err
+		return event, err
 	}
-	if _, found := user.Events[eventId]; !found {
-		return cache.NewErrorHandler(
-			fmt.Errorf("failed to find event with id = %s of user id = %s", eventId, userId),
-			http.StatusBadRequest)
+	event.Tags = tags
+	if event.IsRecurring() {
+		if _, err := event.Rule(); err != nil {
+			return event, models.NewError(models.ErrValidation, err)
+		}
 	}
-	delete(user.Events, eventId)
-	return nil
+	return event, nil
+}
+
+// validateTags trims the tags and drops the ones which differ from previous
+// tags only in case.
+func validateTags(tags []string) ([]string, error) {
+	var result, seen []string
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			return nil, models.Errorf(models.ErrValidation, "tag must not be empty")
+		}
+		if utf8.RuneCountInString(t) > maxTagLength {
+			return nil, models.Errorf(models.ErrValidation, "tag %q is longer than %d characters", t, maxTagLength)
+		}
+		if key := search.Normalize(t); !slices.Contains(seen, key) {
+			seen = append(seen, key)
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
 }
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"dev11/pkg/models"
+	"dev11/pkg/repository/cache"
+)
+
+func newTestService(t *testing.T, dates ...time.Time) *Service {
+	t.Helper()
+	s := NewService(cache.NewUserCache(cache.NewCache()))
+	for i, d := range dates {
+		if _, err := s.CreateEvent("1", models.Event{Name: string(rune('a' + i)), Date: d}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func eventNames(events []models.Event) []string {
+	names := make([]string, 0, len(events))
+	for _, e := range events {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestService_Windows(t *testing.T) {
+	s := newTestService(t,
+		date(2024, 1, 31),                              // a: Wednesday
+		date(2024, 2, 5),                               // b: Monday
+		date(2024, 2, 11).Add(23*time.Hour),            // c: Sunday night
+		date(2024, 2, 12),                              // d: next Monday
+		date(2024, 2, 29),                              // e: leap day
+		date(2024, 3, 1),                               // f
+		date(2024, 2, 5).Add(-time.Nanosecond),         // g: Sunday before b
+		date(2024, 2, 5).Add(12*time.Hour),             // h
+		date(2024, 2, 5).Add(24*time.Hour-time.Second), // i
+	)
+
+	tests := []struct {
+		name  string
+		query func(string, time.Time) ([]models.Event, error)
+		date  time.Time
+		want  []string
+	}{
+		{"day", s.GetEventsForDay, date(2024, 2, 5).Add(15 * time.Hour), []string{"b", "h", "i"}},
+		{"week from monday", s.GetEventsForWeek, date(2024, 2, 5), []string{"b", "h", "i", "c"}},
+		{"week from sunday", s.GetEventsForWeek, date(2024, 2, 11), []string{"b", "h", "i", "c"}},
+		{"leap month", s.GetEventsForMonth, date(2024, 2, 17), []string{"g", "b", "h", "i", "c", "d", "e"}},
+		{"month", s.GetEventsForMonth, date(2024, 1, 1), []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.query("1", tt.date)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if names := eventNames(got); !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("got %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_GetEventsInRange(t *testing.T) {
+	s := newTestService(t, date(2024, 5, 3), date(2024, 5, 1), date(2024, 5, 2), date(2024, 5, 4))
+
+	got, err := s.GetEventsInRange("1", date(2024, 5, 1), date(2024, 5, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := eventNames(got); !reflect.DeepEqual(names, []string{"b", "c", "a"}) {
+		t.Errorf("GetEventsInRange() = %v, want [b c a]", names)
+	}
+
+	if _, err = s.GetEventsInRange("2", date(2024, 5, 1), date(2024, 5, 4)); err == nil {
+		t.Error("expected error for unknown user")
+	}
+}
+
+func TestService_GetEventsForDayInLocation(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skip(err)
+	}
+	s := newTestService(t, time.Date(2024, 2, 6, 1, 0, 0, 0, moscow))
+
+	tests := []struct {
+		name string
+		date time.Time
+		want int
+	}{
+		{"moscow day", time.Date(2024, 2, 6, 0, 0, 0, 0, moscow), 1},
+		{"utc day", date(2024, 2, 6), 0},
+		{"previous utc day", date(2024, 2, 5), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetEventsForDay("1", tt.date)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("got %d events, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestService_CreateEventUniqueIds(t *testing.T) {
+	s := newTestService(t, date(2024, 1, 1), date(2024, 1, 2))
+	events, _ := s.GetEvents("1")
+	if err := s.DeleteEvent("1", events[0].Id, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	const workers, perWorker = 8, 25
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				if _, err := s.CreateEvent("1", models.Event{Name: "x", Date: date(2024, 2, 1)}); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	events, err := s.GetEvents("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make(map[string]bool, len(events))
+	for _, e := range events {
+		ids[e.Id] = true
+	}
+	if want := 1 + workers*perWorker; len(events) != want || len(ids) != want {
+		t.Errorf("got %d events with %d distinct ids, want %d", len(events), len(ids), want)
+	}
+	if events[0].Name != "b" {
+		t.Errorf("event %q was overwritten", "b")
+	}
+}
+
+func TestService_MaxEvents(t *testing.T) {
+	s := newTestService(t)
+	s.SetMaxEvents(5)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.CreateEvent("1", models.Event{Name: "a", Date: date(2024, 1, i+1)})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, models.ErrQuotaExceeded):
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+	if created != 5 {
+		t.Errorf("created %d events, want 5", created)
+	}
+
+	events, err := s.GetEvents("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.DeleteEvent("1", events[0].Id, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.CreateEvent("1", models.Event{Name: "b", Date: date(2024, 2, 1)}); err != nil {
+		t.Errorf("creating an event after deleting one failed: %v", err)
+	}
+}
+
+func TestService_PatchEvent(t *testing.T) {
+	s := newTestService(t, date(2024, 1, 1))
+	events, _ := s.GetEvents("1")
+	id := events[0].Id
+
+	// the first attempt of the patch is raced by an update of the description
+	attempts := 0
+	patched, err := s.PatchEvent("1", id, 0, func(event *models.Event) error {
+		attempts++
+		if attempts == 1 {
+			if _, err := s.UpdateEvent("1", models.Event{Id: id, Name: "a", Description: "agenda", Date: event.Date}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		event.Name = "b"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 || patched.Name != "b" || patched.Description != "agenda" || patched.Version != 3 {
+		t.Errorf("PatchEvent() = %+v after %d attempts, want both changes at version 3 after 2", patched, attempts)
+	}
+
+	if _, err = s.PatchEvent("1", id, 2, func(event *models.Event) error { return nil }); !errors.Is(err, models.ErrPreconditionFailed) {
+		t.Errorf("PatchEvent() of an old version error = %v, want %v", err, models.ErrPreconditionFailed)
+	}
+	if _, err = s.PatchEvent("1", "unknown", 0, func(event *models.Event) error { return nil }); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("PatchEvent() of an unknown event error = %v, want %v", err, models.ErrNotFound)
+	}
+}